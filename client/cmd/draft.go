@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/draft"
+	"passwordless-mail-client/pkg/model"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ask for recipients, subject and attachments, write the body in $EDITOR
+// and save it as a draft that can be sent right away as user
+func ComposeCmd(user string) error {
+	store, err := DraftStore()
+	if err != nil {
+		return err
+	}
+	input := bufio.NewReader(stdinLine{})
+
+	var to, cc, bcc []string
+	for len(to)+len(cc)+len(bcc) == 0 {
+		to, err = promptAddresses(input, "to")
+		if err != nil {
+			return err
+		}
+		cc, err = promptAddresses(input, "cc (optional)")
+		if err != nil {
+			return err
+		}
+		bcc, err = promptAddresses(input, "bcc (optional)")
+		if err != nil {
+			return err
+		}
+		if len(to)+len(cc)+len(bcc) == 0 {
+			fmt.Println("mail needs at least one recipient")
+		}
+	}
+	subject, err := prompt(input, "subject")
+	if err != nil {
+		return err
+	}
+
+	// draft may be sent from another directory, keep absolute paths
+	var attachments []string
+	paths, err := prompt(input, "attachments (optional, comma separated files)")
+	if err != nil {
+		return err
+	}
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("can not attach %s: %w", path, err)
+		}
+		absolutePath, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, absolutePath)
+	}
+
+	body, err := EditText("")
+	if err != nil {
+		return err
+	}
+	body = strings.TrimRight(body, "\n")
+
+	name, path, err := store.Create(draft.NewName(time.Now()), model.MailFileContent{
+		To:          to,
+		Cc:          cc,
+		Bcc:         bcc,
+		Subject:     &subject,
+		Body:        &body,
+		Attachments: attachments,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("draft %s saved to %s\n", name, path)
+
+	answer, err := prompt(input, "send now? [y/N]")
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(answer) != "y" && strings.ToLower(answer) != "yes" {
+		fmt.Printf("send it later with: kmail -send-draft %s\n", name)
+		return nil
+	}
+
+	return SendDraftCmd(name, "", user)
+}
+
+// print name, recipients and subject of every draft, most recent first
+func ListDraftsCmd() error {
+	store, err := DraftStore()
+	if err != nil {
+		return err
+	}
+	drafts, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	if len(drafts) == 0 {
+		fmt.Println("no draft")
+		return nil
+	}
+	for _, d := range drafts {
+		if d.Err != nil {
+			fmt.Printf("%s  %s  (invalid: %v)\n", d.ModTime.Format(time.DateTime), d.Name, d.Err)
+			continue
+		}
+		subject := ""
+		if d.Mail.Subject != nil {
+			subject = *d.Mail.Subject
+		}
+		recipients := append(append(append([]string{}, d.Mail.To...), d.Mail.Cc...), d.Mail.Bcc...)
+		fmt.Printf("%s  %s  to %s  %q\n", d.ModTime.Format(time.DateTime), d.Name, strings.Join(recipients, ", "), subject)
+	}
+
+	return nil
+}
+
+// open the kmail file of draft name in $EDITOR, a new draft starts from an empty mail
+func EditDraftCmd(name string) error {
+	store, err := DraftStore()
+	if err != nil {
+		return err
+	}
+	path, err := store.Path(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		subject, body := "", ""
+		path, err = store.Save(name, model.MailFileContent{
+			To:      model.AddressList{},
+			Subject: &subject,
+			Body:    &body,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = runEditor(path)
+	if err != nil {
+		return err
+	}
+
+	// the file is kept as it is, so a mistake can be fixed by editing again
+	_, err = store.Load(name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("draft %s saved to %s\n", strings.TrimSuffix(name, draft.Extension), path)
+
+	return nil
+}
+
+// send draft name with SendMailCmd and delete it once every request is sent
+func SendDraftCmd(name string, attach string, user string) error {
+	store, err := DraftStore()
+	if err != nil {
+		return err
+	}
+	saved, err := store.Load(name)
+	if err != nil {
+		return err
+	}
+
+	err = SendMailCmd(saved.Path, attach, user)
+	if err != nil {
+		return err
+	}
+
+	err = store.Delete(saved.Name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("draft %s sent and deleted\n", saved.Name)
+
+	return nil
+}
+
+func DraftStore() (*draft.Store, error) {
+	dir, err := draft.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return draft.NewStore(dir), nil
+}
+
+// let the user write text in $VISUAL or $EDITOR, vi when neither is set
+func EditText(initial string) (string, error) {
+	file, err := os.CreateTemp("", "kmail-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(initial)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = runEditor(file.Name())
+	if err != nil {
+		return "", err
+	}
+	text, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return string(text), nil
+}
+
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// editor may come with arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	command := exec.Command(args[0], append(args[1:], path)...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	err := command.Run()
+	if err != nil {
+		return fmt.Errorf("can not run editor %s: %w", editor, err)
+	}
+
+	return nil
+}
+
+func prompt(input *bufio.Reader, label string) (string, error) {
+	fmt.Printf("%s: ", label)
+	line, err := input.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// comma separated addresses, asked again until every address is valid
+func promptAddresses(input *bufio.Reader, label string) ([]string, error) {
+	for {
+		line, err := prompt(input, label)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses := []string{}
+		var invalid error
+		for _, address := range strings.Split(line, ",") {
+			address = strings.TrimSpace(address)
+			if address == "" {
+				continue
+			}
+			if _, err := account.ParseAddress(address); err != nil {
+				invalid = fmt.Errorf("invalid address %s: %w", address, err)
+				break
+			}
+			addresses = append(addresses, address)
+		}
+		if invalid == nil {
+			return addresses, nil
+		}
+		fmt.Println(invalid)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/keystore"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// account of user, an identity name in the keystore or the default identity
+// when empty. a hex private key is still accepted but it leaks into shell
+// history and the process list, import it with -import instead
+func LoadAccount(user string) (*account.Account, error) {
+	if isPrivateKeyHex(user) {
+		fmt.Fprintln(os.Stderr, "warning: private key on the command line is visible to other users, import it into the keystore with -import")
+		return account.ConnectAccount(user)
+	}
+
+	store, err := OpenKeystore()
+	if err != nil {
+		return nil, err
+	}
+	name := user
+	if name == "" {
+		name = store.Default()
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no identity in %s, create one with -keygen <name> or -import <name>", store.Path())
+	}
+	// unknown name fails before the passphrase is asked
+	_, err = store.Address(name)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, err := ReadSecret(fmt.Sprintf("passphrase of %s: ", name))
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Unlock(name, passphrase)
+}
+
+func OpenKeystore() (*keystore.Keystore, error) {
+	path, err := keystore.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return keystore.Open(path)
+}
+
+// generate a private key from crypto/rand and encrypt it into the keystore as name
+func KeygenCmd(name string) error {
+	store, err := OpenKeystore()
+	if err != nil {
+		return err
+	}
+	acc, err := account.GenerateAccount()
+	if err != nil {
+		return err
+	}
+
+	return addIdentity(store, name, acc)
+}
+
+// encrypt a private key into the keystore as name, the key is read from
+// key file as PEM or hex, or typed as hex at a hidden prompt
+func ImportKeyCmd(name string, keyFile string) error {
+	store, err := OpenKeystore()
+	if err != nil {
+		return err
+	}
+
+	var privateKey []byte
+	if keyFile != "" {
+		privateKey, err = os.ReadFile(keyFile)
+	} else {
+		privateKey, err = ReadSecret("private key (hex): ")
+	}
+	if err != nil {
+		return err
+	}
+	acc, err := account.ImportPrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	return addIdentity(store, name, acc)
+}
+
+// generate a mnemonic and show it once, the identity name is the account
+// at index of it. the words and the optional mnemonic passphrase restore
+// the identity with -mnemonic-restore, they are not kept anywhere else
+func NewMnemonicCmd(name string, index uint) error {
+	store, err := OpenKeystore()
+	if err != nil {
+		return err
+	}
+	mnemonic, err := account.NewMnemonic()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "write down these words in order, they are shown only this once:")
+	fmt.Fprintln(os.Stderr)
+	for i, word := range strings.Fields(mnemonic) {
+		fmt.Fprintf(os.Stderr, "%2d. %s\n", i+1, word)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	passphrase, err := ReadSecret("mnemonic passphrase (optional): ")
+	if err != nil {
+		return err
+	}
+	if len(passphrase) > 0 {
+		repeated, err := ReadSecret("repeat mnemonic passphrase: ")
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(passphrase, repeated) {
+			return fmt.Errorf("passphrases do not match")
+		}
+	}
+	acc, err := account.AccountFromMnemonic(mnemonic, string(passphrase), uint32(index))
+	if err != nil {
+		return err
+	}
+
+	return addIdentity(store, name, acc)
+}
+
+// derive the account at index of a mnemonic typed at a hidden prompt
+// and encrypt it into the keystore as name
+func RestoreMnemonicCmd(name string, index uint) error {
+	store, err := OpenKeystore()
+	if err != nil {
+		return err
+	}
+	mnemonic, err := ReadSecret("mnemonic: ")
+	if err != nil {
+		return err
+	}
+	err = account.ValidateMnemonic(string(mnemonic))
+	if err != nil {
+		return err
+	}
+	passphrase, err := ReadSecret("mnemonic passphrase (optional): ")
+	if err != nil {
+		return err
+	}
+	acc, err := account.AccountFromMnemonic(string(mnemonic), string(passphrase), uint32(index))
+	if err != nil {
+		return err
+	}
+
+	return addIdentity(store, name, acc)
+}
+
+func addIdentity(store *keystore.Keystore, name string, acc *account.Account) error {
+	passphrase, err := ReadNewPassphrase(name)
+	if err != nil {
+		return err
+	}
+	err = store.Add(name, acc, passphrase)
+	if err != nil {
+		return err
+	}
+	err = store.Save()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("identity %s (%s) saved to %s\n", name, acc.GetAddress(), store.Path())
+
+	return nil
+}
+
+// print the public key of user as PEM, or its private key as PKCS#8 PEM
+// after unlocking it. key is public or private
+func ExportKeyCmd(key string, user string) error {
+	switch key {
+	case "public":
+		address, err := LoadAddress(user)
+		if err != nil {
+			return err
+		}
+		publicKey, err := account.ParseAddress(address)
+		if err != nil {
+			return err
+		}
+		fmt.Print(account.ExportPublicKeyPEM(publicKey))
+	case "private":
+		acc, err := LoadAccount(user)
+		if err != nil {
+			return err
+		}
+		privateKeyPEM, err := acc.ExportPrivateKeyPEM()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "warning: anyone with this private key can read your mail and sign as you")
+		fmt.Print(privateKeyPEM)
+	default:
+		return fmt.Errorf("invalid export: use public or private")
+	}
+
+	return nil
+}
+
+// print the address of user, it is stored in the keystore so no passphrase is needed
+func AddressCmd(user string) error {
+	address, err := LoadAddress(user)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(address)
+
+	return nil
+}
+
+// address of user without unlocking its private key, see LoadAccount
+func LoadAddress(user string) (string, error) {
+	if isPrivateKeyHex(user) {
+		acc, err := account.ConnectAccount(user)
+		if err != nil {
+			return "", err
+		}
+		return acc.GetAddress(), nil
+	}
+
+	store, err := OpenKeystore()
+	if err != nil {
+		return "", err
+	}
+
+	return store.Address(user)
+}
+
+// print name and address of every identity, * marks the default
+func ListIdentitiesCmd() error {
+	store, err := OpenKeystore()
+	if err != nil {
+		return err
+	}
+
+	identities := store.Identities()
+	if len(identities) == 0 {
+		fmt.Printf("no identity in %s\n", store.Path())
+		return nil
+	}
+	for _, identity := range identities {
+		marker := " "
+		if identity.Default {
+			marker = "*"
+		}
+		fmt.Printf("%s %s %s\n", marker, identity.Name, identity.Address)
+	}
+
+	return nil
+}
+
+// the private key is gone with the identity unless it is kept somewhere else
+func RemoveIdentityCmd(name string) error {
+	store, err := OpenKeystore()
+	if err != nil {
+		return err
+	}
+	err = store.Remove(name)
+	if err != nil {
+		return err
+	}
+	err = store.Save()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("identity %s removed\n", name)
+
+	return nil
+}
+
+func DefaultIdentityCmd(name string) error {
+	store, err := OpenKeystore()
+	if err != nil {
+		return err
+	}
+	err = store.SetDefault(name)
+	if err != nil {
+		return err
+	}
+	err = store.Save()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("identity %s is the default\n", name)
+
+	return nil
+}
+
+// ask for the passphrase of a new identity twice
+func ReadNewPassphrase(name string) ([]byte, error) {
+	passphrase, err := ReadSecret(fmt.Sprintf("new passphrase of %s: ", name))
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase is required")
+	}
+	repeated, err := ReadSecret("repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, repeated) {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+// read a line without echo from the terminal, a line of stdin when it is
+// not a terminal so scripts can pipe it in
+func ReadSecret(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return secret, err
+	}
+
+	line, err := bufio.NewReader(stdinLine{}).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return nil, err
+	}
+	fmt.Fprintln(os.Stderr)
+
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+// reads stdin one byte at a time, so a secret never takes
+// the lines after it from the next prompt
+type stdinLine struct{}
+
+func (stdinLine) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return os.Stdin.Read(p[:1])
+}
+
+// hex private key given to -user, anything else is an identity name
+func isPrivateKeyHex(value string) bool {
+	_, err := account.ConnectAccount(value)
+
+	return err == nil
+}
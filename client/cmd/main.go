@@ -1,317 +1,1130 @@
 package main
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"encoding/asn1"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"math/big"
 	"net/http"
 	"os"
-	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/kmail"
 	"passwordless-mail-client/pkg/model"
 	"passwordless-mail-client/pkg/request"
+	"path/filepath"
 	"strings"
+
+	"github.com/google/uuid"
 )
 
-func main() {
-	errChan := make(chan error)
-	defer close(errChan)
-	// go ListenErrChan(errChan)
+// base url of the kmail server requests are signed for, set with -server
+// or the KMAIL_SERVER environment variable
+var ServerURL = "http://localhost:8080"
+
+// session token from -login sent instead of a signature on read and write
+// requests, set with -token or the KMAIL_TOKEN environment variable
+var Token string
+
+func main() {
+	errChan := make(chan error)
+	defer close(errChan)
+	// go ListenErrChan(errChan)
+
+	if server := os.Getenv("KMAIL_SERVER"); server != "" {
+		ServerURL = server
+	}
+	Token = os.Getenv("KMAIL_TOKEN")
+
+	serverFlag := flag.String("server", ServerURL, "base url of the kmail server")
+	tokenFlag := flag.String("token", Token, "session token of -login to use instead of signing read and write requests")
+	credentialFlag := flag.String("user", "", "identity name in the keystore, the default identity when empty")
+	inboxFlag := flag.String("inbox", "", "get inbox")
+	sendMailFlag := flag.String("send", "", "send mail")
+	attachFlag := flag.String("attach", "", "comma separated files to attach to the mail of -send or -send-draft")
+	composeFlag := flag.Bool("compose", false, "write a new mail and save it as a draft")
+	draftsFlag := flag.Bool("drafts", false, "list drafts")
+	draftFlag := flag.String("draft", "", "edit draft name in $EDITOR, a new name starts an empty draft")
+	sendDraftFlag := flag.String("send-draft", "", "send draft name and delete it")
+	downloadFlag := flag.String("download", "", "save attachments of mail id to the working directory")
+	sentFlag := flag.String("sent", "", "get sent mails")
+	readFlag := flag.String("read", "", "verify, decrypt and print mail id")
+	jsonFlag := flag.Bool("json", false, "print the mail of -read as json")
+	searchFlag := flag.String("search", "", "search inbox with query file")
+	verifyFlag := flag.String("verify", "", "verify sender signature of mail id")
+	replyFlag := flag.String("reply", "", "print a reply kmail file for mail id")
+	deleteFlag := flag.String("delete", "", "move mail id to trash")
+	restoreFlag := flag.String("restore", "", "restore mail id from trash")
+	markReadFlag := flag.String("mark-read", "", "mark comma separated mail ids as read")
+	markUnreadFlag := flag.String("mark-unread", "", "mark comma separated mail ids as unread")
+	purgeTrashFlag := flag.Bool("purge-trash", false, "permanently delete every mail in trash")
+	loginFlag := flag.String("login", "", "print a session token with comma separated scopes (read, write)")
+	logoutFlag := flag.Bool("logout", false, "revoke the session token of -token")
+	revokeSessionsFlag := flag.Bool("revoke-sessions", false, "revoke every session token of the user")
+	keygenFlag := flag.String("keygen", "", "generate a private key into the keystore as identity name")
+	importFlag := flag.String("import", "", "import a private key into the keystore as identity name")
+	keyFileFlag := flag.String("key-file", "", "PEM or hex private key file of -import, typed at a prompt when empty")
+	exportFlag := flag.String("export", "", "print public key or private key of the user as PEM (public or private)")
+	addressFlag := flag.Bool("address", false, "print address of the user")
+	mnemonicNewFlag := flag.String("mnemonic-new", "", "generate a mnemonic into the keystore as identity name")
+	mnemonicRestoreFlag := flag.String("mnemonic-restore", "", "restore identity name from a mnemonic")
+	indexFlag := flag.Uint("index", 0, "derivation index of -mnemonic-new and -mnemonic-restore, one mnemonic backs up many identities")
+	identitiesFlag := flag.Bool("identities", false, "list identities of the keystore")
+	identityRemoveFlag := flag.String("identity-remove", "", "remove identity name from the keystore")
+	identityDefaultFlag := flag.String("identity-default", "", "use identity name when -user is not set")
+	flag.Parse()
+	ServerURL = *serverFlag
+	Token = *tokenFlag
+
+	if *keygenFlag != "" {
+		err := KeygenCmd(*keygenFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *importFlag != "" {
+		err := ImportKeyCmd(*importFlag, *keyFileFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *mnemonicNewFlag != "" {
+		err := NewMnemonicCmd(*mnemonicNewFlag, *indexFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *mnemonicRestoreFlag != "" {
+		err := RestoreMnemonicCmd(*mnemonicRestoreFlag, *indexFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *exportFlag != "" {
+		err := ExportKeyCmd(*exportFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *addressFlag {
+		err := AddressCmd(*credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *identitiesFlag {
+		err := ListIdentitiesCmd()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *identityRemoveFlag != "" {
+		err := RemoveIdentityCmd(*identityRemoveFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *identityDefaultFlag != "" {
+		err := DefaultIdentityCmd(*identityDefaultFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *inboxFlag != "" {
+		err := GetInboxCmd(*inboxFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *sentFlag != "" {
+		err := GetSentCmd(*sentFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *readFlag != "" {
+		err := ReadMailCmd(*readFlag, *credentialFlag, *jsonFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *searchFlag != "" {
+		err := SearchMailCmd(*searchFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *sendMailFlag != "" {
+		err := SendMailCmd(*sendMailFlag, *attachFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *composeFlag {
+		err := ComposeCmd(*credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *draftsFlag {
+		err := ListDraftsCmd()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *draftFlag != "" {
+		err := EditDraftCmd(*draftFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *sendDraftFlag != "" {
+		err := SendDraftCmd(*sendDraftFlag, *attachFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *verifyFlag != "" {
+		err := VerifyMailCmd(*verifyFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *downloadFlag != "" {
+		err := DownloadAttachmentsCmd(*downloadFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *replyFlag != "" {
+		err := ReplyMailCmd(*replyFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *deleteFlag != "" || *restoreFlag != "" {
+		var err error
+		if *deleteFlag != "" {
+			err = TrashMailCmd(*deleteFlag, *credentialFlag, request.DeleteEmail)
+		} else {
+			err = TrashMailCmd(*restoreFlag, *credentialFlag, request.RestoreEmail)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *markReadFlag != "" || *markUnreadFlag != "" {
+		var err error
+		if *markReadFlag != "" {
+			err = MarkMailsCmd(*markReadFlag, *credentialFlag, request.MarkRead)
+		} else {
+			err = MarkMailsCmd(*markUnreadFlag, *credentialFlag, request.MarkUnread)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *purgeTrashFlag {
+		err := PurgeTrashCmd(*credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *loginFlag != "" {
+		err := LoginCmd(*loginFlag, *credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *logoutFlag {
+		err := LogoutCmd()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+
+	if *revokeSessionsFlag {
+		err := RevokeSessionsCmd(*credentialFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		return
+	}
+}
+
+func AddCmd(
+	flagName string,
+	description string,
+	command func(flagValue string) error,
+	errChan chan error,
+) {
+	readFlag := flag.String(flagName, "", description)
+	flag.Parse()
+
+	if *readFlag != "" {
+		err := command(*readFlag)
+		errChan <- err
+	}
+}
+
+// client of ServerURL signing as user, see LoadAccount. read and write
+// requests send Token instead of a signature when it is set
+func NewClient(user string) (*kmail.Client, error) {
+	acc, err := LoadAccount(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return kmail.NewClient(ServerURL, &http.Client{}, acc).WithToken(Token), nil
+}
+
+func GetInboxCmd(queryPath string, user string) error {
+	query, err := ReadQueryFile(queryPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClient(user)
+	if err != nil {
+		return err
+	}
+
+	// inbox is decrypted with user private key before printing
+	inbox, err := client.Inbox(query)
+	if err != nil {
+		return fmt.Errorf("can not get inbox: %w", err)
+	}
+	inboxBytes, err := json.Marshal(inbox)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(inboxBytes))
+
+	return nil
+}
+
+// read page and limit from a query json file
+func ReadQueryFile(queryPath string) (model.QueryJson, error) {
+	// validate query path
+	if _, err := os.Stat(queryPath); os.IsNotExist(err) {
+		return model.QueryJson{}, fmt.Errorf("query file not found, invalid path or file name")
+	}
+
+	// validate query json
+	queryFile, err := os.Open(queryPath)
+	if err != nil {
+		return model.QueryJson{}, err
+	}
+	defer queryFile.Close()
+	// validate that queryFile contains a valid json
+	var query model.QueryJson
+	err = json.NewDecoder(queryFile).Decode(&query)
+	hasCursor := query.Before != nil || query.After != nil
+	if err != nil || (query.Page == nil && !hasCursor) || query.Limit == nil {
+		return model.QueryJson{}, fmt.Errorf("invalid query json: please use this format\n\t{ \"page\":int, \"limit\":int }")
+	}
+	if (query.Page != nil && *query.Page <= 0) || *query.Limit <= 0 {
+		return model.QueryJson{}, fmt.Errorf("invalid query json: page and limit should be greater than 0")
+	}
+	if query.Before != nil && query.After != nil {
+		return model.QueryJson{}, fmt.Errorf("invalid query json: use either before or after cursor")
+	}
+
+	return query, nil
+}
+
+// list mails sent by the user, their content is encrypted to
+// each recipient so only id and recipient are printed
+func GetSentCmd(queryPath string, user string) error {
+	client, err := NewClient(user)
+	if err != nil {
+		return err
+	}
+
+	query, err := ReadQueryFile(queryPath)
+	if err != nil {
+		return err
+	}
+	if query.Page == nil {
+		return fmt.Errorf("invalid query json: sent mails are listed by page and limit")
+	}
+
+	message, err := request.NewGetSent(client.BaseURL())
+	if err != nil {
+		return err
+	}
+	apiPath := fmt.Sprintf("/mail/sent?page=%d&limit=%d", *query.Page, *query.Limit)
+	body, err := client.PostAuthenticated(apiPath, message)
+	if err != nil {
+		return fmt.Errorf("can not get sent mails: %w", err)
+	}
+
+	var sent request.GetSentResponse
+	err = json.Unmarshal(body, &sent)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("total sent: %d\n", sent.Total)
+	for _, mail := range sent.Sent {
+		fmt.Printf("%s %s to %s\n", mail.SentAt, mail.ID, strings.Join(append(mail.To, mail.Cc...), ", "))
+	}
+
+	return nil
+}
+
+// mails fetched per request when keyword is matched on the client
+const searchFetchLimit = 100
+
+// most mails a keyword is matched against, newest first. the server can
+// not match keywords in encrypted mails so every mail is downloaded
+const searchScanLimit = 1000
+
+// server filters by sender and time, keyword is matched locally after
+// decryption so it is never sent to the server
+func SearchMailCmd(queryPath string, user string) error {
+	client, err := NewClient(user)
+	if err != nil {
+		return err
+	}
+
+	// validate query path
+	if _, err := os.Stat(queryPath); os.IsNotExist(err) {
+		return fmt.Errorf("query file not found, invalid path or file name")
+	}
+	queryFile, err := os.Open(queryPath)
+	if err != nil {
+		return err
+	}
+	defer queryFile.Close()
+	var query model.SearchQueryJson
+	err = json.NewDecoder(queryFile).Decode(&query)
+	if err != nil || query.Page == nil || query.Limit == nil {
+		return fmt.Errorf("invalid search json: please use this format\n\t{ \"page\":int, \"limit\":int, \"keyword\":string, \"from\":string, \"since\":string, \"until\":string }")
+	}
+	if *query.Page <= 0 || *query.Limit <= 0 {
+		return fmt.Errorf("invalid search json: page and limit should be greater than 0")
+	}
+	var keyword, from, since, until string
+	if query.Keyword != nil {
+		keyword = *query.Keyword
+	}
+	if query.From != nil {
+		from = *query.From
+	}
+	if query.Since != nil {
+		since = *query.Since
+	}
+	if query.Until != nil {
+		until = *query.Until
+	}
+
+	searchPage := func(page int, limit int) (request.GetInboxResponse, error) {
+		message, err := request.NewSearchEmail(client.BaseURL(), from, since, until)
+		if err != nil {
+			return request.GetInboxResponse{}, err
+		}
+		apiPath := fmt.Sprintf("/mail/search?page=%d&limit=%d", page, limit)
+		body, err := client.PostAuthenticated(apiPath, message)
+		if err != nil {
+			return request.GetInboxResponse{}, fmt.Errorf("can not search mails: %w", err)
+		}
+		var result request.GetInboxResponse
+		err = json.Unmarshal(body, &result)
+		if err != nil {
+			return request.GetInboxResponse{}, err
+		}
+		client.DecryptInbox(&result)
+		return result, nil
+	}
 
-	credentialFlag := flag.String("user", "", "user private key")
-	inboxFlag := flag.String("inbox", "", "get inbox")
-	sendMailFlag := flag.String("send", "", "send mail")
-	flag.Parse()
+	var result request.GetInboxResponse
+	if keyword == "" {
+		result, err = searchPage(*query.Page, *query.Limit)
+		if err != nil {
+			return err
+		}
+	} else {
+		// fetch the newest mails matching the server filters, then page the keyword matches
+		matches := []model.Mail{}
+		for page := 1; page <= searchScanLimit/searchFetchLimit; page++ {
+			fetched, err := searchPage(page, searchFetchLimit)
+			if err != nil {
+				return err
+			}
+			if page == 1 && fetched.Total > searchScanLimit {
+				result.Truncated = true
+				fmt.Fprintf(os.Stderr, "warning: keyword is only matched against the newest %d of %d mails, narrow the search with from, since or until\n", searchScanLimit, fetched.Total)
+			}
+			result.Undecryptable = append(result.Undecryptable, fetched.Undecryptable...)
+			for _, mail := range fetched.Inbox {
+				if MatchKeyword(mail, keyword) {
+					matches = append(matches, mail)
+					if mail.ReadAt == nil {
+						result.Unread++
+					}
+				}
+			}
+			if len(fetched.Inbox)+len(fetched.Undecryptable) < searchFetchLimit {
+				break
+			}
+		}
+		result.Total = len(matches)
+		start := min((*query.Page-1)*(*query.Limit), len(matches))
+		end := min(start+*query.Limit, len(matches))
+		result.Inbox = matches[start:end]
+	}
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
 
-	// TestPrivateKey1 := "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab147"
-	// TestPrivateKey2 := "1baa694aa9154f63b1503c7138f187780f221670f035403ff428a65182bab146"
-	// testAccount1, _ := account.ConnectAccount(TestPrivateKey1)
-	// testAccount2, _ := account.ConnectAccount(TestPrivateKey2)
-	// testAccount1Address := testAccount1.GetAddress()
-	// testAccount2Address := testAccount2.GetAddress()
-	// fmt.Println("Test Account 1 Address:", testAccount1Address)
-	// fmt.Println("Test Account 2 Address:", testAccount2Address)
-	// "cd91d7cab64774ed58db47351f885cb600df09cc44354b16560308189a7c5013f862679f86d71f264d0b18cd608e87c44ee6d18059fa7ab9c4da9a5e56b9cb57"
+	fmt.Println(string(resultBytes))
 
-	if *inboxFlag != "" {
-		if *credentialFlag == "" {
-			fmt.Println("user credential is required")
-			os.Exit(1)
-			return
+	return nil
+}
+
+// every word of keyword must be in subject or body, case insensitive
+func MatchKeyword(mail model.Mail, keyword string) bool {
+	content := strings.ToLower(mail.Subject + "\n" + mail.Body)
+	for _, word := range strings.Fields(strings.ToLower(keyword)) {
+		if !strings.Contains(content, word) {
+			return false
 		}
+	}
 
-		err := GetInboxCmd(*inboxFlag, *credentialFlag)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-			return
+	return true
+}
+
+// attach is a comma separated list of files, added to attachments of the kmail file
+func SendMailCmd(mailPath string, attach string, user string) error {
+	// validate mail path
+	if _, err := os.Stat(mailPath); os.IsNotExist(err) {
+		return fmt.Errorf("mail file not found, invalid path or file name")
+	}
+
+	// validate mail json
+	mailFile, err := os.Open(mailPath)
+	if err != nil {
+		return err
+	}
+	defer mailFile.Close()
+	var mail model.MailFileContent
+	err = json.NewDecoder(mailFile).Decode(&mail)
+	if err != nil ||
+		len(mail.To)+len(mail.Cc)+len(mail.Bcc) == 0 ||
+		mail.Subject == nil ||
+		mail.Body == nil {
+		return fmt.Errorf("invalid kmail json: please use this format\n\t{ \"to\":string or [string], \"cc\":[string], \"bcc\":[string], \"subject\":string, \"body\":string, \"attachments\":[string] }")
+	}
+	attachmentPaths := mail.Attachments
+	for _, path := range strings.Split(attach, ",") {
+		if strings.TrimSpace(path) != "" {
+			attachmentPaths = append(attachmentPaths, strings.TrimSpace(path))
 		}
+	}
 
-		return
+	client, err := NewClient(user)
+	if err != nil {
+		return err
 	}
 
-	if *sendMailFlag != "" {
-		if *credentialFlag == "" {
-			fmt.Println("user credential is required")
-			os.Exit(1)
-			return
+	var inReplyTo *uuid.UUID
+	if mail.InReplyTo != nil {
+		id, err := uuid.Parse(*mail.InReplyTo)
+		if err != nil {
+			return fmt.Errorf("invalid kmail json: in_reply_to should be mail id")
 		}
+		inReplyTo = &id
+	}
 
-		err := SendMailCmd(*sendMailFlag, *credentialFlag)
+	var attachments []kmail.Attachment
+	for _, path := range attachmentPaths {
+		content, err := os.ReadFile(path)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-			return
+			return fmt.Errorf("can not read attachment %s: %w", path, err)
 		}
+		attachments = append(attachments, kmail.Attachment{
+			Name:    filepath.Base(path),
+			Content: content,
+		})
+	}
 
-		return
+	// one request for to and cc, then one per bcc recipient
+	responses, err := client.Send(kmail.OutgoingMail{
+		To:          mail.To,
+		Cc:          mail.Cc,
+		Bcc:         mail.Bcc,
+		Subject:     *mail.Subject,
+		Body:        *mail.Body,
+		InReplyTo:   inReplyTo,
+		Attachments: attachments,
+	})
+	for _, response := range responses {
+		responseBytes, err := json.Marshal(response)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(responseBytes))
 	}
+	if err != nil {
+		return fmt.Errorf("can not send mail: %w", err)
+	}
+
+	return nil
 }
 
-func AddCmd(
-	flagName string,
-	description string,
-	command func(flagValue string) error,
-	errChan chan error,
-) {
-	readFlag := flag.String(flagName, "", description)
-	flag.Parse()
+// verify mail id, then download, check and decrypt each attachment
+// into the working directory under the name the sender gave it
+func DownloadAttachmentsCmd(mailID string, user string) error {
+	client, err := NewClient(user)
+	if err != nil {
+		return err
+	}
 
-	if *readFlag != "" {
-		err := command(*readFlag)
-		errChan <- err
+	id, err := uuid.Parse(mailID)
+	if err != nil {
+		return fmt.Errorf("invalid mail id: mail id should be uuid")
 	}
-}
 
-func GetInboxCmd(queryPath string, user string) error {
-	// validate user credential should be 64 characters and hex
-	if len(user) != 64 {
-		return fmt.Errorf("invalid user credential: credential should be hex with 64 characters long")
+	// hashes and keys come from the signed data, so the server can not swap the files
+	mail, err := FetchMail(client, id)
+	if err != nil {
+		return err
+	}
+	if len(mail.Attachments) == 0 {
+		fmt.Printf("mail %s has no attachment\n", mail.ID)
+		return nil
 	}
-	for _, c := range user {
-		if c < '0' || c > 'f' {
-			return fmt.Errorf("invalid user credential: credential should be hex with 64 characters long")
+	attachments, err := client.Attachments(mail)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		// name is chosen by the sender, never write outside the working directory
+		// and never overwrite an existing file
+		name := filepath.Base(attachment.Name)
+		if name == "." || name == ".." || name == string(filepath.Separator) {
+			name = attachment.Hash
 		}
+		file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return fmt.Errorf("can not save attachment %s: %w", name, err)
+		}
+		_, err = file.Write(attachment.Content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("can not save attachment %s: %w", name, err)
+		}
+
+		fmt.Printf("saved %s (%d bytes)\n", name, len(attachment.Content))
 	}
 
-	// validate query path
-	if _, err := os.Stat(queryPath); os.IsNotExist(err) {
-		return fmt.Errorf("query file not found, invalid path or file name")
+	return nil
+}
+
+// print headers and body of mail id after checking the sender signature,
+// as json is the mail as the server returned it with content decrypted
+func ReadMailCmd(mailID string, user string, asJSON bool) error {
+	client, err := NewClient(user)
+	if err != nil {
+		return err
 	}
 
-	// validate query json
-	queryFile, err := os.Open(queryPath)
+	id, err := uuid.Parse(mailID)
+	if err != nil {
+		return fmt.Errorf("invalid mail id: mail id should be uuid")
+	}
+
+	mail, err := FetchMail(client, id)
 	if err != nil {
 		return err
 	}
-	defer queryFile.Close()
-	// validate that queryFile contains a valid json
-	var query model.QueryJson
-	err = json.NewDecoder(queryFile).Decode(&query)
-	if err != nil || query.Page == nil || query.Limit == nil {
-		return fmt.Errorf("invalid query json: please use this format\n\t{ \"page\":int, \"limit\":int }")
+
+	if asJSON {
+		mailBytes, err := json.MarshalIndent(mail, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(mailBytes))
+		return nil
 	}
-	if *query.Page <= 0 || *query.Limit <= 0 {
-		return fmt.Errorf("invalid query json: page and limit should be greater than 0")
+
+	fmt.Print(RenderMail(mail, client.Account().GetAddress()))
+
+	return nil
+}
+
+// headers, a blank line and the body of a verified mail, content of a
+// mail sent to someone else is encrypted to that recipient
+func RenderMail(mail model.Mail, user string) string {
+	var out strings.Builder
+	header := func(name string, value string) {
+		if value != "" {
+			fmt.Fprintf(&out, "%-12s %s\n", name+":", value)
+		}
+	}
+
+	subject := mail.Subject
+	body := mail.Body
+	if mail.Recipient != user {
+		subject = "(encrypted to " + mail.Recipient + ")"
+		body = ""
 	}
 
-	acc, err := account.ConnectAccount(user)
+	header("ID", mail.ID.String())
+	header("Date", mail.SentAt)
+	header("From", mail.From+" (signature verified)")
+	header("To", strings.Join(mail.To, ", "))
+	header("Cc", strings.Join(mail.Cc, ", "))
+	header("Bcc", strings.Join(mail.Bcc, ", "))
+	if mail.InReplyTo != nil {
+		header("In-Reply-To", mail.InReplyTo.String())
+	}
+	header("Subject", subject)
+	if len(mail.Attachments) > 0 {
+		header("Attachments", fmt.Sprintf("%d, save them with -download %s", len(mail.Attachments), mail.ID))
+	}
+	fmt.Fprintf(&out, "\n%s\n", body)
+
+	return out.String()
+}
+
+func VerifyMailCmd(mailID string, user string) error {
+	client, err := NewClient(user)
 	if err != nil {
 		return err
 	}
 
-	message, err := request.NewGetInbox()
+	id, err := uuid.Parse(mailID)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid mail id: mail id should be uuid")
 	}
-	signedMessage, err := acc.Sign(message)
+
+	// do not trust the server, check the signature with sender public key
+	mail, err := FetchMail(client, id)
 	if err != nil {
 		return err
 	}
-	requestBody := model.RequestBody{
-		Data:      string(message),
-		Signature: signedMessage,
-	}
-	requestBodyByte, err := json.Marshal(requestBody)
+
+	fmt.Printf("verified: mail %s was signed by sender %s\n", mail.ID, mail.From)
+
+	return nil
+}
+
+// print a kmail file replying to mail id, recipient and subject are
+// filled in from the original mail, write the body and send it with -send
+func ReplyMailCmd(mailID string, user string) error {
+	client, err := NewClient(user)
 	if err != nil {
 		return err
 	}
-	BaseInboxPath := "http://localhost:8080/mail/inbox"
-	queryParams := fmt.Sprintf("?page=%d&limit=%d", *query.Page, *query.Limit)
-	apiPath := BaseInboxPath + queryParams
-	payLoad := strings.NewReader(string(requestBodyByte))
-	request, err := http.NewRequest(http.MethodGet, apiPath, payLoad)
+
+	id, err := uuid.Parse(mailID)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid mail id: mail id should be uuid")
 	}
-	request.Header.Add("x-public-key", acc.GetAddress())
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+	// reply goes to whoever signed the mail, so do not trust the server about it
+	mail, err := FetchMail(client, id)
 	if err != nil {
 		return err
 	}
 
-	// print response body
-	body, err := io.ReadAll(response.Body)
+	// replying to a mail I sent continues the thread with its recipient,
+	// the subject is encrypted to that recipient so it can not be reused
+	to := model.AddressList{mail.From}
+	if mail.From == client.Account().GetAddress() {
+		to = model.AddressList{mail.Recipient}
+	}
+	subject := ""
+	if mail.Recipient == client.Account().GetAddress() {
+		subject = mail.Subject
+	}
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = strings.TrimSpace("Re: " + subject)
+	}
+
+	body := ""
+	inReplyTo := mail.ID.String()
+	reply, err := json.MarshalIndent(model.MailFileContent{
+		To:        to,
+		Subject:   &subject,
+		Body:      &body,
+		InReplyTo: &inReplyTo,
+	}, "", "\t")
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(string(body))
+	fmt.Println(string(reply))
 
 	return nil
 }
 
-func hexToBytes(hexStr string) ([]byte, error) {
-	// Convert the hex string to a big integer
-	bigInt, success := new(big.Int).SetString(hexStr, 16)
-	if !success {
-		return nil, fmt.Errorf("failed to convert hex string to big integer")
+// get one mail by id from the server, checked against the sender signature
+// and decrypted when user is the recipient
+func FetchMail(client *kmail.Client, id uuid.UUID) (model.Mail, error) {
+	mail, err := client.Read(id)
+	if errors.Is(err, kmail.ErrNotFound) {
+		return model.Mail{}, fmt.Errorf("mail %s not found", id)
+	}
+	if errors.Is(err, kmail.ErrInvalidMail) {
+		return model.Mail{}, err
+	}
+	if err != nil {
+		return model.Mail{}, fmt.Errorf("can not read mail %s: %w", id, err)
 	}
 
-	// Convert the big integer to bytes
-	bytes := bigInt.Bytes()
-
-	return bytes, nil
+	return mail, nil
 }
 
-type Account struct {
-	PrivateKey *ecdsa.PrivateKey
-	PublicKey  *ecdsa.PublicKey
-}
+// move mail to trash or restore it, action is request.DeleteEmail or request.RestoreEmail
+func TrashMailCmd(mailID string, user string, action request.ActionName) error {
+	client, err := NewClient(user)
+	if err != nil {
+		return err
+	}
 
-func ConnectAccount(privateKeyHex string) (*Account, error) {
-	// Convert the private key from hex to bytes
-	privateKeyBytes, err := hexToBytes(privateKeyHex)
+	id, err := uuid.Parse(mailID)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("invalid mail id: mail id should be uuid")
 	}
 
-	// Generate the ECDSA private key from the bytes
-	privateKey := new(ecdsa.PrivateKey)
-	privateKey.Curve = elliptic.P256()
-	privateKey.D = new(big.Int).SetBytes(privateKeyBytes)
+	var message []byte
+	var path string
+	if action == request.RestoreEmail {
+		message, err = request.NewRestoreEmail(client.BaseURL(), id)
+		path = "/mail/restore"
+	} else {
+		message, err = request.NewDeleteEmail(client.BaseURL(), id)
+		path = "/mail/delete"
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = client.PostAuthenticated(path, message)
+	if errors.Is(err, kmail.ErrNotFound) {
+		return fmt.Errorf("mail %s not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("can not %s %s: %w", action, id, err)
+	}
 
-	// Compute the public key points
-	privateKey.PublicKey.X, privateKey.PublicKey.Y = privateKey.Curve.ScalarBaseMult(privateKeyBytes)
+	if action == request.RestoreEmail {
+		fmt.Printf("mail %s restored to inbox\n", id)
+	} else {
+		fmt.Printf("mail %s moved to trash\n", id)
+	}
 
-	return &Account{
-		PrivateKey: privateKey,
-		PublicKey:  &privateKey.PublicKey,
-	}, nil
+	return nil
 }
 
-func (a *Account) Sign(data []byte) ([]byte, error) {
-	// Sign the data with the private key
-	r, s, err := ecdsa.Sign(rand.Reader, a.PrivateKey, data)
+// mark mails as read or unread, action is request.MarkRead or request.MarkUnread
+func MarkMailsCmd(mailIDs string, user string, action request.ActionName) error {
+	client, err := NewClient(user)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	var ids []uuid.UUID
+	for _, mailID := range strings.Split(mailIDs, ",") {
+		id, err := uuid.Parse(strings.TrimSpace(mailID))
+		if err != nil {
+			return fmt.Errorf("invalid mail id %q: mail id should be uuid", mailID)
+		}
+		ids = append(ids, id)
 	}
 
-	// Encode the signature
-	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	var message []byte
+	var path string
+	if action == request.MarkUnread {
+		message, err = request.NewMarkUnread(client.BaseURL(), ids)
+		path = "/mail/unread"
+	} else {
+		message, err = request.NewMarkRead(client.BaseURL(), ids)
+		path = "/mail/read"
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return signature, nil
-}
+	body, err := client.PostAuthenticated(path, message)
+	if err != nil {
+		return fmt.Errorf("can not %s: %w", action, err)
+	}
 
-func Verify(publicKey *ecdsa.PublicKey, data, signature []byte) bool {
-	// Decode the signature
-	var decoded struct{ R, S *big.Int }
-	_, err := asn1.Unmarshal(signature, &decoded)
+	var marked request.MarkEmailsResponse
+	err = json.Unmarshal(body, &marked)
 	if err != nil {
-		return false
+		return err
 	}
 
-	// Verify the signature
-	return ecdsa.Verify(publicKey, data, decoded.R, decoded.S)
+	fmt.Printf("%d mails updated\n", marked.Updated)
+
+	return nil
 }
 
-func SendMailCmd(mailPath string, user string) error {
-	// validate mail path
-	if _, err := os.Stat(mailPath); os.IsNotExist(err) {
-		return fmt.Errorf("mail file not found, invalid path or file name")
+func PurgeTrashCmd(user string) error {
+	client, err := NewClient(user)
+	if err != nil {
+		return err
 	}
 
-	// validate mail json
-	mailFile, err := os.Open(mailPath)
+	message, err := request.NewPurgeTrash(client.BaseURL())
 	if err != nil {
 		return err
 	}
-	defer mailFile.Close()
-	var mail model.MailFileContent
-	err = json.NewDecoder(mailFile).Decode(&mail)
-	if err != nil ||
-		mail.To == nil ||
-		mail.Subject == nil ||
-		mail.Body == nil {
-		return fmt.Errorf("invalid kmail json: please use this format\n\t{ \"to\":string, \"subject\":string, \"body\":string }")
+
+	body, err := client.PostAuthenticated("/mail/trash/purge", message)
+	if err != nil {
+		return fmt.Errorf("can not purge trash: %w", err)
 	}
 
-	// validate user credential should be 64 characters and hex
-	if len(user) != 64 {
-		return fmt.Errorf("invalid user credential: credential should be hex with 64 characters long")
+	var purged request.PurgeTrashResponse
+	err = json.Unmarshal(body, &purged)
+	if err != nil {
+		return err
 	}
-	for _, c := range user {
-		if c < '0' || c > 'f' {
-			return fmt.Errorf("invalid user credential: credential should be hex with 64 characters long")
-		}
+
+	fmt.Printf("%d mails permanently deleted from trash\n", purged.Purged)
+
+	return nil
+}
+
+func LoginCmd(scopes string, user string) error {
+	client, err := NewClient(user)
+	if err != nil {
+		return err
 	}
 
-	acc, err := account.ConnectAccount(user)
+	var loginScopes []request.Scope
+	for _, scope := range strings.Split(scopes, ",") {
+		loginScopes = append(loginScopes, request.Scope(strings.TrimSpace(scope)))
+	}
+	err = request.CheckScopes(loginScopes)
 	if err != nil {
 		return err
 	}
 
-	message, err := request.NewSendEmail(
-		*mail.To,
-		*mail.Subject,
-		*mail.Body,
-	)
+	nonce, err := FetchChallenge(client)
 	if err != nil {
 		return err
 	}
-	signedMessage, err := acc.Sign(message)
+	message, err := request.NewLogin(client.BaseURL(), nonce, loginScopes)
 	if err != nil {
 		return err
 	}
-	requestBody := model.RequestBody{
-		Data:      string(message),
-		Signature: signedMessage,
+
+	body, err := client.PostSigned("/auth/login", message)
+	if err != nil {
+		return fmt.Errorf("can not login: %w", err)
 	}
-	requestBodyByte, err := json.Marshal(requestBody)
+
+	var session request.LoginResponse
+	err = json.Unmarshal(body, &session)
 	if err != nil {
 		return err
 	}
-	BaseSendMailPath := "http://localhost:8080/mail/send"
-	payLoad := strings.NewReader(string(requestBodyByte))
-	request, err := http.NewRequest(http.MethodPost, BaseSendMailPath, payLoad)
+
+	fmt.Println(session.Token)
+	fmt.Fprintf(os.Stderr, "session of %s with scopes %v expires at %s\n", session.Address, session.Scopes, session.ExpiresAt)
+
+	return nil
+}
+
+// revoke Token, no identity is needed
+func LogoutCmd() error {
+	client := kmail.NewClient(ServerURL, &http.Client{}, nil).WithToken(Token)
+	_, err := client.Logout()
+	if err != nil {
+		return fmt.Errorf("can not logout: %w", err)
+	}
+
+	fmt.Println("session token revoked")
+
+	return nil
+}
+
+func RevokeSessionsCmd(user string) error {
+	client, err := NewClient(user)
 	if err != nil {
 		return err
 	}
-	request.Header.Add("x-public-key", acc.GetAddress())
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+	nonce, err := FetchChallenge(client)
+	if err != nil {
+		return err
+	}
+	message, err := request.NewRevokeSessions(client.BaseURL(), nonce)
 	if err != nil {
 		return err
 	}
 
-	// print response body
-	body, err := io.ReadAll(response.Body)
+	body, err := client.PostSigned("/auth/revoke", message)
+	if err != nil {
+		return fmt.Errorf("can not revoke sessions: %w", err)
+	}
+
+	var revoked request.RevokeSessionsResponse
+	err = json.Unmarshal(body, &revoked)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(string(body))
+	fmt.Printf("%d sessions revoked\n", revoked.Revoked)
 
 	return nil
 }
+
+// get a single use nonce to sign for login or revoke sessions
+func FetchChallenge(client *kmail.Client) (string, error) {
+	body, err := client.Post("/auth/challenge")
+	if err != nil {
+		return "", fmt.Errorf("can not get challenge: %w", err)
+	}
+
+	var challenge request.ChallengeResponse
+	err = json.Unmarshal(body, &challenge)
+	if err != nil {
+		return "", err
+	}
+
+	return challenge.Nonce, nil
+}
@@ -1,9 +1,11 @@
 package account
 
 import (
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/hex"
@@ -12,48 +14,27 @@ import (
 	"math/big"
 )
 
-func hexToBytes(hexStr string) ([]byte, error) {
-	// Convert the hex string to a big integer
-	bigInt, success := new(big.Int).SetString(hexStr, 16)
-	if !success {
-		return nil, fmt.Errorf("failed to convert hex string to big integer")
-	}
-
-	// Convert the big integer to bytes
-	bytes := bigInt.Bytes()
-
-	return bytes, nil
-}
-
 type Account struct {
 	PrivateKey *ecdsa.PrivateKey
 	PublicKey  *ecdsa.PublicKey
 }
 
+// private key is fixed width hex of a P-256 scalar, see ErrInvalidPrivateKey
 func ConnectAccount(privateKeyHex string) (*Account, error) {
-	// Convert the private key from hex to bytes
-	privateKeyBytes, err := hexToBytes(privateKeyHex)
-	if err != nil {
-		return nil, err
+	privateKeyBytes, err := hex.DecodeString(privateKeyHex)
+	if err != nil || len(privateKeyBytes) != privateKeyLength {
+		return nil, ErrInvalidPrivateKey
 	}
 
-	// Generate the ECDSA private key from the bytes
-	privateKey := new(ecdsa.PrivateKey)
-	privateKey.Curve = elliptic.P256()
-	privateKey.D = new(big.Int).SetBytes(privateKeyBytes)
-
-	// Compute the public key points
-	privateKey.PublicKey.X, privateKey.PublicKey.Y = privateKey.Curve.ScalarBaseMult(privateKeyBytes)
-
-	return &Account{
-		PrivateKey: privateKey,
-		PublicKey:  &privateKey.PublicKey,
-	}, nil
+	return newAccount(privateKeyBytes)
 }
 
+// sign SHA-256 digest of data, ecdsa would otherwise only
+// sign the first 32 bytes of data and ignore the rest
 func (a *Account) Sign(data []byte) ([]byte, error) {
 	// Sign the data with the private key
-	r, s, err := ecdsa.Sign(rand.Reader, a.PrivateKey, data)
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, a.PrivateKey, digest[:])
 	if err != nil {
 		return nil, err
 	}
@@ -67,9 +48,9 @@ func (a *Account) Sign(data []byte) ([]byte, error) {
 	return signature, nil
 }
 
-// get public key address in hex format
+// get canonical public key address, see Address
 func (a *Account) GetAddress() string {
-	return PublicKeyToHex(a.PublicKey)
+	return PublicKeyToAddress(a.PublicKey).String()
 }
 
 func Verify(publicKey *ecdsa.PublicKey, data, signature []byte) bool {
@@ -81,7 +62,8 @@ func Verify(publicKey *ecdsa.PublicKey, data, signature []byte) bool {
 	}
 
 	// Verify the signature
-	return ecdsa.Verify(publicKey, data, decoded.R, decoded.S)
+	digest := sha256.Sum256(data)
+	return ecdsa.Verify(publicKey, digest[:], decoded.R, decoded.S)
 }
 
 func ExportPublicKeyPEM(publicKey *ecdsa.PublicKey) string {
@@ -98,15 +80,28 @@ func ExportPublicKeyPEM(publicKey *ecdsa.PublicKey) string {
 	return string(publicKeyPEM)
 }
 
+// length of X || Y of an uncompressed P-256 public key
+const uncompressedPublicKeyLength = 64
+
+// parse fixed width hex of X || Y and check the point is on P-256
 func HexToPublicKey(hexStr string) (*ecdsa.PublicKey, error) {
 	// Convert the hex string back to bytes
 	bytes, err := hex.DecodeString(hexStr)
 	if err != nil {
 		return nil, err
 	}
+	if len(bytes) != uncompressedPublicKeyLength {
+		return nil, fmt.Errorf("public key should be %d bytes", uncompressedPublicKeyLength)
+	}
+
+	// ecdh rejects points that are not on the curve
+	_, err = ecdh.P256().NewPublicKey(append([]byte{4}, bytes...))
+	if err != nil {
+		return nil, fmt.Errorf("public key is not on P-256 curve")
+	}
 
 	// Split the bytes back into X and Y coordinates
-	xBytes, yBytes := bytes[:len(bytes)/2], bytes[len(bytes)/2:]
+	xBytes, yBytes := bytes[:uncompressedPublicKeyLength/2], bytes[uncompressedPublicKeyLength/2:]
 
 	// Convert the bytes to big.Int
 	x := new(big.Int).SetBytes(xBytes)
@@ -122,9 +117,12 @@ func HexToPublicKey(hexStr string) (*ecdsa.PublicKey, error) {
 	return pubKey, nil
 }
 
+// fixed width hex of X || Y, each coordinate padded to 32 bytes
 func PublicKeyToHex(pubKey *ecdsa.PublicKey) string {
 	// Concatenate the X and Y coordinates
-	concat := append(pubKey.X.Bytes(), pubKey.Y.Bytes()...)
+	concat := make([]byte, uncompressedPublicKeyLength)
+	pubKey.X.FillBytes(concat[:uncompressedPublicKeyLength/2])
+	pubKey.Y.FillBytes(concat[uncompressedPublicKeyLength/2:])
 
 	// Convert the bytes to a hex string
 	hexStr := hex.EncodeToString(concat)
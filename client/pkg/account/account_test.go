@@ -2,6 +2,7 @@ package account_test
 
 import (
 	"passwordless-mail-client/pkg/account"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,3 +25,230 @@ func TestAccount(t *testing.T) {
 		assert.Equal(t, testAccount.PublicKey, publicKey)
 	})
 }
+
+func TestEncryptMail(t *testing.T) {
+	const OtherPrivateKey = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+
+	t.Run("should decrypt mail encrypted to the account public key", func(t *testing.T) {
+		// Arrange
+		recipient, connectErr := account.ConnectAccount(TestPrivateKey)
+		subject := "test subject"
+		body := "test body"
+
+		// Act
+		sealed, encryptErr := account.EncryptMail(recipient.PublicKey, subject, body)
+		decryptedSubject, decryptedBody, decryptErr := recipient.DecryptMail(*sealed)
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.NoError(t, encryptErr)
+		assert.NoError(t, decryptErr)
+		assert.NotEqual(t, subject, sealed.Subject)
+		assert.NotEqual(t, body, sealed.Body)
+		assert.Equal(t, subject, decryptedSubject)
+		assert.Equal(t, body, decryptedBody)
+	})
+
+	t.Run("should not decrypt mail encrypted to another public key", func(t *testing.T) {
+		// Arrange
+		recipient, connectErr1 := account.ConnectAccount(TestPrivateKey)
+		other, connectErr2 := account.ConnectAccount(OtherPrivateKey)
+
+		// Act
+		sealed, encryptErr := account.EncryptMail(recipient.PublicKey, "test subject", "test body")
+		_, _, decryptErr := other.DecryptMail(*sealed)
+
+		// Assert
+		assert.NoError(t, connectErr1)
+		assert.NoError(t, connectErr2)
+		assert.NoError(t, encryptErr)
+		assert.Error(t, decryptErr)
+	})
+
+	t.Run("should not decrypt mail when subject and body are swapped", func(t *testing.T) {
+		// Arrange
+		recipient, connectErr := account.ConnectAccount(TestPrivateKey)
+		sealed, encryptErr := account.EncryptMail(recipient.PublicKey, "test subject", "test body")
+		swapped := account.SealedMail{
+			EphemeralKey: sealed.EphemeralKey,
+			Subject:      sealed.Body,
+			Body:         sealed.Subject,
+		}
+
+		// Act
+		_, _, decryptErr := recipient.DecryptMail(swapped)
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.NoError(t, encryptErr)
+		assert.Error(t, decryptErr)
+	})
+
+	t.Run("should decrypt attachment keys only when the mail has them", func(t *testing.T) {
+		// Arrange
+		recipient, connectErr := account.ConnectAccount(TestPrivateKey)
+		attachmentKeys := `[{"hash":"test hash","name":"test.txt"}]`
+
+		// Act
+		sealed, encryptErr1 := account.EncryptMailWithAttachments(recipient.PublicKey, "test subject", "test body", attachmentKeys)
+		plain, encryptErr2 := account.EncryptMail(recipient.PublicKey, "test subject", "test body")
+		decrypted, decryptErr1 := recipient.DecryptAttachmentKeys(*sealed)
+		empty, decryptErr2 := recipient.DecryptAttachmentKeys(*plain)
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.NoError(t, encryptErr1)
+		assert.NoError(t, encryptErr2)
+		assert.NoError(t, decryptErr1)
+		assert.NoError(t, decryptErr2)
+		assert.NotEqual(t, attachmentKeys, sealed.AttachmentKeys)
+		assert.Equal(t, attachmentKeys, decrypted)
+		assert.Empty(t, plain.AttachmentKeys)
+		assert.Empty(t, empty)
+	})
+}
+
+func TestEncryptAttachment(t *testing.T) {
+	t.Run("should decrypt attachment with its key", func(t *testing.T) {
+		// Arrange
+		content := []byte("test attachment")
+
+		// Act
+		ciphertext, key, encryptErr := account.EncryptAttachment(content)
+		decrypted, decryptErr := account.DecryptAttachment(ciphertext, key)
+
+		// Assert
+		assert.NoError(t, encryptErr)
+		assert.NoError(t, decryptErr)
+		assert.NotContains(t, string(ciphertext), string(content))
+		assert.Equal(t, content, decrypted)
+	})
+
+	t.Run("should not decrypt attachment with another key", func(t *testing.T) {
+		// Arrange
+		ciphertext, _, encryptErr1 := account.EncryptAttachment([]byte("test attachment"))
+		_, otherKey, encryptErr2 := account.EncryptAttachment([]byte("other attachment"))
+
+		// Act
+		_, decryptErr := account.DecryptAttachment(ciphertext, otherKey)
+
+		// Assert
+		assert.NoError(t, encryptErr1)
+		assert.NoError(t, encryptErr2)
+		assert.Error(t, decryptErr)
+	})
+}
+
+func TestAddress(t *testing.T) {
+	// public key X coordinate of this key starts with a zero byte
+	const LeadingZeroPrivateKey = "1baa00000000000000000000000000000000000000000000000000000000006b"
+
+	t.Run("should convert public key to address and parse it back", func(t *testing.T) {
+		// Arrange
+		testAccount, connectErr := account.ConnectAccount(TestPrivateKey)
+
+		// Act
+		address := account.PublicKeyToAddress(testAccount.PublicKey)
+		publicKey, parseErr := account.ParseAddress(address.String())
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.NoError(t, parseErr)
+		assert.True(t, strings.HasPrefix(address.String(), account.AddressPrefix+"1"))
+		assert.Equal(t, address.String(), testAccount.GetAddress())
+		assert.True(t, testAccount.PublicKey.Equal(publicKey))
+	})
+
+	t.Run("should keep fixed width when public key coordinate has leading zero", func(t *testing.T) {
+		// Arrange
+		testAccount, connectErr := account.ConnectAccount(LeadingZeroPrivateKey)
+		otherAccount, otherConnectErr := account.ConnectAccount(TestPrivateKey)
+
+		// Act
+		hexKey := account.PublicKeyToHex(testAccount.PublicKey)
+		hexPublicKey, hexParseErr := account.HexToPublicKey(hexKey)
+		address := account.PublicKeyToAddress(testAccount.PublicKey)
+		addressPublicKey, addressParseErr := account.ParseAddress(address.String())
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.NoError(t, otherConnectErr)
+		assert.NoError(t, hexParseErr)
+		assert.NoError(t, addressParseErr)
+		assert.Equal(t, 128, len(hexKey))
+		assert.Equal(t, len(otherAccount.GetAddress()), len(address.String()))
+		assert.True(t, testAccount.PublicKey.Equal(hexPublicKey))
+		assert.True(t, testAccount.PublicKey.Equal(addressPublicKey))
+	})
+
+	t.Run("should reject address with invalid checksum", func(t *testing.T) {
+		// Arrange
+		testAccount, connectErr := account.ConnectAccount(TestPrivateKey)
+		address := testAccount.GetAddress()
+		lastChar := address[len(address)-1]
+		typo := byte('q')
+		if lastChar == 'q' {
+			typo = 'p'
+		}
+		badAddress := address[:len(address)-1] + string(typo)
+
+		// Act
+		publicKey, parseErr := account.ParseAddress(badAddress)
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.Nil(t, publicKey)
+		assert.ErrorContains(t, parseErr, "checksum")
+	})
+
+	t.Run("should reject address with wrong length, prefix or mixed case", func(t *testing.T) {
+		// Arrange
+		testAccount, connectErr := account.ConnectAccount(TestPrivateKey)
+		address := testAccount.GetAddress()
+		badAddresses := []string{
+			"",
+			address[:len(address)-2],
+			"bad key heehee! ow!",
+			account.PublicKeyToHex(testAccount.PublicKey),
+			strings.ToUpper(address[:10]) + address[10:],
+			"mail" + address[len(account.AddressPrefix):],
+		}
+
+		// Act & Assert
+		assert.NoError(t, connectErr)
+		for _, badAddress := range badAddresses {
+			publicKey, parseErr := account.ParseAddress(badAddress)
+			assert.Nil(t, publicKey, badAddress)
+			assert.Error(t, parseErr, badAddress)
+		}
+	})
+
+	t.Run("should accept upper case address", func(t *testing.T) {
+		// Arrange
+		testAccount, connectErr := account.ConnectAccount(TestPrivateKey)
+
+		// Act
+		publicKey, parseErr := account.ParseAddress(strings.ToUpper(testAccount.GetAddress()))
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.NoError(t, parseErr)
+		assert.True(t, testAccount.PublicKey.Equal(publicKey))
+	})
+
+	t.Run("should reject hex public key that is not on the curve", func(t *testing.T) {
+		// Arrange
+		offCurveKey := strings.Repeat("01", 64)
+		shortKey := strings.Repeat("01", 63)
+
+		// Act
+		offCurvePublicKey, offCurveErr := account.HexToPublicKey(offCurveKey)
+		shortPublicKey, shortErr := account.HexToPublicKey(shortKey)
+
+		// Assert
+		assert.Nil(t, offCurvePublicKey)
+		assert.Error(t, offCurveErr)
+		assert.Nil(t, shortPublicKey)
+		assert.Error(t, shortErr)
+	})
+}
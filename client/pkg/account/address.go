@@ -0,0 +1,64 @@
+package account
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+)
+
+// human readable prefix of every address, e.g. kmail1q...
+const AddressPrefix = "kmail"
+
+// length of a compressed P-256 public key
+const compressedPublicKeyLength = 33
+
+// canonical account address: bech32m with prefix "kmail"
+// of the 33 byte compressed P-256 public key
+type Address string
+
+func PublicKeyToAddress(publicKey *ecdsa.PublicKey) Address {
+	compressed := elliptic.MarshalCompressed(elliptic.P256(), publicKey.X, publicKey.Y)
+
+	// 8 bit to 5 bit with padding never fails
+	data, _ := convertBits(compressed, 8, 5, true)
+
+	return Address(bech32Encode(AddressPrefix, data))
+}
+
+// validate prefix, checksum, length and that the key is on P-256
+func ParseAddress(address string) (*ecdsa.PublicKey, error) {
+	hrp, data, err := bech32Decode(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	if hrp != AddressPrefix {
+		return nil, fmt.Errorf("invalid address: prefix should be %s", AddressPrefix)
+	}
+
+	compressed, err := convertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	if len(compressed) != compressedPublicKeyLength {
+		return nil, fmt.Errorf("invalid address: public key should be %d bytes", compressedPublicKeyLength)
+	}
+
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), compressed)
+	if x == nil {
+		return nil, fmt.Errorf("invalid address: public key is not on P-256 curve")
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     x,
+		Y:     y,
+	}, nil
+}
+
+func (a Address) PublicKey() (*ecdsa.PublicKey, error) {
+	return ParseAddress(string(a))
+}
+
+func (a Address) String() string {
+	return string(a)
+}
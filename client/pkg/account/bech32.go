@@ -0,0 +1,140 @@
+package account
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bech32m (BIP-350) encoding used for account addresses,
+// the 6 character checksum detects any typo of up to 4 characters
+
+const (
+	bech32Charset        = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32mConstant      = 0x2bc830a3
+	bech32MaxLength      = 90
+	bech32ChecksumLength = 6
+)
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+
+	return checksum
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c>>5))
+	}
+	expanded = append(expanded, 0)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c&31))
+	}
+
+	return expanded
+}
+
+func bech32Checksum(hrp string, data []byte) []byte {
+	values := append(bech32HrpExpand(hrp), data...)
+	values = append(values, make([]byte, bech32ChecksumLength)...)
+	polymod := bech32Polymod(values) ^ bech32mConstant
+
+	checksum := make([]byte, bech32ChecksumLength)
+	for i := range checksum {
+		checksum[i] = byte((polymod >> (5 * (5 - i))) & 31)
+	}
+
+	return checksum
+}
+
+// data is 5 bit groups, see convertBits
+func bech32Encode(hrp string, data []byte) string {
+	combined := append(append([]byte{}, data...), bech32Checksum(hrp, data)...)
+
+	var encoded strings.Builder
+	encoded.WriteString(hrp)
+	encoded.WriteByte('1')
+	for _, value := range combined {
+		encoded.WriteByte(bech32Charset[value])
+	}
+
+	return encoded.String()
+}
+
+// return hrp and 5 bit groups without checksum
+func bech32Decode(encoded string) (string, []byte, error) {
+	if len(encoded) > bech32MaxLength {
+		return "", nil, fmt.Errorf("address is too long")
+	}
+	if strings.ToLower(encoded) != encoded && strings.ToUpper(encoded) != encoded {
+		return "", nil, fmt.Errorf("address has mixed case")
+	}
+	encoded = strings.ToLower(encoded)
+
+	separator := strings.LastIndexByte(encoded, '1')
+	if separator < 1 || separator+bech32ChecksumLength+1 > len(encoded) {
+		return "", nil, fmt.Errorf("address has invalid separator position")
+	}
+
+	hrp := encoded[:separator]
+	for _, c := range hrp {
+		if c < 33 || c > 126 {
+			return "", nil, fmt.Errorf("address has invalid prefix character")
+		}
+	}
+
+	data := make([]byte, 0, len(encoded)-separator-1)
+	for _, c := range encoded[separator+1:] {
+		value := strings.IndexRune(bech32Charset, c)
+		if value < 0 {
+			return "", nil, fmt.Errorf("address has invalid character %q", c)
+		}
+		data = append(data, byte(value))
+	}
+
+	if bech32Polymod(append(bech32HrpExpand(hrp), data...)) != bech32mConstant {
+		return "", nil, fmt.Errorf("address has invalid checksum")
+	}
+
+	return hrp, data[:len(data)-bech32ChecksumLength], nil
+}
+
+// regroup bits, e.g. 8 bit bytes to 5 bit bech32 groups and back
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	accumulator := uint32(0)
+	bits := uint(0)
+	maxValue := uint32(1)<<toBits - 1
+	converted := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+
+	for _, value := range data {
+		if uint32(value)>>fromBits != 0 {
+			return nil, fmt.Errorf("invalid data value %d", value)
+		}
+		accumulator = accumulator<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			converted = append(converted, byte(accumulator>>bits&maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			converted = append(converted, byte(accumulator<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || accumulator<<(toBits-bits)&maxValue != 0 {
+		return nil, fmt.Errorf("invalid padding")
+	}
+
+	return converted, nil
+}
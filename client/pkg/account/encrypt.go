@@ -0,0 +1,235 @@
+package account
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// additional data bound to each encrypted field,
+// so a subject ciphertext can not be swapped into the body
+const (
+	subjectAdditionalData        = "kmail subject"
+	bodyAdditionalData           = "kmail body"
+	attachmentKeysAdditionalData = "kmail attachment keys"
+	attachmentAdditionalData     = "kmail attachment"
+)
+
+// mail content encrypted to the recipient public key,
+// ephemeral key is the hex of an uncompressed P-256 point,
+// subject, body and attachment keys are base64(nonce || ciphertext).
+// attachment keys is empty when the mail has no attachment
+type SealedMail struct {
+	EphemeralKey   string
+	Subject        string
+	Body           string
+	AttachmentKeys string
+}
+
+// encrypt subject and body with an ECIES-style scheme:
+// ephemeral P-256 key -> ECDH with recipient -> SHA-256 KDF -> AES-256-GCM
+func EncryptMail(recipient *ecdsa.PublicKey, subject string, body string) (*SealedMail, error) {
+	return EncryptMailWithAttachments(recipient, subject, body, "")
+}
+
+// same as EncryptMail, attachment keys is the plaintext that lets the
+// recipient find and decrypt the attachments, it is left out when empty
+func EncryptMailWithAttachments(recipient *ecdsa.PublicKey, subject string, body string, attachmentKeys string) (*SealedMail, error) {
+	recipientKey, err := recipient.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient public key: %w", err)
+	}
+
+	ephemeralKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedSecret, err := ephemeralKey.ECDH(recipientKey)
+	if err != nil {
+		return nil, err
+	}
+	key := deriveMailKey(sharedSecret, ephemeralKey.PublicKey(), recipientKey)
+
+	encryptedSubject, err := seal(key, []byte(subject), subjectAdditionalData)
+	if err != nil {
+		return nil, err
+	}
+	encryptedBody, err := seal(key, []byte(body), bodyAdditionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedAttachmentKeys := ""
+	if attachmentKeys != "" {
+		encryptedAttachmentKeys, err = seal(key, []byte(attachmentKeys), attachmentKeysAdditionalData)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &SealedMail{
+		EphemeralKey:   hex.EncodeToString(ephemeralKey.PublicKey().Bytes()),
+		Subject:        encryptedSubject,
+		Body:           encryptedBody,
+		AttachmentKeys: encryptedAttachmentKeys,
+	}, nil
+}
+
+// decrypt mail that was encrypted to this account public key
+func (a *Account) DecryptMail(mail SealedMail) (string, string, error) {
+	key, err := a.mailKey(mail.EphemeralKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	subject, err := open(key, mail.Subject, subjectAdditionalData)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := open(key, mail.Body, bodyAdditionalData)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(subject), string(body), nil
+}
+
+// decrypt attachment keys of a mail, empty when the mail has no attachment
+func (a *Account) DecryptAttachmentKeys(mail SealedMail) (string, error) {
+	if mail.AttachmentKeys == "" {
+		return "", nil
+	}
+
+	key, err := a.mailKey(mail.EphemeralKey)
+	if err != nil {
+		return "", err
+	}
+	attachmentKeys, err := open(key, mail.AttachmentKeys, attachmentKeysAdditionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return string(attachmentKeys), nil
+}
+
+// encrypt attachment content with a new random AES-256-GCM key,
+// ciphertext is nonce || ciphertext and can be shared by every recipient
+// because the key is only sent encrypted to each of them
+func EncryptAttachment(content []byte) ([]byte, []byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, err := sealBytes(key, content, attachmentAdditionalData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ciphertext, key, nil
+}
+
+func DecryptAttachment(ciphertext []byte, key []byte) ([]byte, error) {
+	return openBytes(key, ciphertext, attachmentAdditionalData)
+}
+
+// key of a mail sent to this account with the given ephemeral key
+func (a *Account) mailKey(ephemeralKeyHex string) ([]byte, error) {
+	ephemeralKeyBytes, err := hex.DecodeString(ephemeralKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+	ephemeralKey, err := ecdh.P256().NewPublicKey(ephemeralKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+	privateKey, err := a.PrivateKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+
+	sharedSecret, err := privateKey.ECDH(ephemeralKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return deriveMailKey(sharedSecret, ephemeralKey, privateKey.PublicKey()), nil
+}
+
+// key = SHA-256(shared secret || ephemeral public key || recipient public key)
+func deriveMailKey(sharedSecret []byte, ephemeralKey *ecdh.PublicKey, recipientKey *ecdh.PublicKey) []byte {
+	hash := sha256.New()
+	hash.Write(sharedSecret)
+	hash.Write(ephemeralKey.Bytes())
+	hash.Write(recipientKey.Bytes())
+
+	return hash.Sum(nil)
+}
+
+func seal(key []byte, plaintext []byte, additionalData string) (string, error) {
+	sealed, err := sealBytes(key, plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func open(key []byte, ciphertext string, additionalData string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	return openBytes(key, sealed, additionalData)
+}
+
+func sealBytes(key []byte, plaintext []byte, additionalData string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, []byte(additionalData)), nil
+}
+
+func openBytes(key []byte, sealed []byte, additionalData string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid ciphertext: too short")
+	}
+
+	nonce, encrypted := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, encrypted, []byte(additionalData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt mail: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
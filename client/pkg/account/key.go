@@ -0,0 +1,106 @@
+package account
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// length of a P-256 private key scalar
+const privateKeyLength = 32
+
+var ErrInvalidPrivateKey = errors.New("invalid private key: private key should be a P-256 scalar as hex with 64 characters long")
+
+// new account with a private key from crypto/rand
+func GenerateAccount() (*Account, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccount(privateKey.D.FillBytes(make([]byte, privateKeyLength)))
+}
+
+// check the scalar is in [1, N-1] of P-256, ecdh rejects anything else,
+// then compute its public key
+func newAccount(privateKeyBytes []byte) (*Account, error) {
+	_, err := ecdh.P256().NewPrivateKey(privateKeyBytes)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	privateKey := new(ecdsa.PrivateKey)
+	privateKey.Curve = elliptic.P256()
+	privateKey.D = new(big.Int).SetBytes(privateKeyBytes)
+	privateKey.PublicKey.X, privateKey.PublicKey.Y = privateKey.Curve.ScalarBaseMult(privateKeyBytes)
+
+	return &Account{
+		PrivateKey: privateKey,
+		PublicKey:  &privateKey.PublicKey,
+	}, nil
+}
+
+// fixed width hex of the private key, what ConnectAccount reads
+func (a *Account) PrivateKeyHex() string {
+	return hex.EncodeToString(a.PrivateKey.D.FillBytes(make([]byte, privateKeyLength)))
+}
+
+// private key as PKCS#8 PEM, see ExportPublicKeyPEM for the public key
+func (a *Account) ExportPrivateKeyPEM() (string, error) {
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(a.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: privateKeyBytes,
+	})), nil
+}
+
+// read a private key as PKCS#8 or SEC 1 PEM, or as hex
+func ImportPrivateKey(data []byte) (*Account, error) {
+	text := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(text, "-----BEGIN") {
+		return ConnectAccount(text)
+	}
+
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key: can not decode PEM")
+	}
+	var privateKey *ecdsa.PrivateKey
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		ecdsaKey, ok := parsed.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("invalid private key: private key should be ECDSA P-256")
+		}
+		privateKey = ecdsaKey
+	case "EC PRIVATE KEY":
+		parsed, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		privateKey = parsed
+	default:
+		return nil, fmt.Errorf("invalid private key: unsupported PEM type %s", block.Type)
+	}
+	if privateKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("invalid private key: private key should be ECDSA P-256")
+	}
+
+	return newAccount(privateKey.D.FillBytes(make([]byte, privateKeyLength)))
+}
@@ -0,0 +1,190 @@
+package account
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// entropy of a new mnemonic, 256 bits are 24 words
+const mnemonicEntropyLength = 32
+
+// indexes from here on are hardened in SLIP-10, every derivation index is hardened
+const maxDerivationIndex = 1<<31 - 1
+
+var (
+	ErrInvalidMnemonic       = errors.New("invalid mnemonic: mnemonic should be 12 to 24 words of the BIP39 english word list")
+	ErrInvalidMnemonicIndex  = fmt.Errorf("invalid derivation index: index should be between 0 and %d", maxDerivationIndex)
+	ErrMnemonicChecksum      = errors.New("invalid mnemonic: checksum does not match, a word may be misspelled or out of order")
+	errInvalidMnemonicLength = errors.New("entropy should be 16 to 32 bytes in steps of 4")
+)
+
+// BIP39 english word list, https://github.com/bitcoin/bips/blob/master/bip-0039/english.txt
+//
+//go:embed english.txt
+var englishWordList string
+
+var (
+	mnemonicWords     = strings.Fields(englishWordList)
+	mnemonicWordIndex = wordIndex(mnemonicWords)
+)
+
+func wordIndex(words []string) map[string]int {
+	index := make(map[string]int, len(words))
+	for i, word := range words {
+		index[word] = i
+	}
+
+	return index
+}
+
+// new 24 words mnemonic from crypto/rand
+func NewMnemonic() (string, error) {
+	entropy := make([]byte, mnemonicEntropyLength)
+	_, err := rand.Read(entropy)
+	if err != nil {
+		return "", err
+	}
+
+	return MnemonicFromEntropy(entropy)
+}
+
+// BIP39 mnemonic of entropy, the words are 11 bits each of the entropy
+// followed by the first len(entropy)/4 bits of its SHA-256
+func MnemonicFromEntropy(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", errInvalidMnemonicLength
+	}
+
+	checksumBits := len(entropy) / 4
+	checksum := sha256.Sum256(entropy)
+	bits := new(big.Int).SetBytes(entropy)
+	bits.Lsh(bits, uint(checksumBits))
+	bits.Or(bits, big.NewInt(int64(checksum[0]>>(8-checksumBits))))
+
+	words := make([]string, (len(entropy)*8+checksumBits)/11)
+	mask := big.NewInt(1<<11 - 1)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = mnemonicWords[new(big.Int).And(bits, mask).Int64()]
+		bits.Rsh(bits, 11)
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// check words of mnemonic are in the word list and their checksum matches
+func ValidateMnemonic(mnemonic string) error {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return ErrInvalidMnemonic
+	}
+
+	bits := new(big.Int)
+	for _, word := range words {
+		index, ok := mnemonicWordIndex[word]
+		if !ok {
+			return fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, word)
+		}
+		bits.Lsh(bits, 11)
+		bits.Or(bits, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) * 11 / 33
+	checksum := new(big.Int).And(bits, big.NewInt(1<<checksumBits-1))
+	entropy := new(big.Int).Rsh(bits, uint(checksumBits)).FillBytes(make([]byte, checksumBits*4))
+	expected := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(expected[0]>>(8-checksumBits)) {
+		return ErrMnemonicChecksum
+	}
+
+	return nil
+}
+
+// BIP39 seed of mnemonic and passphrase, a different passphrase gives
+// a different seed so it has to be kept along with the words
+func MnemonicSeed(mnemonic string, passphrase string) ([]byte, error) {
+	err := ValidateMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := norm.NFKD.String(strings.Join(strings.Fields(strings.ToLower(mnemonic)), " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+
+	return pbkdf2.Key([]byte(normalized), []byte(salt), 2048, 64, sha512.New), nil
+}
+
+// account at index of mnemonic, the same words, passphrase and index
+// always give the same account so the phrase is a backup of every index
+func AccountFromMnemonic(mnemonic string, passphrase string, index uint32) (*Account, error) {
+	seed, err := MnemonicSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return AccountFromSeed(seed, index)
+}
+
+// SLIP-10 nist256p1 private key of seed at path m/index'
+func AccountFromSeed(seed []byte, index uint32) (*Account, error) {
+	if index > maxDerivationIndex {
+		return nil, ErrInvalidMnemonicIndex
+	}
+
+	key, chainCode := slip10MasterKey(seed)
+	key, _ = slip10HardenedChild(key, chainCode, index)
+
+	return newAccount(key)
+}
+
+func slip10MasterKey(seed []byte) ([]byte, []byte) {
+	sum := hmacSHA512([]byte("Nist256p1 seed"), seed)
+	for !isValidScalar(sum[:32]) {
+		sum = hmacSHA512([]byte("Nist256p1 seed"), sum)
+	}
+
+	return sum[:32], sum[32:]
+}
+
+// child keys are only derived hardened, they can not be computed from a public key
+func slip10HardenedChild(key []byte, chainCode []byte, index uint32) ([]byte, []byte) {
+	n := elliptic.P256().Params().N
+	data := append([]byte{0}, key...)
+	for {
+		data = binary.BigEndian.AppendUint32(data, index|1<<31)
+		sum := hmacSHA512(chainCode, data)
+		child := new(big.Int).SetBytes(sum[:32])
+		if child.Cmp(n) < 0 {
+			child.Add(child, new(big.Int).SetBytes(key))
+			child.Mod(child, n)
+			if child.Sign() != 0 {
+				return child.FillBytes(make([]byte, privateKeyLength)), sum[32:]
+			}
+		}
+		data = append([]byte{1}, sum[32:]...)
+	}
+}
+
+func isValidScalar(scalar []byte) bool {
+	value := new(big.Int).SetBytes(scalar)
+
+	return value.Sign() != 0 && value.Cmp(elliptic.P256().Params().N) < 0
+}
+
+func hmacSHA512(key []byte, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,102 @@
+package account_test
+
+import (
+	"encoding/hex"
+	"passwordless-mail-client/pkg/account"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMnemonic(t *testing.T) {
+	// BIP39 test vectors, seeds use passphrase TREZOR
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			entropy:  "80808080808080808080808080808080",
+			mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+			seed:     "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+		},
+	}
+
+	t.Run("should match BIP39 test vectors", func(t *testing.T) {
+		for _, vector := range vectors {
+			// Arrange
+			entropy, decodeErr := hex.DecodeString(vector.entropy)
+
+			// Act
+			mnemonic, mnemonicErr := account.MnemonicFromEntropy(entropy)
+			seed, seedErr := account.MnemonicSeed(vector.mnemonic, "TREZOR")
+
+			// Assert
+			assert.NoError(t, decodeErr)
+			assert.NoError(t, mnemonicErr)
+			assert.NoError(t, seedErr)
+			assert.Equal(t, vector.mnemonic, mnemonic)
+			assert.Equal(t, vector.seed, hex.EncodeToString(seed))
+		}
+	})
+
+	t.Run("should match SLIP-10 nist256p1 test vector", func(t *testing.T) {
+		// Arrange
+		seed, decodeErr := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+		// Act
+		derived, deriveErr := account.AccountFromSeed(seed, 0)
+
+		// Assert
+		assert.NoError(t, decodeErr)
+		assert.NoError(t, deriveErr)
+		assert.Equal(t, "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c", derived.PrivateKeyHex())
+	})
+
+	t.Run("should restore the same account from the same words, passphrase and index", func(t *testing.T) {
+		// Arrange
+		mnemonic, newErr := account.NewMnemonic()
+
+		// Act
+		created, createErr := account.AccountFromMnemonic(mnemonic, "passphrase", 0)
+		restored, restoreErr := account.AccountFromMnemonic("  "+strings.ToUpper(mnemonic)+"\n", "passphrase", 0)
+		otherIndex, otherIndexErr := account.AccountFromMnemonic(mnemonic, "passphrase", 1)
+		otherPassphrase, otherPassphraseErr := account.AccountFromMnemonic(mnemonic, "", 0)
+
+		// Assert
+		assert.NoError(t, newErr)
+		assert.NoError(t, createErr)
+		assert.NoError(t, restoreErr)
+		assert.NoError(t, otherIndexErr)
+		assert.NoError(t, otherPassphraseErr)
+		assert.Len(t, strings.Fields(mnemonic), 24)
+		assert.Equal(t, created.GetAddress(), restored.GetAddress())
+		assert.NotEqual(t, created.GetAddress(), otherIndex.GetAddress())
+		assert.NotEqual(t, created.GetAddress(), otherPassphrase.GetAddress())
+	})
+
+	t.Run("should not restore account from invalid mnemonic or index", func(t *testing.T) {
+		// Act
+		_, checksumErr := account.AccountFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", "", 0)
+		_, unknownWordErr := account.AccountFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon kmail", "", 0)
+		_, lengthErr := account.AccountFromMnemonic("abandon about", "", 0)
+		_, indexErr := account.AccountFromMnemonic(vectors[0].mnemonic, "", 1<<31)
+
+		// Assert
+		assert.ErrorIs(t, checksumErr, account.ErrMnemonicChecksum)
+		assert.ErrorIs(t, unknownWordErr, account.ErrInvalidMnemonic)
+		assert.ErrorIs(t, lengthErr, account.ErrInvalidMnemonic)
+		assert.ErrorIs(t, indexErr, account.ErrInvalidMnemonicIndex)
+	})
+}
@@ -0,0 +1,221 @@
+package draft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"passwordless-mail-client/pkg/model"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// drafts are kmail files, the same format -send reads
+const Extension = ".kmail"
+
+var ErrInvalidName = errors.New("invalid draft name: use letters, digits, '-' and '_' only")
+
+// saved draft, err is set when the file is not a valid kmail file
+type Draft struct {
+	Name    string
+	Path    string
+	ModTime time.Time
+	Mail    model.MailFileContent
+	Err     error
+}
+
+// drafts of the user, KMAIL_DRAFTS overrides the directory
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("KMAIL_DRAFTS"); dir != "" {
+		return dir, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "kmail", "drafts"), nil
+}
+
+// most drafts Create tries with the same name before it gives up
+const maxNameSuffix = 100
+
+// name of a new draft, drafts composed later sort after it
+func NewName(now time.Time) string {
+	return "draft-" + now.Format("20060102-150405")
+}
+
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// file of a draft, name may end with the extension but is never a path
+func (s *Store) Path(name string) (string, error) {
+	name = strings.TrimSuffix(name, Extension)
+	if name == "" {
+		return "", ErrInvalidName
+	}
+	for _, c := range name {
+		isValid := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+		if !isValid {
+			return "", ErrInvalidName
+		}
+	}
+
+	return filepath.Join(s.dir, name+Extension), nil
+}
+
+// write mail to the draft, it is only readable by the user.
+// an existing draft of name is replaced, see Create for a new draft
+func (s *Store) Save(name string, mail model.MailFileContent) (string, error) {
+	path, err := s.Path(name)
+	if err != nil {
+		return "", err
+	}
+	content, err := s.encode(mail)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(path, content, 0o600)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// write mail to a new draft, it never replaces another draft. name gets
+// a -2, -3, ... suffix while a draft with it exists, the name and path
+// of the draft are returned
+func (s *Store) Create(name string, mail model.MailFileContent) (string, string, error) {
+	content, err := s.encode(mail)
+	if err != nil {
+		return "", "", err
+	}
+
+	for i := 1; i <= maxNameSuffix; i++ {
+		draftName := strings.TrimSuffix(name, Extension)
+		if i > 1 {
+			draftName = fmt.Sprintf("%s-%d", draftName, i)
+		}
+		path, err := s.Path(draftName)
+		if err != nil {
+			return "", "", err
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		return draftName, path, nil
+	}
+
+	return "", "", fmt.Errorf("draft %s and %d drafts with its name already exist", name, maxNameSuffix-1)
+}
+
+// kmail file of mail, the drafts directory is created when it is missing
+func (s *Store) encode(mail model.MailFileContent) ([]byte, error) {
+	err := os.MkdirAll(s.dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.MarshalIndent(mail, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(content, '\n'), nil
+}
+
+func (s *Store) Load(name string) (Draft, error) {
+	path, err := s.Path(name)
+	if err != nil {
+		return Draft{}, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Draft{}, fmt.Errorf("draft %s not found", strings.TrimSuffix(name, Extension))
+	}
+	if err != nil {
+		return Draft{}, err
+	}
+
+	draft := s.read(path, info)
+	if draft.Err != nil {
+		return Draft{}, draft.Err
+	}
+
+	return draft, nil
+}
+
+// every draft, most recently changed first
+func (s *Store) List() ([]Draft, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Draft{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	drafts := []Draft{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != Extension {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, s.read(filepath.Join(s.dir, entry.Name()), info))
+	}
+	sort.SliceStable(drafts, func(i, j int) bool {
+		return drafts[i].ModTime.After(drafts[j].ModTime)
+	})
+
+	return drafts, nil
+}
+
+func (s *Store) Delete(name string) error {
+	path, err := s.Path(name)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func (s *Store) read(path string, info os.FileInfo) Draft {
+	draft := Draft{
+		Name:    strings.TrimSuffix(info.Name(), Extension),
+		Path:    path,
+		ModTime: info.ModTime(),
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		draft.Err = err
+		return draft
+	}
+	err = json.Unmarshal(content, &draft.Mail)
+	if err != nil {
+		draft.Err = fmt.Errorf("invalid draft %s: %w", draft.Name, err)
+	}
+
+	return draft
+}
@@ -0,0 +1,149 @@
+package draft_test
+
+import (
+	"os"
+	"passwordless-mail-client/pkg/draft"
+	"passwordless-mail-client/pkg/model"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+
+	var (
+		dir   string
+		store *draft.Store
+	)
+
+	beforeEach := func(t *testing.T) {
+		dir = filepath.Join(t.TempDir(), "drafts")
+		store = draft.NewStore(dir)
+	}
+
+	newMail := func(subject string) model.MailFileContent {
+		body := "test body"
+		return model.MailFileContent{
+			To:      model.AddressList{"kmail1q0wa7cvg2xgja058sxzevnn6vnfpy34c5hqryk735tzwky4zjmvnwvjeked"},
+			Subject: &subject,
+			Body:    &body,
+		}
+	}
+
+	t.Run("should save draft as a kmail file only the user can read", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+
+		// Act
+		path, saveErr := store.Save("hello", newMail("test subject"))
+		info, statErr := os.Stat(path)
+		loaded, loadErr := store.Load("hello.kmail")
+
+		// Assert
+		assert.NoError(t, saveErr)
+		assert.NoError(t, statErr)
+		assert.NoError(t, loadErr)
+		assert.Equal(t, filepath.Join(dir, "hello.kmail"), path)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		assert.Equal(t, "hello", loaded.Name)
+		assert.Equal(t, "test subject", *loaded.Mail.Subject)
+	})
+
+	t.Run("should create new draft with a suffix instead of replacing a draft of the same name", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		name := draft.NewName(time.Now())
+		_, saveErr := store.Save(name, newMail("saved subject"))
+
+		// Act
+		secondName, secondPath, secondErr := store.Create(name, newMail("second subject"))
+		thirdName, _, thirdErr := store.Create(name, newMail("third subject"))
+		saved, savedErr := store.Load(name)
+		second, loadErr := store.Load(secondName)
+
+		// Assert
+		assert.NoError(t, saveErr)
+		assert.NoError(t, secondErr)
+		assert.NoError(t, thirdErr)
+		assert.NoError(t, savedErr)
+		assert.NoError(t, loadErr)
+		assert.Equal(t, name+"-2", secondName)
+		assert.Equal(t, filepath.Join(dir, name+"-2.kmail"), secondPath)
+		assert.Equal(t, name+"-3", thirdName)
+		assert.Equal(t, "saved subject", *saved.Mail.Subject)
+		assert.Equal(t, "second subject", *second.Mail.Subject)
+	})
+
+	t.Run("should list drafts most recently changed first with invalid ones marked", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		oldPath, saveErr1 := store.Save("old", newMail("old subject"))
+		_, saveErr2 := store.Save("new", newMail("new subject"))
+		invalidErr := os.WriteFile(filepath.Join(dir, "broken.kmail"), []byte("{"), 0o600)
+		otherErr := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a draft"), 0o600)
+		past := time.Now().Add(-time.Hour)
+		chtimesErr := os.Chtimes(oldPath, past, past)
+
+		// Act
+		drafts, listErr := store.List()
+
+		// Assert
+		assert.NoError(t, saveErr1)
+		assert.NoError(t, saveErr2)
+		assert.NoError(t, invalidErr)
+		assert.NoError(t, otherErr)
+		assert.NoError(t, chtimesErr)
+		assert.NoError(t, listErr)
+		assert.Len(t, drafts, 3)
+		assert.Equal(t, "old", drafts[2].Name)
+		for _, d := range drafts {
+			if d.Name == "broken" {
+				assert.Error(t, d.Err)
+			} else {
+				assert.NoError(t, d.Err)
+			}
+		}
+	})
+
+	t.Run("should list no draft when drafts directory does not exist", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+
+		// Act
+		drafts, listErr := store.List()
+
+		// Assert
+		assert.NoError(t, listErr)
+		assert.Empty(t, drafts)
+	})
+
+	t.Run("should not accept a name outside the drafts directory", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+
+		// Act
+		_, saveErr := store.Save("../hello", newMail("test subject"))
+		_, loadErr := store.Load("/etc/passwd")
+
+		// Assert
+		assert.ErrorIs(t, saveErr, draft.ErrInvalidName)
+		assert.ErrorIs(t, loadErr, draft.ErrInvalidName)
+	})
+
+	t.Run("should delete draft", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		_, saveErr := store.Save("hello", newMail("test subject"))
+
+		// Act
+		deleteErr := store.Delete("hello")
+		_, loadErr := store.Load("hello")
+
+		// Assert
+		assert.NoError(t, saveErr)
+		assert.NoError(t, deleteErr)
+		assert.EqualError(t, loadErr, "draft hello not found")
+	})
+}
@@ -0,0 +1,320 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"passwordless-mail-client/pkg/account"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// version of the keystore file
+const Version = 1
+
+// scrypt cost of new identities, params are stored with every identity
+// so they can be raised without breaking existing keystores
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLength   = 16
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrIdentityExists   = errors.New("identity already exists")
+	ErrWrongPassphrase  = errors.New("wrong passphrase")
+	ErrInvalidName      = errors.New("invalid identity name: use letters, digits, '-' and '_' only")
+)
+
+// private key of an identity encrypted with AES-256-GCM under a key
+// derived from the passphrase with scrypt
+type encryptedIdentity struct {
+	Address    string       `json:"address"`
+	KDF        string       `json:"kdf"`
+	KDFParams  scryptParams `json:"kdf_params"`
+	Nonce      []byte       `json:"nonce"`
+	Ciphertext []byte       `json:"ciphertext"`
+}
+
+type scryptParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+type keystoreFile struct {
+	Version    int                          `json:"version"`
+	Default    string                       `json:"default,omitempty"`
+	Identities map[string]encryptedIdentity `json:"identities"`
+}
+
+// identity as it is listed, without its private key
+type Identity struct {
+	Name    string
+	Address string
+	Default bool
+}
+
+// identities of a keystore file, changes are written with Save
+type Keystore struct {
+	path string
+	file keystoreFile
+}
+
+// keystore of the user, KMAIL_KEYSTORE overrides the file
+func DefaultPath() (string, error) {
+	if path := os.Getenv("KMAIL_KEYSTORE"); path != "" {
+		return path, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "kmail", "keystore.json"), nil
+}
+
+// read the keystore at path, a missing file is an empty keystore
+func Open(path string) (*Keystore, error) {
+	keystore := &Keystore{
+		path: path,
+		file: keystoreFile{Version: Version, Identities: map[string]encryptedIdentity{}},
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return keystore, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(content, &keystore.file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore %s: %w", path, err)
+	}
+	if keystore.file.Version != Version {
+		return nil, fmt.Errorf("keystore %s has unsupported version %d", path, keystore.file.Version)
+	}
+	if keystore.file.Identities == nil {
+		keystore.file.Identities = map[string]encryptedIdentity{}
+	}
+
+	return keystore, nil
+}
+
+func (k *Keystore) Path() string {
+	return k.path
+}
+
+// encrypt private key of acc with passphrase as identity name,
+// the first identity becomes the default
+func (k *Keystore) Add(name string, acc *account.Account, passphrase []byte) error {
+	err := checkName(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := k.file.Identities[name]; ok {
+		return fmt.Errorf("%w: %s", ErrIdentityExists, name)
+	}
+	if len(passphrase) == 0 {
+		return fmt.Errorf("passphrase is required")
+	}
+
+	params := scryptParams{N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, saltLength)}
+	_, err = rand.Read(params.Salt)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(passphrase, params)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+
+	address := acc.GetAddress()
+	privateKey, err := hex.DecodeString(acc.PrivateKeyHex())
+	if err != nil {
+		return err
+	}
+	k.file.Identities[name] = encryptedIdentity{
+		Address:    address,
+		KDF:        "scrypt",
+		KDFParams:  params,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, privateKey, additionalData(name, address)),
+	}
+	if k.file.Default == "" {
+		k.file.Default = name
+	}
+
+	return nil
+}
+
+// decrypt identity name, the default identity when name is empty
+func (k *Keystore) Unlock(name string, passphrase []byte) (*account.Account, error) {
+	if name == "" {
+		name = k.file.Default
+	}
+	identity, ok := k.file.Identities[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrIdentityNotFound, name)
+	}
+	if identity.KDF != "scrypt" {
+		return nil, fmt.Errorf("identity %s uses unsupported kdf %s", name, identity.KDF)
+	}
+
+	aead, err := newAEAD(passphrase, identity.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := aead.Open(nil, identity.Nonce, identity.Ciphertext, additionalData(name, identity.Address))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	acc, err := account.ConnectAccount(hex.EncodeToString(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	if acc.GetAddress() != identity.Address {
+		return nil, fmt.Errorf("identity %s does not match its address", name)
+	}
+
+	return acc, nil
+}
+
+// address of identity name without unlocking it, the default identity when name is empty
+func (k *Keystore) Address(name string) (string, error) {
+	if name == "" {
+		name = k.file.Default
+	}
+	identity, ok := k.file.Identities[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrIdentityNotFound, name)
+	}
+
+	return identity.Address, nil
+}
+
+func (k *Keystore) Remove(name string) error {
+	if _, ok := k.file.Identities[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrIdentityNotFound, name)
+	}
+
+	delete(k.file.Identities, name)
+	if k.file.Default == name {
+		k.file.Default = ""
+	}
+
+	return nil
+}
+
+func (k *Keystore) SetDefault(name string) error {
+	if _, ok := k.file.Identities[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrIdentityNotFound, name)
+	}
+	k.file.Default = name
+
+	return nil
+}
+
+// name of the default identity, empty when there is none
+func (k *Keystore) Default() string {
+	return k.file.Default
+}
+
+// every identity sorted by name
+func (k *Keystore) Identities() []Identity {
+	identities := []Identity{}
+	for name, identity := range k.file.Identities {
+		identities = append(identities, Identity{
+			Name:    name,
+			Address: identity.Address,
+			Default: name == k.file.Default,
+		})
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].Name < identities[j].Name
+	})
+
+	return identities
+}
+
+// write the keystore so only the user can read it, the file is replaced
+// at once so a failed write never loses identities
+func (k *Keystore) Save() error {
+	content, err := json.MarshalIndent(k.file, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(k.path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(k.path), ".keystore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(append(content, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(file.Name(), 0o600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), k.path)
+}
+
+func newAEAD(passphrase []byte, params scryptParams) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, params.Salt, params.N, params.R, params.P, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// bind the ciphertext to its name and address so entries can not be swapped
+func additionalData(name string, address string) []byte {
+	return []byte("kmail-keystore-v1|" + name + "|" + address)
+}
+
+func checkName(name string) error {
+	if name == "" {
+		return ErrInvalidName
+	}
+	for _, c := range name {
+		isValid := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+		if !isValid {
+			return ErrInvalidName
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package keystore_test
+
+import (
+	"encoding/json"
+	"os"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/keystore"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeystore(t *testing.T) {
+
+	const (
+		TestPrivateKey      = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestOtherPrivateKey = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+		TestPassphrase      = "correct horse battery staple"
+	)
+
+	var (
+		path         string
+		testAccount  *account.Account
+		otherAccount *account.Account
+		err          error
+	)
+
+	beforeEach := func(t *testing.T) {
+		path = filepath.Join(t.TempDir(), "kmail", "keystore.json")
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+		otherAccount, err = account.ConnectAccount(TestOtherPrivateKey)
+		assert.NoError(t, err)
+	}
+
+	t.Run("should unlock saved identity with its passphrase", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		store, openErr := keystore.Open(path)
+		addErr := store.Add("work", testAccount, []byte(TestPassphrase))
+		saveErr := store.Save()
+
+		// Act
+		reopened, reopenErr := keystore.Open(path)
+		unlocked, unlockErr := reopened.Unlock("work", []byte(TestPassphrase))
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, addErr)
+		assert.NoError(t, saveErr)
+		assert.NoError(t, reopenErr)
+		assert.NoError(t, unlockErr)
+		assert.Equal(t, testAccount.GetAddress(), unlocked.GetAddress())
+		assert.Equal(t, testAccount.PrivateKey.D, unlocked.PrivateKey.D)
+	})
+
+	t.Run("should save keystore readable only by the user without the plain private key", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		store, openErr := keystore.Open(path)
+		addErr := store.Add("work", testAccount, []byte(TestPassphrase))
+
+		// Act
+		saveErr := store.Save()
+		info, statErr := os.Stat(path)
+		content, readErr := os.ReadFile(path)
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, addErr)
+		assert.NoError(t, saveErr)
+		assert.NoError(t, statErr)
+		assert.NoError(t, readErr)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		assert.False(t, strings.Contains(string(content), TestPrivateKey))
+	})
+
+	t.Run("should return wrong passphrase", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		store, openErr := keystore.Open(path)
+		addErr := store.Add("work", testAccount, []byte(TestPassphrase))
+
+		// Act
+		_, unlockErr := store.Unlock("work", []byte("wrong passphrase"))
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, addErr)
+		assert.ErrorIs(t, unlockErr, keystore.ErrWrongPassphrase)
+	})
+
+	t.Run("should not unlock an identity whose entry was swapped with another", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		store, openErr := keystore.Open(path)
+		addErr1 := store.Add("work", testAccount, []byte(TestPassphrase))
+		addErr2 := store.Add("home", otherAccount, []byte(TestPassphrase))
+		saveErr := store.Save()
+		var file map[string]any
+		content, readErr := os.ReadFile(path)
+		unmarshalErr := json.Unmarshal(content, &file)
+		identities := file["identities"].(map[string]any)
+		identities["work"], identities["home"] = identities["home"], identities["work"]
+		swapped, marshalErr := json.Marshal(file)
+		writeErr := os.WriteFile(path, swapped, 0o600)
+
+		// Act
+		reopened, reopenErr := keystore.Open(path)
+		_, unlockErr := reopened.Unlock("work", []byte(TestPassphrase))
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, addErr1)
+		assert.NoError(t, addErr2)
+		assert.NoError(t, saveErr)
+		assert.NoError(t, readErr)
+		assert.NoError(t, unmarshalErr)
+		assert.NoError(t, marshalErr)
+		assert.NoError(t, writeErr)
+		assert.NoError(t, reopenErr)
+		assert.ErrorIs(t, unlockErr, keystore.ErrWrongPassphrase)
+	})
+
+	t.Run("should unlock the default identity when name is empty", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		store, openErr := keystore.Open(path)
+		addErr1 := store.Add("work", testAccount, []byte(TestPassphrase))
+		addErr2 := store.Add("home", otherAccount, []byte("other passphrase"))
+		defaultErr := store.SetDefault("home")
+
+		// Act
+		unlocked, unlockErr := store.Unlock("", []byte("other passphrase"))
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, addErr1)
+		assert.NoError(t, addErr2)
+		assert.NoError(t, defaultErr)
+		assert.NoError(t, unlockErr)
+		assert.Equal(t, otherAccount.GetAddress(), unlocked.GetAddress())
+		address, addressErr := store.Address("")
+		assert.NoError(t, addressErr)
+		assert.Equal(t, otherAccount.GetAddress(), address)
+		assert.Equal(t, []keystore.Identity{
+			{Name: "home", Address: otherAccount.GetAddress(), Default: true},
+			{Name: "work", Address: testAccount.GetAddress(), Default: false},
+		}, store.Identities())
+	})
+
+	t.Run("should not add identity with a used or invalid name", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		store, openErr := keystore.Open(path)
+		addErr := store.Add("work", testAccount, []byte(TestPassphrase))
+
+		// Act
+		existsErr := store.Add("work", otherAccount, []byte(TestPassphrase))
+		invalidErr := store.Add("../work", otherAccount, []byte(TestPassphrase))
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, addErr)
+		assert.ErrorIs(t, existsErr, keystore.ErrIdentityExists)
+		assert.ErrorIs(t, invalidErr, keystore.ErrInvalidName)
+	})
+
+	t.Run("should remove identity and its default", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		store, openErr := keystore.Open(path)
+		addErr := store.Add("work", testAccount, []byte(TestPassphrase))
+
+		// Act
+		removeErr := store.Remove("work")
+		_, unlockErr := store.Unlock("work", []byte(TestPassphrase))
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, addErr)
+		assert.NoError(t, removeErr)
+		assert.ErrorIs(t, unlockErr, keystore.ErrIdentityNotFound)
+		assert.Empty(t, store.Default())
+	})
+}
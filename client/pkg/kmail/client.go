@@ -0,0 +1,158 @@
+package kmail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/model"
+	"passwordless-mail-client/pkg/request"
+	"strings"
+)
+
+// Client talks to one kmail server as account, every request is signed
+// with the account private key for the base url of the server, or sent
+// with a session token when the client has one and the endpoint takes it
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	account    *account.Account
+	token      string
+}
+
+// base url is the origin requests are signed for, e.g. http://localhost:8080,
+// it must be the origin the server was started with. http client may be
+// nil to use http.DefaultClient
+func NewClient(baseURL string, httpClient *http.Client, acc *account.Account) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+		account:    acc,
+	}
+}
+
+// origin to create signed messages for, see the request package
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *Client) Account() *account.Account {
+	return c.account
+}
+
+// copy of the client that sends token from login as a bearer token on read
+// and write requests instead of signing them, an empty token signs again.
+// sending mail, login and revoke sessions are always signed
+func (c *Client) WithToken(token string) *Client {
+	tokenClient := *c
+	tokenClient.token = token
+
+	return &tokenClient
+}
+
+// post message to path with the session token of the client,
+// or signed when the client has no token, see WithToken
+func (c *Client) PostAuthenticated(path string, message []byte) ([]byte, error) {
+	if c.token == "" {
+		return c.PostSigned(path, message)
+	}
+
+	requestBody, err := json.Marshal(model.RequestBody{
+		Data: string(message),
+	})
+	if err != nil {
+		return nil, err
+	}
+	tokenRequest, err := http.NewRequest(http.MethodPost, c.baseURL+path, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	tokenRequest.Header.Set("Content-Type", "application/json")
+	tokenRequest.Header.Set("Authorization", "Bearer "+c.token)
+
+	return c.do(tokenRequest)
+}
+
+// sign message and post it to path of the server, path may have a query.
+// the body of a successful response is returned, any other response
+// is returned as *Error
+func (c *Client) PostSigned(path string, message []byte) ([]byte, error) {
+	signature, err := c.account.Sign(message)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, err := json.Marshal(model.RequestBody{
+		Data:      string(message),
+		Signature: signature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	signedRequest, err := http.NewRequest(http.MethodPost, c.baseURL+path, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	signedRequest.Header.Set("Content-Type", "application/json")
+	signedRequest.Header.Set("x-public-key", c.account.GetAddress())
+
+	return c.do(signedRequest)
+}
+
+// post without a signature, for endpoints such as the login challenge
+func (c *Client) Post(path string) ([]byte, error) {
+	unsignedRequest, err := http.NewRequest(http.MethodPost, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(unsignedRequest)
+}
+
+// revoke the session token of the client, it does not need the account
+func (c *Client) Logout() (request.RevokeSessionsResponse, error) {
+	if c.token == "" {
+		return request.RevokeSessionsResponse{}, fmt.Errorf("session token is required")
+	}
+	logoutRequest, err := http.NewRequest(http.MethodPost, c.baseURL+"/auth/logout", nil)
+	if err != nil {
+		return request.RevokeSessionsResponse{}, err
+	}
+	logoutRequest.Header.Set("Authorization", "Bearer "+c.token)
+
+	body, err := c.do(logoutRequest)
+	if err != nil {
+		return request.RevokeSessionsResponse{}, err
+	}
+	var revoked request.RevokeSessionsResponse
+	err = json.Unmarshal(body, &revoked)
+	if err != nil {
+		return request.RevokeSessionsResponse{}, err
+	}
+
+	return revoked, nil
+}
+
+func (c *Client) do(httpRequest *http.Request) ([]byte, error) {
+	response, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, newError(response.StatusCode, body)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,322 @@
+package kmail_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/kmail"
+	"passwordless-mail-client/pkg/model"
+	"passwordless-mail-client/pkg/request"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+
+	const (
+		TestSenderPrivateKey    = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestRecipientPrivateKey = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+	)
+
+	var (
+		sender    *account.Account
+		recipient *account.Account
+		err       error
+
+		server   *httptest.Server
+		client   *kmail.Client
+		received []*http.Request
+		headers  []request.Header
+		respond  func(w http.ResponseWriter, r *http.Request)
+	)
+
+	// server that checks the signature of every request and answers with respond
+	beforeEach := func(t *testing.T) {
+		sender, err = account.ConnectAccount(TestSenderPrivateKey)
+		assert.NoError(t, err)
+		recipient, err = account.ConnectAccount(TestRecipientPrivateKey)
+		assert.NoError(t, err)
+
+		received = nil
+		headers = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body model.RequestBody
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			publicKey, err := account.ParseAddress(r.Header.Get("x-public-key"))
+			assert.NoError(t, err)
+			assert.True(t, account.Verify(publicKey, []byte(body.Data), body.Signature))
+			var header request.Header
+			assert.NoError(t, json.Unmarshal([]byte(body.Data), &header))
+
+			received = append(received, r)
+			headers = append(headers, header)
+			respond(w, r)
+		}))
+		t.Cleanup(server.Close)
+		client = kmail.NewClient(server.URL+"/", server.Client(), recipient)
+	}
+
+	// delivery to the recipient as the server returns it for a mail signed by sender
+	newMail := func(t *testing.T, subject string, body string) model.Mail {
+		message, err := request.NewSendEmail(server.URL, recipient.GetAddress(), subject, body)
+		assert.NoError(t, err)
+		signature, err := sender.Sign(message)
+		assert.NoError(t, err)
+		var sendEmail request.SendEmailRequest
+		assert.NoError(t, json.Unmarshal(message, &sendEmail))
+		delivery := sendEmail.Deliveries[0]
+
+		return model.Mail{
+			ID:           uuid.New(),
+			MessageID:    sendEmail.MessageID,
+			From:         sender.GetAddress(),
+			Recipient:    delivery.Recipient,
+			To:           sendEmail.To,
+			Cc:           sendEmail.Cc,
+			EphemeralKey: delivery.EphemeralKey,
+			Subject:      delivery.Subject,
+			Body:         delivery.Body,
+			SignedData:   string(message),
+			Signature:    signature,
+		}
+	}
+
+	respondJSON := func(status int, value any) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(value)
+		}
+	}
+
+	t.Run("should sign inbox request for the base url and decrypt every mail", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		mail := newMail(t, "test subject", "test body")
+		respond = respondJSON(http.StatusOK, request.GetInboxResponse{Inbox: []model.Mail{mail}, Total: 1})
+		page, limit := 2, 5
+
+		// Act
+		inbox, inboxErr := client.Inbox(model.QueryJson{Page: &page, Limit: &limit})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Equal(t, "/mail/inbox", received[0].URL.Path)
+		assert.Equal(t, "2", received[0].URL.Query().Get("page"))
+		assert.Equal(t, "5", received[0].URL.Query().Get("limit"))
+		assert.Equal(t, request.GetInbox, headers[0].Action)
+		assert.Equal(t, server.URL, headers[0].Origin)
+		assert.Equal(t, 1, inbox.Total)
+		assert.Equal(t, "test subject", inbox.Inbox[0].Subject)
+		assert.Equal(t, "test body", inbox.Inbox[0].Body)
+	})
+
+	t.Run("should skip mail that can not be decrypted and keep the other mails", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		plaintext := newMail(t, "test subject", "test body")
+		plaintext.EphemeralKey = ""
+		plaintext.Subject = "plaintext subject"
+		mail := newMail(t, "test subject", "test body")
+		respond = respondJSON(http.StatusOK, request.GetInboxResponse{Inbox: []model.Mail{plaintext, mail}, Total: 2})
+		page, limit := 1, 5
+
+		// Act
+		inbox, inboxErr := client.Inbox(model.QueryJson{Page: &page, Limit: &limit})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Equal(t, 2, inbox.Total)
+		if assert.Len(t, inbox.Inbox, 1) {
+			assert.Equal(t, mail.ID, inbox.Inbox[0].ID)
+			assert.Equal(t, "test body", inbox.Inbox[0].Body)
+		}
+		if assert.Len(t, inbox.Undecryptable, 1) {
+			assert.Equal(t, plaintext.ID, inbox.Undecryptable[0].ID)
+			assert.Equal(t, sender.GetAddress(), inbox.Undecryptable[0].From)
+			assert.NotEmpty(t, inbox.Undecryptable[0].Error)
+		}
+	})
+
+	t.Run("should read verified mail with subject and body decrypted", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		mail := newMail(t, "test subject", "test body")
+		respond = respondJSON(http.StatusOK, mail)
+
+		// Act
+		read, readErr := client.Read(mail.ID)
+
+		// Assert
+		assert.NoError(t, readErr)
+		assert.Equal(t, "/mail", received[0].URL.Path)
+		assert.Equal(t, request.GetEmail, headers[0].Action)
+		assert.Equal(t, mail.ID, read.ID)
+		assert.Equal(t, sender.GetAddress(), read.From)
+		assert.Equal(t, "test subject", read.Subject)
+		assert.Equal(t, "test body", read.Body)
+	})
+
+	t.Run("should return invalid mail when server changed what the sender signed", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		mail := newMail(t, "test subject", "test body")
+		other := newMail(t, "other subject", "other body")
+		mail.Body = other.Body
+		respond = respondJSON(http.StatusOK, mail)
+
+		// Act
+		_, readErr := client.Read(mail.ID)
+
+		// Assert
+		assert.ErrorIs(t, readErr, kmail.ErrInvalidMail)
+	})
+
+	t.Run("should return invalid mail when server answered with another mail", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		mail := newMail(t, "test subject", "test body")
+		respond = respondJSON(http.StatusOK, mail)
+
+		// Act
+		_, readErr := client.Read(uuid.New())
+
+		// Assert
+		assert.ErrorIs(t, readErr, kmail.ErrInvalidMail)
+	})
+
+	t.Run("should return error response of the server as typed error", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		respond = respondJSON(http.StatusNotFound, request.ErrorResponse{
+			Code:      "mail_not_found",
+			Message:   "mail not found",
+			RequestID: "8f0c1f6e-2a52-4f1c-9d3e-7d3c2f0b9b11",
+		})
+
+		// Act
+		_, readErr := client.Read(uuid.New())
+
+		// Assert
+		var apiErr *kmail.Error
+		assert.True(t, errors.As(readErr, &apiErr))
+		assert.ErrorIs(t, readErr, kmail.ErrNotFound)
+		assert.NotErrorIs(t, readErr, kmail.ErrUnauthorized)
+		assert.Equal(t, "mail_not_found", apiErr.Code)
+		assert.Equal(t, "8f0c1f6e-2a52-4f1c-9d3e-7d3c2f0b9b11", apiErr.RequestID)
+	})
+
+	t.Run("should match unsupported protocol version by its code", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		respond = respondJSON(http.StatusBadRequest, request.ErrorResponse{
+			Code:    "unsupported_protocol_version",
+			Message: "unsupported protocol version, please upgrade client to version 3",
+		})
+
+		// Act
+		_, inboxErr := client.Inbox(model.QueryJson{})
+
+		// Assert
+		assert.ErrorIs(t, inboxErr, kmail.ErrUnsupportedVersion)
+		assert.ErrorIs(t, inboxErr, kmail.ErrBadRequest)
+	})
+
+	t.Run("should send one request for to and cc and one per bcc recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		respond = respondJSON(http.StatusCreated, request.SendMailResponse{ID: uuid.New()})
+		client = kmail.NewClient(server.URL, server.Client(), sender)
+
+		// Act
+		responses, sendErr := client.Send(kmail.OutgoingMail{
+			To:      []string{recipient.GetAddress()},
+			Bcc:     []string{sender.GetAddress()},
+			Subject: "test subject",
+			Body:    "test body",
+		})
+
+		// Assert
+		assert.NoError(t, sendErr)
+		assert.Len(t, responses, 2)
+		assert.Len(t, received, 2)
+		for i := range received {
+			assert.Equal(t, "/mail/send", received[i].URL.Path)
+			assert.Equal(t, request.SendEmail, headers[i].Action)
+		}
+	})
+	t.Run("should read inbox with only a session token", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		mail := newMail(t, "test subject", "test body")
+		var tokenRequest *http.Request
+		var tokenBody model.RequestBody
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenRequest = r
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&tokenBody))
+			respondJSON(http.StatusOK, request.GetInboxResponse{Inbox: []model.Mail{mail}, Total: 1})(w, r)
+		}))
+		t.Cleanup(tokenServer.Close)
+		tokenClient := kmail.NewClient(tokenServer.URL, tokenServer.Client(), recipient).WithToken("session-token")
+		page, limit := 1, 5
+
+		// Act
+		inbox, inboxErr := tokenClient.Inbox(model.QueryJson{Page: &page, Limit: &limit})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Equal(t, "Bearer session-token", tokenRequest.Header.Get("Authorization"))
+		assert.Empty(t, tokenRequest.Header.Get("x-public-key"))
+		assert.Empty(t, tokenBody.Signature)
+		var header request.Header
+		assert.NoError(t, json.Unmarshal([]byte(tokenBody.Data), &header))
+		assert.Equal(t, request.GetInbox, header.Action)
+		if assert.Len(t, inbox.Inbox, 1) {
+			assert.Equal(t, "test body", inbox.Inbox[0].Body)
+		}
+	})
+
+	t.Run("should sign send mail even when client has a session token", func(t *testing.T) {
+		// Arrange
+		beforeEach(t)
+		respond = respondJSON(http.StatusCreated, request.SendMailResponse{ID: uuid.New()})
+		tokenClient := client.WithToken("session-token")
+
+		// Act
+		_, sendErr := tokenClient.Send(kmail.OutgoingMail{
+			To:      []string{sender.GetAddress()},
+			Subject: "test subject",
+			Body:    "test body",
+		})
+
+		// Assert
+		assert.NoError(t, sendErr)
+		assert.Empty(t, received[0].Header.Get("Authorization"))
+		assert.Equal(t, recipient.GetAddress(), received[0].Header.Get("x-public-key"))
+	})
+
+	t.Run("should revoke session token on logout", func(t *testing.T) {
+		// Arrange
+		var logoutRequest *http.Request
+		logoutServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logoutRequest = r
+			respondJSON(http.StatusOK, request.RevokeSessionsResponse{Revoked: 1})(w, r)
+		}))
+		t.Cleanup(logoutServer.Close)
+		tokenClient := kmail.NewClient(logoutServer.URL, logoutServer.Client(), nil).WithToken("session-token")
+
+		// Act
+		revoked, logoutErr := tokenClient.Logout()
+		_, noTokenErr := tokenClient.WithToken("").Logout()
+
+		// Assert
+		assert.NoError(t, logoutErr)
+		assert.Equal(t, 1, revoked.Revoked)
+		assert.Equal(t, "/auth/logout", logoutRequest.URL.Path)
+		assert.Equal(t, "Bearer session-token", logoutRequest.Header.Get("Authorization"))
+		assert.Error(t, noTokenErr)
+	})
+}
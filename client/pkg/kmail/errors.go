@@ -0,0 +1,71 @@
+package kmail
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"passwordless-mail-client/pkg/request"
+	"strings"
+)
+
+// match an *Error with errors.Is, e.g. errors.Is(err, kmail.ErrNotFound)
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("not found")
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+)
+
+// mail returned by the server is not what its sender signed
+var ErrInvalidMail = errors.New("mail failed verification")
+
+// error response of the server, code and request id are empty when
+// the body was not an error response (e.g. from a proxy)
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+}
+
+func newError(statusCode int, body []byte) *Error {
+	var errorResponse *request.ErrorResponse
+	if !errors.As(request.ParseError(statusCode, body), &errorResponse) {
+		return &Error{StatusCode: statusCode, Message: strings.TrimSpace(string(body))}
+	}
+
+	return &Error{
+		StatusCode: statusCode,
+		Code:       errorResponse.Code,
+		Message:    errorResponse.Message,
+		RequestID:  errorResponse.RequestID,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+	}
+
+	return fmt.Sprintf("%s (code %s, request id %s)", e.Message, e.Code, e.RequestID)
+}
+
+// status code decides the sentinel, except unsupported protocol version
+// that is a bad request with its own code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnsupportedVersion:
+		return e.Code == "unsupported_protocol_version"
+	}
+
+	return false
+}
@@ -0,0 +1,306 @@
+package kmail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/model"
+	"passwordless-mail-client/pkg/request"
+	"path/filepath"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// file of an outgoing or a received mail, content is the plaintext
+type Attachment struct {
+	Name string
+	// detected from the name or the content when empty
+	MimeType string
+	Content  []byte
+	// hex SHA-256 of the encrypted content, only set on received attachments
+	Hash string
+}
+
+// mail to send, every bcc recipient gets its own copy
+type OutgoingMail struct {
+	To          []string
+	Cc          []string
+	Bcc         []string
+	Subject     string
+	Body        string
+	InReplyTo   *uuid.UUID
+	Attachments []Attachment
+}
+
+// page of the inbox with subject and body of every mail decrypted,
+// query is the same as the inbox query file of the cli
+func (c *Client) Inbox(query model.QueryJson) (request.GetInboxResponse, error) {
+	message, err := request.NewGetInbox(c.baseURL)
+	if err != nil {
+		return request.GetInboxResponse{}, err
+	}
+
+	queryParams := url.Values{}
+	if query.Limit != nil {
+		queryParams.Set("limit", strconv.Itoa(*query.Limit))
+	}
+	if query.Page != nil {
+		queryParams.Set("page", strconv.Itoa(*query.Page))
+	}
+	if query.Before != nil {
+		queryParams.Set("before", *query.Before)
+	}
+	if query.After != nil {
+		queryParams.Set("after", *query.After)
+	}
+	if query.Order != nil {
+		queryParams.Set("order", *query.Order)
+	}
+	body, err := c.PostAuthenticated("/mail/inbox?"+queryParams.Encode(), message)
+	if err != nil {
+		return request.GetInboxResponse{}, err
+	}
+
+	var inbox request.GetInboxResponse
+	err = json.Unmarshal(body, &inbox)
+	if err != nil {
+		return request.GetInboxResponse{}, err
+	}
+	c.DecryptInbox(&inbox)
+
+	return inbox, nil
+}
+
+// decrypt every mail of inbox, a mail that fails is moved to
+// inbox.Undecryptable so one bad sender can not hide the others
+func (c *Client) DecryptInbox(inbox *request.GetInboxResponse) {
+	decrypted := []model.Mail{}
+	for _, mail := range inbox.Inbox {
+		plain, err := c.Decrypt(mail)
+		if err != nil {
+			inbox.Undecryptable = append(inbox.Undecryptable, request.UndecryptableMail{
+				ID:    mail.ID,
+				From:  mail.From,
+				Error: err.Error(),
+			})
+			continue
+		}
+		decrypted = append(decrypted, plain)
+	}
+	inbox.Inbox = decrypted
+}
+
+// one mail the account sent or received, checked against the signature
+// of its sender so the server does not have to be trusted. subject and
+// body are decrypted when the account is the recipient, a mail sent to
+// someone else stays encrypted to that recipient
+func (c *Client) Read(id uuid.UUID) (model.Mail, error) {
+	message, err := request.NewGetEmail(c.baseURL, id)
+	if err != nil {
+		return model.Mail{}, err
+	}
+	body, err := c.PostAuthenticated("/mail", message)
+	if err != nil {
+		return model.Mail{}, err
+	}
+
+	var mail model.Mail
+	err = json.Unmarshal(body, &mail)
+	if err != nil {
+		return model.Mail{}, err
+	}
+	if mail.ID != id {
+		return model.Mail{}, fmt.Errorf("%w: server returned mail %s for %s", ErrInvalidMail, mail.ID, id)
+	}
+	err = request.VerifyMail(mail)
+	if err != nil {
+		return model.Mail{}, fmt.Errorf("%w: mail %s: %v", ErrInvalidMail, id, err)
+	}
+
+	if mail.Recipient != c.account.GetAddress() {
+		return mail, nil
+	}
+
+	return c.Decrypt(mail)
+}
+
+// decrypt subject and body of a mail received by the account
+func (c *Client) Decrypt(mail model.Mail) (model.Mail, error) {
+	subject, body, err := c.account.DecryptMail(account.SealedMail{
+		EphemeralKey: mail.EphemeralKey,
+		Subject:      mail.Subject,
+		Body:         mail.Body,
+	})
+	if err != nil {
+		return model.Mail{}, fmt.Errorf("can not decrypt mail %s: %w", mail.ID, err)
+	}
+
+	mail.Subject = subject
+	mail.Body = body
+
+	return mail, nil
+}
+
+// encrypt and upload the attachments, then send one request for to and cc
+// and one per bcc recipient. responses are in the same order
+func (c *Client) Send(mail OutgoingMail) ([]request.SendMailResponse, error) {
+	attachments, err := c.upload(mail.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := request.NewSendEmailsWithAttachments(
+		c.baseURL,
+		request.Recipients{To: mail.To, Cc: mail.Cc, Bcc: mail.Bcc},
+		mail.Subject,
+		mail.Body,
+		mail.InReplyTo,
+		attachments,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []request.SendMailResponse
+	for _, message := range messages {
+		body, err := c.PostSigned("/mail/send", message)
+		if err != nil {
+			return responses, err
+		}
+		var sent request.SendMailResponse
+		err = json.Unmarshal(body, &sent)
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, sent)
+	}
+
+	return responses, nil
+}
+
+// encrypt every file with its own key and upload it, limits are
+// checked before anything is uploaded
+func (c *Client) upload(files []Attachment) ([]request.OutgoingAttachment, error) {
+	var attachments []request.OutgoingAttachment
+	var references []model.Attachment
+	var contents [][]byte
+	for _, file := range files {
+		mimeType := file.MimeType
+		if mimeType == "" {
+			mimeType = mime.TypeByExtension(filepath.Ext(file.Name))
+		}
+		if mimeType == "" {
+			mimeType = http.DetectContentType(file.Content)
+		}
+
+		ciphertext, key, err := account.EncryptAttachment(file.Content)
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256(ciphertext)
+		attachment := request.OutgoingAttachment{
+			Attachment: model.Attachment{
+				Hash:     hex.EncodeToString(hash[:]),
+				Size:     int64(len(ciphertext)),
+				MimeType: mimeType,
+			},
+			Name: file.Name,
+			Key:  key,
+		}
+		attachments = append(attachments, attachment)
+		references = append(references, attachment.Attachment)
+		contents = append(contents, ciphertext)
+	}
+
+	err := request.CheckAttachments(references)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, content := range contents {
+		message, err := request.NewUploadAttachment(c.baseURL, content)
+		if err != nil {
+			return nil, err
+		}
+		body, err := c.PostAuthenticated("/mail/attachment/upload", message)
+		if err != nil {
+			return nil, fmt.Errorf("can not upload attachment %s: %w", attachments[i].Name, err)
+		}
+
+		var uploaded request.UploadAttachmentResponse
+		err = json.Unmarshal(body, &uploaded)
+		if err != nil {
+			return nil, err
+		}
+		if uploaded.Hash != attachments[i].Hash || uploaded.Size != attachments[i].Size {
+			return nil, fmt.Errorf("server stored attachment %s with another hash or size", attachments[i].Name)
+		}
+	}
+
+	return attachments, nil
+}
+
+// download, check and decrypt the attachments of a mail from Read,
+// hashes and keys come from the signed data so the server can not swap the files
+func (c *Client) Attachments(mail model.Mail) ([]Attachment, error) {
+	if len(mail.Attachments) == 0 {
+		return nil, nil
+	}
+	if mail.Recipient != c.account.GetAddress() {
+		return nil, fmt.Errorf("attachment keys of mail %s are encrypted to its recipient", mail.ID)
+	}
+
+	plainKeys, err := c.account.DecryptAttachmentKeys(account.SealedMail{
+		EphemeralKey:   mail.EphemeralKey,
+		AttachmentKeys: mail.AttachmentKeys,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not decrypt attachment keys of mail %s: %w", mail.ID, err)
+	}
+	var attachmentKeys []request.AttachmentKey
+	err = json.Unmarshal([]byte(plainKeys), &attachmentKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attachment keys of mail %s: %w", mail.ID, err)
+	}
+
+	mimeTypes := map[string]string{}
+	for _, attachment := range mail.Attachments {
+		mimeTypes[attachment.Hash] = attachment.MimeType
+	}
+
+	var attachments []Attachment
+	for _, attachmentKey := range attachmentKeys {
+		message, err := request.NewGetAttachment(c.baseURL, mail.ID, attachmentKey.Hash)
+		if err != nil {
+			return nil, err
+		}
+		body, err := c.PostAuthenticated("/mail/attachment", message)
+		if err != nil {
+			return nil, fmt.Errorf("can not download attachment %s: %w", attachmentKey.Name, err)
+		}
+
+		hash := sha256.Sum256(body)
+		if hex.EncodeToString(hash[:]) != attachmentKey.Hash {
+			return nil, fmt.Errorf("attachment %s does not match its signed hash", attachmentKey.Name)
+		}
+		content, err := account.DecryptAttachment(body, attachmentKey.Key)
+		if err != nil {
+			return nil, fmt.Errorf("can not decrypt attachment %s: %w", attachmentKey.Name, err)
+		}
+
+		attachments = append(attachments, Attachment{
+			Name:     attachmentKey.Name,
+			MimeType: mimeTypes[attachmentKey.Hash],
+			Content:  content,
+			Hash:     attachmentKey.Hash,
+		})
+	}
+
+	return attachments, nil
+}
@@ -1,17 +1,75 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
 
+	"github.com/google/uuid"
+)
+
+// one delivery of a mail to recipient, deliveries of the same mail
+// share the message id. bcc is only set on the delivery to a bcc recipient
 type Mail struct {
-	ID      uuid.UUID `json:"id"`
-	From    string    `json:"from"`
-	To      string    `json:"to"`
-	Subject string    `json:"subject"`
-	Body    string    `json:"body"`
+	ID           uuid.UUID    `json:"id"`
+	MessageID    uuid.UUID    `json:"message_id"`
+	From         string       `json:"from"`
+	Recipient    string       `json:"recipient"`
+	To           []string     `json:"to"`
+	Cc           []string     `json:"cc"`
+	Bcc          []string     `json:"bcc,omitempty"`
+	EphemeralKey string       `json:"ephemeral_key"`
+	Subject      string       `json:"subject"`
+	Body         string       `json:"body"`
+	SignedData   string       `json:"signed_data"`
+	Signature    []byte       `json:"signature"`
+	SentAt       string       `json:"sent_at"`
+	InReplyTo    *uuid.UUID   `json:"in_reply_to"`
+	ThreadID     uuid.UUID    `json:"thread_id"`
+	ReadAt       *string      `json:"read_at"`
+	Attachments  []Attachment `json:"attachments"`
+	// names and keys of attachments encrypted to the recipient,
+	// see account.DecryptAttachmentKeys
+	AttachmentKeys string `json:"attachment_keys,omitempty"`
+}
+
+// encrypted attachment uploaded to the server, hash is the hex SHA-256
+// of the ciphertext, size is its length and mime type is of the plaintext
+type Attachment struct {
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
 }
 
+// to, cc and bcc are each either one address or a list of addresses
 type MailFileContent struct {
-	To      *string `json:"to"`
-	Subject *string `json:"subject"`
-	Body    *string `json:"body`
+	To        AddressList `json:"to"`
+	Cc        AddressList `json:"cc,omitempty"`
+	Bcc       AddressList `json:"bcc,omitempty"`
+	Subject   *string     `json:"subject"`
+	Body      *string     `json:"body"`
+	InReplyTo *string     `json:"in_reply_to,omitempty"`
+	// paths of files to attach, relative to the working directory
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+type AddressList []string
+
+func (l *AddressList) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var address string
+	if json.Unmarshal(data, &address) == nil {
+		*l = AddressList{address}
+		return nil
+	}
+
+	var addresses []string
+	err := json.Unmarshal(data, &addresses)
+	if err != nil {
+		return err
+	}
+	*l = addresses
+
+	return nil
 }
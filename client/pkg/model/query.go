@@ -5,7 +5,23 @@ type RequestBody struct {
 	Signature []byte `json:"signature"`
 }
 
+// before and after are cursors from a previous inbox response,
+// page is not needed when one of them is set
 type QueryJson struct {
-	Page  *int `json:"page"`
-	Limit *int `json:"limit"`
+	Page   *int    `json:"page"`
+	Limit  *int    `json:"limit"`
+	Before *string `json:"before,omitempty"`
+	After  *string `json:"after,omitempty"`
+	Order  *string `json:"order,omitempty"` // newest (default) or oldest
+}
+
+// keyword is matched against subject and body after decryption,
+// from, since and until are sent to the server as signed filters
+type SearchQueryJson struct {
+	Page    *int    `json:"page"`
+	Limit   *int    `json:"limit"`
+	Keyword *string `json:"keyword,omitempty"`
+	From    *string `json:"from,omitempty"`
+	Since   *string `json:"since,omitempty"` // RFC3339
+	Until   *string `json:"until,omitempty"` // RFC3339
 }
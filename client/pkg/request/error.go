@@ -0,0 +1,31 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// body of an error response of the server, request id is what the
+// server logs the failed request with
+type ErrorResponse struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RequestID  string `json:"request_id"`
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%s (code %s, request id %s)", e.Message, e.Code, e.RequestID)
+}
+
+// error of a response with status code, a body that is not an error
+// response (e.g. from a proxy or an older server) is shown as it is
+func ParseError(statusCode int, body []byte) error {
+	errorResponse := &ErrorResponse{StatusCode: statusCode}
+	err := json.Unmarshal(body, errorResponse)
+	if err != nil || errorResponse.Code == "" {
+		return fmt.Errorf("status %d: %s", statusCode, string(body))
+	}
+
+	return errorResponse
+}
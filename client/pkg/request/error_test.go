@@ -0,0 +1,38 @@
+package request_test
+
+import (
+	"errors"
+	"net/http"
+	"passwordless-mail-client/pkg/request"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseError(t *testing.T) {
+
+	t.Run("should decode code, message and request id of an error response", func(t *testing.T) {
+		// Arrange
+		body := []byte(`{"code":"mail_not_found","message":"mail not found","request_id":"8f0c1f6e-2a52-4f1c-9d3e-7d3c2f0b9b11"}`)
+
+		// Act
+		err := request.ParseError(http.StatusNotFound, body)
+
+		// Assert
+		var errorResponse *request.ErrorResponse
+		assert.True(t, errors.As(err, &errorResponse))
+		assert.Equal(t, http.StatusNotFound, errorResponse.StatusCode)
+		assert.Equal(t, "mail_not_found", errorResponse.Code)
+		assert.Equal(t, "mail not found (code mail_not_found, request id 8f0c1f6e-2a52-4f1c-9d3e-7d3c2f0b9b11)", err.Error())
+	})
+
+	t.Run("should show body as it is when it is not an error response", func(t *testing.T) {
+		// Act
+		err := request.ParseError(http.StatusBadGateway, []byte("bad gateway"))
+
+		// Assert
+		var errorResponse *request.ErrorResponse
+		assert.False(t, errors.As(err, &errorResponse))
+		assert.EqualError(t, err, "status 502: bad gateway")
+	})
+}
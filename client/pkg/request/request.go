@@ -1,53 +1,297 @@
 package request
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"passwordless-mail-client/pkg/account"
 	"passwordless-mail-client/pkg/model"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// version of the signed request format,
+// the server rejects requests signed with any other version
+const ProtocolVersion = 3
+
 type ActionName string
 
 const (
-	GetInbox  ActionName = "get inbox"
-	GetEmail  ActionName = "get email"
-	SendEmail ActionName = "send email"
+	GetInbox     ActionName = "get inbox"
+	GetEmail     ActionName = "get email"
+	GetSent      ActionName = "get sent"
+	SearchEmail  ActionName = "search email"
+	GetThread    ActionName = "get thread"
+	SendEmail    ActionName = "send email"
+	DeleteEmail  ActionName = "delete email"
+	RestoreEmail ActionName = "restore email"
+	PurgeTrash   ActionName = "purge trash"
+	MarkRead     ActionName = "mark read"
+	MarkUnread   ActionName = "mark unread"
+
+	UploadAttachment ActionName = "upload attachment"
+	GetAttachment    ActionName = "get attachment"
+
+	Login          ActionName = "login"
+	RevokeSessions ActionName = "revoke sessions"
+)
+
+// what a session token can be used for, sending mail always
+// needs a signature because recipients verify it
+type Scope string
+
+const (
+	// inbox, sent, search, read mail, thread and download attachment
+	ScopeRead Scope = "read"
+	// upload attachment, delete, restore, purge trash and mark read or unread
+	ScopeWrite Scope = "write"
 )
 
+var Scopes = []Scope{ScopeRead, ScopeWrite}
+
+// most mails one mark read or mark unread request can update
+const MaxMarkEmails = 100
+
+// most addresses of to, cc and bcc together one mail can be sent to
+const MaxRecipients = 50
+
+// limits of attachments of one mail, sizes are of the encrypted content
+const (
+	MaxAttachments        = 10
+	MaxAttachmentSize     = 10 << 20
+	MaxAttachmentsPerMail = 25 << 20
+)
+
+// fields shared by every signed request, version, action and origin
+// make a signature only valid for one endpoint on one server. login and
+// revoke sessions are signed over a nonce instead of id and timestamp
+type Header struct {
+	Version   int        `json:"version"`
+	Action    ActionName `json:"action"`
+	Origin    string     `json:"origin"`
+	ID        uuid.UUID  `json:"id"`
+	Timestamp string     `json:"timestamp"`
+}
+
+// header of a new request with a random id, signed now
+func newHeader(action ActionName, origin string) Header {
+	return Header{
+		Version:   ProtocolVersion,
+		Action:    action,
+		Origin:    origin,
+		ID:        uuid.New(),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+}
+
 type GetInboxRequest struct {
-	ID        uuid.UUID `json:"id"`
-	Timestamp string    `json:"timestamp"`
+	Header
 }
 
 type GetInboxResponse struct {
-	Inbox []model.Mail `json:"inbox"`
+	Inbox  []model.Mail `json:"inbox"`
+	Total  int          `json:"total"`
+	Unread int          `json:"unread"`
+	Before string       `json:"before,omitempty"` // cursor for older mails
+	After  string       `json:"after,omitempty"`  // cursor for newer mails
+	// mails left out of inbox because they can not be decrypted,
+	// set by the client and never sent by the server
+	Undecryptable []UndecryptableMail `json:"undecryptable,omitempty"`
+	// set by the client when a keyword search did not scan every mail
+	// matching the other filters, so older matches can be missing
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// mail of the inbox that failed to decrypt, e.g. a plaintext mail
+// from before encryption or a broken ciphertext of its sender
+type UndecryptableMail struct {
+	ID    uuid.UUID `json:"id"`
+	From  string    `json:"from"`
+	Error string    `json:"error"`
+}
+
+type GetSentRequest struct {
+	Header
+}
+
+// subject and body of sent mails are encrypted to the recipient
+type GetSentResponse struct {
+	Sent  []model.Mail `json:"sent"`
 	Total int          `json:"total"`
 }
 
+// filters of a mailbox search, empty filters match every mail.
+// since and until are RFC3339, keywords never leave the client
+// because subject and body are only readable after decryption
+type SearchEmailRequest struct {
+	Header
+	From  string `json:"from,omitempty"`
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+}
+
+// email id is any mail of the thread
+type GetThreadRequest struct {
+	Header
+	EmailID uuid.UUID `json:"email_id"`
+}
+
+// mails of a conversation, oldest first
+type GetThreadResponse struct {
+	ThreadID uuid.UUID    `json:"thread_id"`
+	Mails    []model.Mail `json:"mails"`
+}
+
 type GetEmailRequest struct {
-	ID        uuid.UUID `json:"id"`
-	Timestamp string    `json:"timestamp"`
-	EmailID   uuid.UUID `json:"email_id"`
+	Header
+	EmailID uuid.UUID `json:"email_id"`
 }
 
+// one mail is sent as one request with a delivery for every to and cc
+// recipient, and one request per bcc recipient with only that recipient
+// in bcc and only its delivery, so no signed request reveals the bcc.
+// all requests of a mail share the message id
 type SendEmailRequest struct {
-	ID        uuid.UUID `json:"id"`
-	Timestamp string    `json:"timestamp"`
-	Recipient string    `json:"recipient"`
-	Subject   string    `json:"subject`
-	Body      string    `json:"body"`
+	Header
+	MessageID  uuid.UUID  `json:"message_id"`
+	To         []string   `json:"to"`
+	Cc         []string   `json:"cc"`
+	Bcc        []string   `json:"bcc,omitempty"`
+	Deliveries []Delivery `json:"deliveries"`
+	InReplyTo  *uuid.UUID `json:"in_reply_to,omitempty"`
+	// blobs uploaded before the mail is sent, shared by every delivery
+	Attachments []model.Attachment `json:"attachments,omitempty"`
+}
+
+// subject, body and attachment keys are encrypted to the recipient
+// public key, see account.EncryptMailWithAttachments
+type Delivery struct {
+	Recipient      string `json:"recipient"`
+	EphemeralKey   string `json:"ephemeral_key"`
+	Subject        string `json:"subject"`
+	Body           string `json:"body"`
+	AttachmentKeys string `json:"attachment_keys,omitempty"`
+}
+
+// plaintext of the attachment keys of a delivery is a json list of
+// these, one per attachment, so only recipients can name and decrypt them
+type AttachmentKey struct {
+	Hash string `json:"hash"`
+	Name string `json:"name"`
+	Key  []byte `json:"key"`
+}
+
+// attachment already uploaded, key is from account.EncryptAttachment
+type OutgoingAttachment struct {
+	model.Attachment
+	Name string
+	Key  []byte
+}
+
+// content is the encrypted attachment, base64 in the signed data
+type UploadAttachmentRequest struct {
+	Header
+	Content []byte `json:"content"`
+}
+
+type UploadAttachmentResponse struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// attachment is only readable through a mail the signer sent or received
+type GetAttachmentRequest struct {
+	Header
+	EmailID uuid.UUID `json:"email_id"`
+	Hash    string    `json:"hash"`
+}
+
+// addresses of one mail
+type Recipients struct {
+	To  []string
+	Cc  []string
+	Bcc []string
 }
 
+// id is the delivery to the first recipient of the request,
+// ids has the delivery of every recipient by address
 type SendMailResponse struct {
+	ID        uuid.UUID            `json:"id"`
+	MessageID uuid.UUID            `json:"message_id"`
+	IDs       map[string]uuid.UUID `json:"ids"`
+}
+
+type ChallengeResponse struct {
+	Nonce     string `json:"nonce"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// nonce from the challenge endpoint, it can be used once
+// and replaces id and timestamp of other signed requests
+type LoginRequest struct {
+	Version int        `json:"version"`
+	Action  ActionName `json:"action"`
+	Origin  string     `json:"origin"`
+	Nonce   string     `json:"nonce"`
+	Scopes  []Scope    `json:"scopes"`
+}
+
+// token is sent as "Authorization: Bearer <token>" until it expires or is revoked
+type LoginResponse struct {
+	Token     string  `json:"token"`
+	Address   string  `json:"address"`
+	Scopes    []Scope `json:"scopes"`
+	ExpiresAt string  `json:"expires_at"`
+}
+
+// revoke every session of the signer, signed over a challenge nonce
+// so it works even when the tokens are lost
+type RevokeSessionsRequest struct {
+	Version int        `json:"version"`
+	Action  ActionName `json:"action"`
+	Origin  string     `json:"origin"`
+	Nonce   string     `json:"nonce"`
+}
+
+// used for both logout and revoke sessions
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// used for both delete email and restore email actions
+type TrashEmailRequest struct {
+	Header
+	EmailID uuid.UUID `json:"email_id"`
+}
+
+type TrashEmailResponse struct {
 	ID uuid.UUID `json:"id"`
 }
 
-func NewGetInbox() ([]byte, error) {
+// used for both mark read and mark unread actions
+type MarkEmailsRequest struct {
+	Header
+	EmailIDs []uuid.UUID `json:"email_ids"`
+}
+
+type MarkEmailsResponse struct {
+	Updated int `json:"updated"`
+}
+
+type PurgeTrashRequest struct {
+	Header
+}
+
+type PurgeTrashResponse struct {
+	Purged int `json:"purged"`
+}
+
+// origin is the server base url, e.g. http://localhost:8080
+func NewGetInbox(origin string) ([]byte, error) {
 	getInbox := GetInboxRequest{
-		ID:        uuid.New(),
-		Timestamp: time.Now().Format(time.RFC3339),
+		Header: newHeader(GetInbox, origin),
 	}
 
 	inbox, err := json.Marshal(getInbox)
@@ -58,11 +302,71 @@ func NewGetInbox() ([]byte, error) {
 	return inbox, nil
 }
 
-func NewGetEmail(id uuid.UUID) ([]byte, error) {
+func NewGetSent(origin string) ([]byte, error) {
+	getSent := GetSentRequest{
+		Header: newHeader(GetSent, origin),
+	}
+
+	sent, err := json.Marshal(getSent)
+	if err != nil {
+		return nil, err
+	}
+
+	return sent, nil
+}
+
+// from is a sender address, since and until are RFC3339 times, any of them can be empty
+func NewSearchEmail(origin string, from string, since string, until string) ([]byte, error) {
+	if from != "" {
+		publicKey, err := account.ParseAddress(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sender address: %w", err)
+		}
+		from = account.PublicKeyToAddress(publicKey).String()
+	}
+	for _, value := range []string{since, until} {
+		if value == "" {
+			continue
+		}
+		_, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q: time should be RFC3339", value)
+		}
+	}
+
+	searchEmail := SearchEmailRequest{
+		Header: newHeader(SearchEmail, origin),
+		From:   from,
+		Since:  since,
+		Until:  until,
+	}
+
+	message, err := json.Marshal(searchEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func NewGetThread(origin string, id uuid.UUID) ([]byte, error) {
+	getThread := GetThreadRequest{
+		Header:  newHeader(GetThread, origin),
+		EmailID: id,
+	}
+
+	message, err := json.Marshal(getThread)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func NewGetEmail(origin string, id uuid.UUID) ([]byte, error) {
 	getEmail := GetEmailRequest{
-		ID:        uuid.New(),
-		Timestamp: time.Now().Format(time.RFC3339),
-		EmailID:   id,
+		Header:  newHeader(GetEmail, origin),
+		EmailID: id,
 	}
 
 	message, err := json.Marshal(getEmail)
@@ -73,19 +377,366 @@ func NewGetEmail(id uuid.UUID) ([]byte, error) {
 	return message, nil
 }
 
-func NewSendEmail(recipient string, subject string, body string) ([]byte, error) {
-	mail := SendEmailRequest{
-		ID:        uuid.New(),
-		Timestamp: time.Now().Format(time.RFC3339),
-		Recipient: recipient,
-		Subject:   subject,
-		Body:      body,
+func NewSendEmail(origin string, recipient string, subject string, body string) ([]byte, error) {
+	messages, err := NewSendEmails(origin, Recipients{To: []string{recipient}}, subject, body, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	strJSON, err := json.Marshal(mail)
+	return messages[0], nil
+}
+
+// reply joins the thread of the mail it replies to
+func NewReplyEmail(origin string, recipient string, subject string, body string, inReplyTo uuid.UUID) ([]byte, error) {
+	messages, err := NewSendEmails(origin, Recipients{To: []string{recipient}}, subject, body, &inReplyTo)
 	if err != nil {
 		return nil, err
 	}
 
-	return strJSON, nil
+	return messages[0], nil
+}
+
+// requests to send one mail, in order: the request for to and cc
+// recipients when there is any, then one request per bcc recipient.
+// in reply to is nil unless the mail is a reply
+func NewSendEmails(origin string, recipients Recipients, subject string, body string, inReplyTo *uuid.UUID) ([][]byte, error) {
+	return NewSendEmailsWithAttachments(origin, recipients, subject, body, inReplyTo, nil)
+}
+
+// same as NewSendEmails, attachments must be uploaded first
+func NewSendEmailsWithAttachments(
+	origin string,
+	recipients Recipients,
+	subject string,
+	body string,
+	inReplyTo *uuid.UUID,
+	attachments []OutgoingAttachment,
+) ([][]byte, error) {
+	// server only accepts the canonical form of an address
+	to, err := canonicalAddresses(recipients.To)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := canonicalAddresses(recipients.Cc)
+	if err != nil {
+		return nil, err
+	}
+	bcc, err := canonicalAddresses(recipients.Bcc)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckRecipients(to, cc, bcc)
+	if err != nil {
+		return nil, err
+	}
+
+	var references []model.Attachment
+	var attachmentKeys []AttachmentKey
+	for _, attachment := range attachments {
+		references = append(references, attachment.Attachment)
+		attachmentKeys = append(attachmentKeys, AttachmentKey{
+			Hash: attachment.Hash,
+			Name: attachment.Name,
+			Key:  attachment.Key,
+		})
+	}
+	err = CheckAttachments(references)
+	if err != nil {
+		return nil, err
+	}
+	plainAttachmentKeys := ""
+	if len(attachmentKeys) > 0 {
+		encoded, err := json.Marshal(attachmentKeys)
+		if err != nil {
+			return nil, err
+		}
+		plainAttachmentKeys = string(encoded)
+	}
+
+	messageID := uuid.New()
+	newRequest := func(bcc []string, deliveryRecipients []string) ([]byte, error) {
+		deliveries := []Delivery{}
+		for _, recipient := range deliveryRecipients {
+			recipientPublicKey, err := account.ParseAddress(recipient)
+			if err != nil {
+				return nil, err
+			}
+			sealed, err := account.EncryptMailWithAttachments(recipientPublicKey, subject, body, plainAttachmentKeys)
+			if err != nil {
+				return nil, err
+			}
+			deliveries = append(deliveries, Delivery{
+				Recipient:      recipient,
+				EphemeralKey:   sealed.EphemeralKey,
+				Subject:        sealed.Subject,
+				Body:           sealed.Body,
+				AttachmentKeys: sealed.AttachmentKeys,
+			})
+		}
+
+		return json.Marshal(SendEmailRequest{
+			Header:      newHeader(SendEmail, origin),
+			MessageID:   messageID,
+			To:          to,
+			Cc:          cc,
+			Bcc:         bcc,
+			Deliveries:  deliveries,
+			InReplyTo:   inReplyTo,
+			Attachments: references,
+		})
+	}
+
+	var messages [][]byte
+	if len(to)+len(cc) > 0 {
+		message, err := newRequest(nil, append(append([]string{}, to...), cc...))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	for _, recipient := range bcc {
+		message, err := newRequest([]string{recipient}, []string{recipient})
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// addresses must be canonical, unique across to, cc and bcc,
+// and there must be at least one and at most MaxRecipients of them
+func CheckRecipients(to []string, cc []string, bcc []string) error {
+	total := len(to) + len(cc) + len(bcc)
+	if total == 0 {
+		return fmt.Errorf("mail has no recipient")
+	}
+	if total > MaxRecipients {
+		return fmt.Errorf("mail has more than %d recipients", MaxRecipients)
+	}
+
+	seen := map[string]bool{}
+	for _, addresses := range [][]string{to, cc, bcc} {
+		for _, address := range addresses {
+			publicKey, err := account.ParseAddress(address)
+			if err != nil {
+				return fmt.Errorf("invalid recipient %s: %w", address, err)
+			}
+			if account.PublicKeyToAddress(publicKey).String() != address {
+				return fmt.Errorf("recipient %s is not in canonical form", address)
+			}
+			if seen[address] {
+				return fmt.Errorf("recipient %s is listed more than once", address)
+			}
+			seen[address] = true
+		}
+	}
+
+	return nil
+}
+
+// attachments of one mail must be referenced by a lower case hex SHA-256
+// with a mime type, at most MaxAttachments of them, each at most
+// MaxAttachmentSize and at most MaxAttachmentsPerMail together
+func CheckAttachments(attachments []model.Attachment) error {
+	if len(attachments) > MaxAttachments {
+		return fmt.Errorf("mail has more than %d attachments", MaxAttachments)
+	}
+
+	var total int64
+	seen := map[string]bool{}
+	for _, attachment := range attachments {
+		hash, err := hex.DecodeString(attachment.Hash)
+		if err != nil || len(hash) != 32 || hex.EncodeToString(hash) != attachment.Hash {
+			return fmt.Errorf("invalid attachment hash %q", attachment.Hash)
+		}
+		if seen[attachment.Hash] {
+			return fmt.Errorf("attachment %s is listed more than once", attachment.Hash)
+		}
+		seen[attachment.Hash] = true
+		if attachment.MimeType == "" {
+			return fmt.Errorf("attachment %s has no mime type", attachment.Hash)
+		}
+		if attachment.Size <= 0 || attachment.Size > MaxAttachmentSize {
+			return fmt.Errorf("attachment should be between 1 and %d bytes", MaxAttachmentSize)
+		}
+		total += attachment.Size
+	}
+	if total > MaxAttachmentsPerMail {
+		return fmt.Errorf("attachments of a mail should be at most %d bytes", MaxAttachmentsPerMail)
+	}
+
+	return nil
+}
+
+func canonicalAddresses(addresses []string) ([]string, error) {
+	canonical := []string{}
+	for _, address := range addresses {
+		publicKey, err := account.ParseAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %s: %w", address, err)
+		}
+		canonical = append(canonical, account.PublicKeyToAddress(publicKey).String())
+	}
+
+	return canonical, nil
+}
+
+// content is an attachment encrypted with account.EncryptAttachment
+func NewUploadAttachment(origin string, content []byte) ([]byte, error) {
+	if len(content) == 0 || len(content) > MaxAttachmentSize {
+		return nil, fmt.Errorf("attachment should be between 1 and %d bytes", MaxAttachmentSize)
+	}
+
+	uploadAttachment := UploadAttachmentRequest{
+		Header:  newHeader(UploadAttachment, origin),
+		Content: content,
+	}
+
+	message, err := json.Marshal(uploadAttachment)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// email id is a mail of the signer that has the attachment
+func NewGetAttachment(origin string, emailID uuid.UUID, hash string) ([]byte, error) {
+	getAttachment := GetAttachmentRequest{
+		Header:  newHeader(GetAttachment, origin),
+		EmailID: emailID,
+		Hash:    hash,
+	}
+
+	message, err := json.Marshal(getAttachment)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// scopes should not be empty, see Scopes
+func NewLogin(origin string, nonce string, scopes []Scope) ([]byte, error) {
+	err := CheckScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	login := LoginRequest{
+		Version: ProtocolVersion,
+		Action:  Login,
+		Origin:  origin,
+		Nonce:   nonce,
+		Scopes:  scopes,
+	}
+
+	message, err := json.Marshal(login)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func NewRevokeSessions(origin string, nonce string) ([]byte, error) {
+	revokeSessions := RevokeSessionsRequest{
+		Version: ProtocolVersion,
+		Action:  RevokeSessions,
+		Origin:  origin,
+		Nonce:   nonce,
+	}
+
+	message, err := json.Marshal(revokeSessions)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// scopes must be known and listed once
+func CheckScopes(scopes []Scope) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("session needs at least one scope")
+	}
+
+	seen := map[Scope]bool{}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+		if seen[scope] {
+			return fmt.Errorf("scope %q is listed more than once", scope)
+		}
+		seen[scope] = true
+	}
+
+	return nil
+}
+
+// move mail to trash of the signer, it can be restored until purged
+func NewDeleteEmail(origin string, id uuid.UUID) ([]byte, error) {
+	return newTrashEmail(origin, DeleteEmail, id)
+}
+
+func NewRestoreEmail(origin string, id uuid.UUID) ([]byte, error) {
+	return newTrashEmail(origin, RestoreEmail, id)
+}
+
+func newTrashEmail(origin string, action ActionName, id uuid.UUID) ([]byte, error) {
+	trashEmail := TrashEmailRequest{
+		Header:  newHeader(action, origin),
+		EmailID: id,
+	}
+
+	message, err := json.Marshal(trashEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// permanently delete every mail in trash of the signer
+func NewPurgeTrash(origin string) ([]byte, error) {
+	purgeTrash := PurgeTrashRequest{
+		Header: newHeader(PurgeTrash, origin),
+	}
+
+	message, err := json.Marshal(purgeTrash)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// mark mails of the signer as read, at most MaxMarkEmails at once
+func NewMarkRead(origin string, ids []uuid.UUID) ([]byte, error) {
+	return newMarkEmails(origin, MarkRead, ids)
+}
+
+func NewMarkUnread(origin string, ids []uuid.UUID) ([]byte, error) {
+	return newMarkEmails(origin, MarkUnread, ids)
+}
+
+func newMarkEmails(origin string, action ActionName, ids []uuid.UUID) ([]byte, error) {
+	if len(ids) == 0 || len(ids) > MaxMarkEmails {
+		return nil, fmt.Errorf("mail ids should be between 1 and %d", MaxMarkEmails)
+	}
+
+	markEmails := MarkEmailsRequest{
+		Header:   newHeader(action, origin),
+		EmailIDs: ids,
+	}
+
+	message, err := json.Marshal(markEmails)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
 }
@@ -0,0 +1,107 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/model"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// send email request of protocol version 2, one recipient per mail
+type sendEmailRequestV2 struct {
+	Action       ActionName `json:"action"`
+	Recipient    string     `json:"recipient"`
+	EphemeralKey string     `json:"ephemeral_key"`
+	Subject      string     `json:"subject"`
+	Body         string     `json:"body"`
+	InReplyTo    *uuid.UUID `json:"in_reply_to,omitempty"`
+}
+
+// verify the sender signature stored with the mail and that the mail
+// content is exactly what the sender signed, so a recipient does not
+// have to trust the server about who sent the mail
+func VerifyMail(mail model.Mail) error {
+	senderPublicKey, err := account.ParseAddress(mail.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	isVerify := account.Verify(senderPublicKey, []byte(mail.SignedData), mail.Signature)
+	if !isVerify {
+		return fmt.Errorf("invalid signature: mail was not signed by %s", mail.From)
+	}
+
+	var header Header
+	err = json.Unmarshal([]byte(mail.SignedData), &header)
+	if err != nil {
+		return fmt.Errorf("invalid signed data: %w", err)
+	}
+	if header.Action != SendEmail {
+		return fmt.Errorf("signed data is not a send email request")
+	}
+	if header.Version < ProtocolVersion {
+		return verifyMailV2(mail)
+	}
+
+	var message SendEmailRequest
+	err = json.Unmarshal([]byte(mail.SignedData), &message)
+	if err != nil {
+		return fmt.Errorf("invalid signed data: %w", err)
+	}
+
+	if message.MessageID != mail.MessageID ||
+		!slices.Equal(message.To, mail.To) ||
+		!slices.Equal(message.Cc, mail.Cc) ||
+		!slices.Equal(message.Bcc, mail.Bcc) ||
+		!sameMailID(message.InReplyTo, mail.InReplyTo) {
+		return fmt.Errorf("mail recipients do not match the signed data")
+	}
+	if !slices.Equal(message.Attachments, mail.Attachments) {
+		return fmt.Errorf("mail attachments do not match the signed data")
+	}
+
+	for _, delivery := range message.Deliveries {
+		if delivery.Recipient != mail.Recipient {
+			continue
+		}
+		if delivery.EphemeralKey != mail.EphemeralKey ||
+			delivery.Subject != mail.Subject ||
+			delivery.Body != mail.Body ||
+			delivery.AttachmentKeys != mail.AttachmentKeys {
+			return fmt.Errorf("mail content does not match the signed data")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("signed data has no delivery to %s", mail.Recipient)
+}
+
+func verifyMailV2(mail model.Mail) error {
+	var message sendEmailRequestV2
+	err := json.Unmarshal([]byte(mail.SignedData), &message)
+	if err != nil {
+		return fmt.Errorf("invalid signed data: %w", err)
+	}
+
+	if message.Recipient != mail.Recipient ||
+		message.EphemeralKey != mail.EphemeralKey ||
+		message.Subject != mail.Subject ||
+		message.Body != mail.Body ||
+		!sameMailID(message.InReplyTo, mail.InReplyTo) {
+		return fmt.Errorf("mail content does not match the signed data")
+	}
+
+	return nil
+}
+
+func sameMailID(a *uuid.UUID, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
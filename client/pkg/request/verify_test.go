@@ -0,0 +1,254 @@
+package request_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/model"
+	"passwordless-mail-client/pkg/request"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyMail(t *testing.T) {
+
+	const (
+		TestOrigin              = "http://localhost:8080"
+		TestSenderPrivateKey    = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestRecipientPrivateKey = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+		TestOtherPrivateKey     = "923cebb3d8809d3caf09faa74ae2a39c23824a6fe75c44cab2a73dc6a0f3b606"
+	)
+
+	// delivery to recipient as the server returns it for a signed send mail request
+	deliveryOf := func(t *testing.T, message []byte, signature []byte, sender *account.Account, recipient string) model.Mail {
+		var sendEmail request.SendEmailRequest
+		assert.NoError(t, json.Unmarshal(message, &sendEmail))
+		mail := model.Mail{
+			MessageID:   sendEmail.MessageID,
+			From:        sender.GetAddress(),
+			Recipient:   recipient,
+			To:          sendEmail.To,
+			Cc:          sendEmail.Cc,
+			Bcc:         sendEmail.Bcc,
+			SignedData:  string(message),
+			Signature:   signature,
+			Attachments: sendEmail.Attachments,
+		}
+		for _, delivery := range sendEmail.Deliveries {
+			if delivery.Recipient == recipient {
+				mail.EphemeralKey = delivery.EphemeralKey
+				mail.Subject = delivery.Subject
+				mail.Body = delivery.Body
+				mail.AttachmentKeys = delivery.AttachmentKeys
+			}
+		}
+
+		return mail
+	}
+
+	newSignedMail := func(t *testing.T, signer *account.Account, sender *account.Account) model.Mail {
+		recipient, err := account.ConnectAccount(TestRecipientPrivateKey)
+		assert.NoError(t, err)
+		message, err := request.NewSendEmail(TestOrigin, recipient.GetAddress(), "test subject", "test body")
+		assert.NoError(t, err)
+		signature, err := signer.Sign(message)
+		assert.NoError(t, err)
+
+		return deliveryOf(t, message, signature, sender, recipient.GetAddress())
+	}
+
+	t.Run("should verify mail signed by the sender", func(t *testing.T) {
+		// Arrange
+		sender, connectErr := account.ConnectAccount(TestSenderPrivateKey)
+		mail := newSignedMail(t, sender, sender)
+
+		// Act
+		verifyErr := request.VerifyMail(mail)
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.NoError(t, verifyErr)
+	})
+
+	t.Run("should reject mail when sender address is not the signer", func(t *testing.T) {
+		// Arrange
+		sender, connectErr1 := account.ConnectAccount(TestSenderPrivateKey)
+		other, connectErr2 := account.ConnectAccount(TestOtherPrivateKey)
+		mail := newSignedMail(t, other, sender)
+
+		// Act
+		verifyErr := request.VerifyMail(mail)
+
+		// Assert
+		assert.NoError(t, connectErr1)
+		assert.NoError(t, connectErr2)
+		assert.Error(t, verifyErr)
+	})
+
+	t.Run("should reject mail when content does not match signed data", func(t *testing.T) {
+		// Arrange
+		sender, connectErr := account.ConnectAccount(TestSenderPrivateKey)
+		mail := newSignedMail(t, sender, sender)
+		mail.Body = mail.Subject
+
+		// Act
+		verifyErr := request.VerifyMail(mail)
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.EqualError(t, verifyErr, "mail content does not match the signed data")
+	})
+
+	t.Run("should reject mail when server moves it to another thread", func(t *testing.T) {
+		// Arrange
+		sender, connectErr := account.ConnectAccount(TestSenderPrivateKey)
+		mail := newSignedMail(t, sender, sender)
+		otherMailID := uuid.New()
+		mail.InReplyTo = &otherMailID
+
+		// Act
+		verifyErr := request.VerifyMail(mail)
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.EqualError(t, verifyErr, "mail recipients do not match the signed data")
+	})
+
+	t.Run("should verify every delivery of a mail with cc and bcc without revealing bcc", func(t *testing.T) {
+		// Arrange
+		sender, senderErr := account.ConnectAccount(TestSenderPrivateKey)
+		recipient, recipientErr := account.ConnectAccount(TestRecipientPrivateKey)
+		other, otherErr := account.ConnectAccount(TestOtherPrivateKey)
+		recipients := request.Recipients{
+			To:  []string{recipient.GetAddress()},
+			Cc:  []string{sender.GetAddress()},
+			Bcc: []string{other.GetAddress()},
+		}
+
+		// Act
+		messages, newMsgErr := request.NewSendEmails(TestOrigin, recipients, "test subject", "test body", nil)
+
+		// Assert
+		assert.NoError(t, senderErr)
+		assert.NoError(t, recipientErr)
+		assert.NoError(t, otherErr)
+		assert.NoError(t, newMsgErr)
+		assert.Equal(t, 2, len(messages))
+		assert.NotContains(t, string(messages[0]), other.GetAddress())
+		for i, delivered := range [][]string{{recipient.GetAddress(), sender.GetAddress()}, {other.GetAddress()}} {
+			signature, signErr := sender.Sign(messages[i])
+			assert.NoError(t, signErr)
+			for _, address := range delivered {
+				mail := deliveryOf(t, messages[i], signature, sender, address)
+				assert.NoError(t, request.VerifyMail(mail))
+			}
+		}
+	})
+
+	t.Run("should reject mail when server adds a recipient", func(t *testing.T) {
+		// Arrange
+		sender, connectErr1 := account.ConnectAccount(TestSenderPrivateKey)
+		other, connectErr2 := account.ConnectAccount(TestOtherPrivateKey)
+		mail := newSignedMail(t, sender, sender)
+		mail.Cc = []string{other.GetAddress()}
+
+		// Act
+		verifyErr := request.VerifyMail(mail)
+
+		// Assert
+		assert.NoError(t, connectErr1)
+		assert.NoError(t, connectErr2)
+		assert.EqualError(t, verifyErr, "mail recipients do not match the signed data")
+	})
+
+	t.Run("should reject recipients listed more than once", func(t *testing.T) {
+		// Arrange
+		recipient, connectErr := account.ConnectAccount(TestRecipientPrivateKey)
+		recipients := request.Recipients{
+			To:  []string{recipient.GetAddress()},
+			Bcc: []string{recipient.GetAddress()},
+		}
+
+		// Act
+		_, newMsgErr := request.NewSendEmails(TestOrigin, recipients, "test subject", "test body", nil)
+
+		// Assert
+		assert.NoError(t, connectErr)
+		assert.Error(t, newMsgErr)
+	})
+
+	t.Run("should verify mail with attachments and reject a swapped attachment", func(t *testing.T) {
+		// Arrange
+		sender, senderErr := account.ConnectAccount(TestSenderPrivateKey)
+		recipient, recipientErr := account.ConnectAccount(TestRecipientPrivateKey)
+		ciphertext, key, encryptErr := account.EncryptAttachment([]byte("test attachment"))
+		attachment := request.OutgoingAttachment{
+			Attachment: model.Attachment{
+				Hash:     fmt.Sprintf("%x", sha256.Sum256(ciphertext)),
+				Size:     int64(len(ciphertext)),
+				MimeType: "text/plain",
+			},
+			Name: "test.txt",
+			Key:  key,
+		}
+		recipients := request.Recipients{To: []string{recipient.GetAddress()}}
+		messages, newMsgErr := request.NewSendEmailsWithAttachments(TestOrigin, recipients, "test subject", "test body", nil, []request.OutgoingAttachment{attachment})
+		signature, signErr := sender.Sign(messages[0])
+		mail := deliveryOf(t, messages[0], signature, sender, recipient.GetAddress())
+		swapped := deliveryOf(t, messages[0], signature, sender, recipient.GetAddress())
+		swapped.Attachments = []model.Attachment{{Hash: fmt.Sprintf("%x", sha256.Sum256([]byte("other"))), Size: 5, MimeType: "text/plain"}}
+
+		// Act
+		verifyErr := request.VerifyMail(mail)
+		swappedErr := request.VerifyMail(swapped)
+		attachmentKeys, decryptErr := recipient.DecryptAttachmentKeys(account.SealedMail{
+			EphemeralKey:   mail.EphemeralKey,
+			AttachmentKeys: mail.AttachmentKeys,
+		})
+
+		// Assert
+		assert.NoError(t, senderErr)
+		assert.NoError(t, recipientErr)
+		assert.NoError(t, encryptErr)
+		assert.NoError(t, newMsgErr)
+		assert.NoError(t, signErr)
+		assert.NoError(t, decryptErr)
+		assert.NoError(t, verifyErr)
+		assert.EqualError(t, swappedErr, "mail attachments do not match the signed data")
+		assert.NotContains(t, string(messages[0]), "test.txt")
+		var keys []request.AttachmentKey
+		assert.NoError(t, json.Unmarshal([]byte(attachmentKeys), &keys))
+		assert.Equal(t, []request.AttachmentKey{{Hash: attachment.Hash, Name: "test.txt", Key: key}}, keys)
+	})
+
+	t.Run("should reject attachments over the size limits", func(t *testing.T) {
+		// Arrange
+		attachment := func(i int, size int64) model.Attachment {
+			return model.Attachment{
+				Hash:     fmt.Sprintf("%x", sha256.Sum256([]byte{byte(i)})),
+				Size:     size,
+				MimeType: "application/octet-stream",
+			}
+		}
+		tooLarge := []model.Attachment{attachment(1, request.MaxAttachmentSize+1)}
+		tooLargeTogether := []model.Attachment{
+			attachment(1, request.MaxAttachmentSize),
+			attachment(2, request.MaxAttachmentSize),
+			attachment(3, request.MaxAttachmentSize),
+		}
+		withinLimits := []model.Attachment{attachment(1, request.MaxAttachmentSize), attachment(2, 1)}
+
+		// Act
+		tooLargeErr := request.CheckAttachments(tooLarge)
+		tooLargeTogetherErr := request.CheckAttachments(tooLargeTogether)
+		withinLimitsErr := request.CheckAttachments(withinLimits)
+
+		// Assert
+		assert.Error(t, tooLargeErr)
+		assert.Error(t, tooLargeTogetherErr)
+		assert.NoError(t, withinLimitsErr)
+	})
+}
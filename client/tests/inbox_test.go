@@ -2,7 +2,9 @@ package tests
 
 import (
 	"bytes"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -15,7 +17,7 @@ func TestInbox(t *testing.T) {
 
 	const (
 		testPrivateKey = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
-		mainPath       = "../cmd/main.go"
+		mainPath       = "../cmd"
 		testQuery      = "../tests/util/query.test.json"
 	)
 
@@ -63,8 +65,10 @@ func TestInbox(t *testing.T) {
 		assert.Equal(t, "exit status 1\n", stderr.String())
 	})
 
-	t.Run("should require user private key", func(t *testing.T) {
+	t.Run("should require an identity when keystore is empty", func(t *testing.T) {
+		keystorePath := filepath.Join(t.TempDir(), "keystore.json")
 		cmd := exec.Command("go", "run", mainPath, "-inbox", testQuery)
+		cmd.Env = append(os.Environ(), "KMAIL_KEYSTORE="+keystorePath)
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
@@ -72,13 +76,14 @@ func TestInbox(t *testing.T) {
 		err := cmd.Run()
 
 		assert.Error(t, err)
-		assert.Equal(t, "user credential is required\n", stdout.String())
+		assert.Equal(t, "no identity in "+keystorePath+", create one with -keygen <name> or -import <name>\n", stdout.String())
 		assert.Equal(t, "exit status 1\n", stderr.String())
 	})
 
-	t.Run("should handle invalid user private key with wrong length", func(t *testing.T) {
-		invalidCredential := "1baa694c49154f63b15" // wrong length
-		cmd := exec.Command("go", "run", mainPath, "-inbox", testQuery, "-user", invalidCredential)
+	t.Run("should handle unknown identity without asking passphrase", func(t *testing.T) {
+		keystorePath := newTestKeystore(t, testPrivateKey)
+		cmd := exec.Command("go", "run", mainPath, "-inbox", testQuery, "-user", "1baa694c49154f63b15")
+		cmd.Env = append(os.Environ(), "KMAIL_KEYSTORE="+keystorePath)
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
@@ -86,13 +91,15 @@ func TestInbox(t *testing.T) {
 		err := cmd.Run()
 
 		assert.Error(t, err)
-		assert.Equal(t, "invalid user credential: credential should be hex with 64 characters long\n", stdout.String())
+		assert.Equal(t, "identity not found: 1baa694c49154f63b15\n", stdout.String())
 		assert.Equal(t, "exit status 1\n", stderr.String())
 	})
 
-	t.Run("should handle invalid user private key with wrong format", func(t *testing.T) {
-		invalidCredential := "x#aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" // non-hex
-		cmd := exec.Command("go", "run", mainPath, "-inbox", testQuery, "-user", invalidCredential)
+	t.Run("should handle wrong passphrase of identity", func(t *testing.T) {
+		keystorePath := newTestKeystore(t, testPrivateKey)
+		cmd := exec.Command("go", "run", mainPath, "-inbox", testQuery, "-user", testIdentity)
+		cmd.Env = append(os.Environ(), "KMAIL_KEYSTORE="+keystorePath)
+		cmd.Stdin = strings.NewReader("wrong passphrase\n")
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
@@ -100,11 +107,27 @@ func TestInbox(t *testing.T) {
 		err := cmd.Run()
 
 		assert.Error(t, err)
-		assert.Equal(t, "invalid user credential: credential should be hex with 64 characters long\n", stdout.String())
-		assert.Equal(t, "exit status 1\n", stderr.String())
+		assert.Equal(t, "wrong passphrase\n", stdout.String())
+		assert.Equal(t, "passphrase of alice: \nexit status 1\n", stderr.String())
+	})
+
+	t.Run("should get inbox of the default identity", func(t *testing.T) {
+		keystorePath := newTestKeystore(t, testPrivateKey)
+		cmd := exec.Command("go", "run", mainPath, "-inbox", testQuery)
+		cmd.Env = append(os.Environ(), "KMAIL_KEYSTORE="+keystorePath)
+		cmd.Stdin = strings.NewReader(testPassphrase + "\n")
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		cmdErr := cmd.Run()
+
+		assert.NoError(t, cmdErr)
+		assert.True(t, strings.Contains(stdout.String(), "inbox"))
+		assert.Equal(t, "passphrase of alice: \n", stderr.String())
 	})
 
-	t.Run("should send correct api request to server", func(t *testing.T) {
+	t.Run("should send correct api request to server with hex private key and warn about it", func(t *testing.T) {
 		cmd := exec.Command("go", "run", mainPath, "-inbox", testQuery, "-user", testPrivateKey)
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
@@ -119,6 +142,6 @@ func TestInbox(t *testing.T) {
 		assert.NoError(t, cmdErr)
 		assert.True(t, haveInbox)
 		assert.True(t, haveTotal)
-		assert.Empty(t, stderr.String())
+		assert.Equal(t, "warning: private key on the command line is visible to other users, import it into the keystore with -import\n", stderr.String())
 	})
 }
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/keystore"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testIdentity   = "alice"
+	testPassphrase = "correct horse battery staple"
+)
+
+// keystore in a temp dir with privateKey as the default identity testIdentity
+func newTestKeystore(t *testing.T, privateKey string) string {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	store, err := keystore.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := account.ConnectAccount(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Add(testIdentity, acc, []byte(testPassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
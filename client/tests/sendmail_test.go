@@ -2,6 +2,7 @@ package tests
 
 import (
 	"bytes"
+	"os"
 	"os/exec"
 	"strings"
 	"testing"
@@ -13,7 +14,7 @@ func TestSendMail(t *testing.T) {
 
 	const (
 		testPrivateKey = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
-		mainPath       = "../cmd/main.go"
+		mainPath       = "../cmd"
 		testMail       = "../tests/util/good.kmail.json"
 	)
 
@@ -41,13 +42,14 @@ func TestSendMail(t *testing.T) {
 		err := cmd.Run()
 
 		assert.Error(t, err)
-		assert.Equal(t, "invalid kmail json: please use this format\n\t{ \"to\":string, \"subject\":string, \"body\":string }\n", stdout.String())
+		assert.Equal(t, "invalid kmail json: please use this format\n\t{ \"to\":string or [string], \"cc\":[string], \"bcc\":[string], \"subject\":string, \"body\":string, \"attachments\":[string] }\n", stdout.String())
 		assert.Equal(t, "exit status 1\n", stderr.String())
 	})
 
-	t.Run("should handle invalid user private key", func(t *testing.T) {
-		badPrivateKey := "y57g8glkrj09ug039[]"
-		cmd := exec.Command("go", "run", mainPath, "-send", testMail, "-user", badPrivateKey)
+	t.Run("should handle unknown identity without asking passphrase", func(t *testing.T) {
+		keystorePath := newTestKeystore(t, testPrivateKey)
+		cmd := exec.Command("go", "run", mainPath, "-send", testMail, "-user", "bob")
+		cmd.Env = append(os.Environ(), "KMAIL_KEYSTORE="+keystorePath)
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
@@ -55,12 +57,47 @@ func TestSendMail(t *testing.T) {
 		err := cmd.Run()
 
 		assert.Error(t, err)
-		assert.Equal(t, "invalid user credential: credential should be hex with 64 characters long\n", stdout.String())
+		assert.Equal(t, "identity not found: bob\n", stdout.String())
 		assert.Equal(t, "exit status 1\n", stderr.String())
 	})
 
+	t.Run("should handle wrong passphrase of identity", func(t *testing.T) {
+		keystorePath := newTestKeystore(t, testPrivateKey)
+		cmd := exec.Command("go", "run", mainPath, "-send", testMail, "-user", testIdentity)
+		cmd.Env = append(os.Environ(), "KMAIL_KEYSTORE="+keystorePath)
+		cmd.Stdin = strings.NewReader("wrong passphrase\n")
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+
+		assert.Error(t, err)
+		assert.Equal(t, "wrong passphrase\n", stdout.String())
+		assert.Equal(t, "passphrase of alice: \nexit status 1\n", stderr.String())
+	})
+
 	t.Run("should send correct api request to server", func(t *testing.T) {
 		// send mail correctly
+		keystorePath := newTestKeystore(t, testPrivateKey)
+		cmd := exec.Command("go", "run", mainPath, "-send", testMail, "-user", testIdentity)
+		cmd.Env = append(os.Environ(), "KMAIL_KEYSTORE="+keystorePath)
+		cmd.Stdin = strings.NewReader(testPassphrase + "\n")
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+
+		result := stdout.String()
+		haveID := strings.Contains(result, "id")
+
+		assert.NoError(t, err)
+		assert.True(t, haveID)
+		assert.Equal(t, "passphrase of alice: \n", stderr.String())
+	})
+
+	t.Run("should send with hex private key and warn about it", func(t *testing.T) {
 		cmd := exec.Command("go", "run", mainPath, "-send", testMail, "-user", testPrivateKey)
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
@@ -73,6 +110,6 @@ func TestSendMail(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.True(t, haveID)
-		assert.Empty(t, stderr.String())
+		assert.Equal(t, "warning: private key on the command line is visible to other users, import it into the keystore with -import\n", stderr.String())
 	})
 }
@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS attachment_keys;
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS attachments;
\ No newline at end of file
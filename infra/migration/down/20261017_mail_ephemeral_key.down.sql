@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS ephemeral_key;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS read_at;
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS mail_message_id_recipient_idx;
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS cc_recipients;
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS to_recipients;
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS recipient_type;
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS message_id;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS mail_recipient_sent_at_idx;
\ No newline at end of file
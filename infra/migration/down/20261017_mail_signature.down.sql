@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS signed_data;
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS signature;
\ No newline at end of file
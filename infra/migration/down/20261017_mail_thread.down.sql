@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS mail_thread_id_idx;
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS thread_id;
+ALTER TABLE IF EXISTS mail DROP COLUMN IF EXISTS in_reply_to;
\ No newline at end of file
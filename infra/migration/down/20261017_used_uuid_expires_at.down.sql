@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS used_uuid_expires_at_idx;
+ALTER TABLE IF EXISTS used_uuid DROP COLUMN IF EXISTS expires_at;
\ No newline at end of file
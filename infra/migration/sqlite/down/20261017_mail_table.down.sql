@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mail;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS used_uuid;
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- uuids are lowercase text, timestamps are UTC text ordered as text,
+-- to_recipients and cc_recipients are text arrays in the postgres array format
+CREATE TABLE IF NOT EXISTS mail (
+    id TEXT PRIMARY KEY DEFAULT (lower(
+        hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+        substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+    )),
+    recipient VARCHAR(128) NOT NULL,
+    sender VARCHAR(128) NOT NULL,
+    mail_subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    sent_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    ephemeral_key VARCHAR(130) NOT NULL DEFAULT '',
+    signed_data TEXT NOT NULL DEFAULT '',
+    signature BLOB,
+    deleted_at TIMESTAMP,
+    read_at TIMESTAMP,
+    in_reply_to TEXT,
+    thread_id TEXT,
+    message_id TEXT,
+    recipient_type VARCHAR(3) NOT NULL DEFAULT 'to',
+    to_recipients TEXT NOT NULL DEFAULT '{}',
+    cc_recipients TEXT NOT NULL DEFAULT '{}',
+    attachments TEXT NOT NULL DEFAULT '[]',
+    attachment_keys TEXT NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS mail_message_id_recipient_idx ON mail (message_id, recipient);
+CREATE INDEX IF NOT EXISTS mail_recipient_sent_at_idx ON mail (recipient, sent_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS mail_recipient_sender_sent_at_idx ON mail (recipient, sender, sent_at DESC);
+CREATE INDEX IF NOT EXISTS mail_thread_id_idx ON mail (thread_id, sent_at);
\ No newline at end of file
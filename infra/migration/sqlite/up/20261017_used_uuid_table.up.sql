@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS used_uuid (
+    uuid TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    expires_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '+3 minutes'))
+);
+CREATE INDEX IF NOT EXISTS used_uuid_expires_at_idx ON used_uuid (expires_at);
\ No newline at end of file
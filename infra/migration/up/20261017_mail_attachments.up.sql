@@ -0,0 +1,2 @@
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS attachment_keys TEXT NOT NULL DEFAULT '';
\ No newline at end of file
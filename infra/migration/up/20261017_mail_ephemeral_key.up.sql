@@ -0,0 +1 @@
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS ephemeral_key VARCHAR(130) NOT NULL DEFAULT '';
\ No newline at end of file
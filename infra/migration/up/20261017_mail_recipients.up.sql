@@ -0,0 +1,6 @@
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS message_id UUID;
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS recipient_type VARCHAR(3) NOT NULL DEFAULT 'to';
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS to_recipients TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS cc_recipients TEXT[] NOT NULL DEFAULT '{}';
+UPDATE mail SET message_id = id, to_recipients = ARRAY[recipient] WHERE message_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS mail_message_id_recipient_idx ON mail (message_id, recipient);
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS mail_recipient_sender_sent_at_idx ON mail (recipient, sender, sent_at DESC);
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS mail_recipient_sent_at_idx ON mail (recipient, sent_at DESC, id DESC);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS signed_data TEXT NOT NULL DEFAULT '';
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS signature BYTEA;
\ No newline at end of file
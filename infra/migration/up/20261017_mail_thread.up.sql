@@ -0,0 +1,4 @@
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS in_reply_to UUID;
+ALTER TABLE mail ADD COLUMN IF NOT EXISTS thread_id UUID;
+UPDATE mail SET thread_id = id WHERE thread_id IS NULL;
+CREATE INDEX IF NOT EXISTS mail_thread_id_idx ON mail (thread_id, sent_at);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE used_uuid ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP + INTERVAL '3 minutes');
+CREATE INDEX IF NOT EXISTS used_uuid_expires_at_idx ON used_uuid (expires_at);
\ No newline at end of file
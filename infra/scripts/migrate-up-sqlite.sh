@@ -0,0 +1,18 @@
+#!/bin/bash
+
+echo "$(date): SQLite migration up..."
+source ../.env
+
+# sqlite://path/to/kmail.db, without the driver options after ?
+databaseFile=${DATABASE_CONNECTION_STRING#sqlite://}
+databaseFile=${databaseFile%%\?*}
+if [ "$databaseFile" == "$DATABASE_CONNECTION_STRING" ] || [ ! "$databaseFile" ]; then
+  echo "DATABASE_CONNECTION_STRING should be sqlite://<database file>"
+  exit 1
+fi
+
+for file in ../migration/sqlite/up/*.up.sql; do
+  sqlite3 "$databaseFile" < $file || exit 1
+done
+
+echo "$(date): SQLite migration up complete"
@@ -3,32 +3,82 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 	handler "passwordless-mail-server/pkg/api"
 	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/blob"
 	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/storage"
 	"passwordless-mail-server/pkg/util"
-
-	_ "github.com/lib/pq"
+	"time"
 )
 
 func main() {
 	const PORT = ":8080"
 
-	database := util.ConnectDatabase()
-	defer database.Close()
+	connectionString, err := util.ConnectionString()
+	if err != nil {
+		log.Fatal(err)
+	}
+	stores, err := storage.Open(connectionString)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stores.Close()
+
+	// origin that clients sign requests for
+	origin := os.Getenv("SERVER_ORIGIN")
+	if origin == "" {
+		origin = "http://localhost" + PORT
+	}
+
+	// mails in trash are purged after retention, e.g. 720h
+	trashRetention := 30 * 24 * time.Hour
+	if retention := os.Getenv("TRASH_RETENTION"); retention != "" {
+		parsed, err := time.ParseDuration(retention)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("invalid TRASH_RETENTION %q", retention)
+		}
+		trashRetention = parsed
+	}
+
+	// session tokens expire after ttl, e.g. 15m
+	sessionTTL := 15 * time.Minute
+	if ttl := os.Getenv("SESSION_TTL"); ttl != "" {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("invalid SESSION_TTL %q", ttl)
+		}
+		sessionTTL = parsed
+	}
+
+	// encrypted attachments are stored as files named by their SHA-256
+	attachmentDir := os.Getenv("ATTACHMENT_DIR")
+	if attachmentDir == "" {
+		attachmentDir = "./data/attachments"
+	}
+	blobStore, err := blob.NewFileStore(attachmentDir)
+	if err != nil {
+		log.Fatalf("can not open ATTACHMENT_DIR %q: %v", attachmentDir, err)
+	}
 
 	// service factory
-	mailStore := mail.NewStore(database)
-	uuidStore := auth.NewUUIDStore(database)
-	mailService := mail.NewService(mailStore, uuidStore)
+	mailService := mail.NewService(stores.Mail, blobStore)
+	authService := auth.NewService(stores.Session, origin, sessionTTL)
+	verifier := auth.NewVerifier(stores.UUID, origin)
+	middleware := handler.NewMiddleware(verifier, authService)
 	mailHandler := handler.NewHandler(mailService)
+	authHandler := handler.NewAuthHandler(authService)
+
+	stopTrashPurge := mail.StartTrashPurge(mailService, trashRetention, time.Hour)
+	defer stopTrashPurge()
+	stopUUIDPrune := auth.StartUUIDPrune(stores.UUID, time.Minute)
+	defer stopUUIDPrune()
+	stopSessionPrune := auth.StartSessionPrune(stores.Session, time.Minute)
+	defer stopSessionPrune()
 
-	// routes
-	http.HandleFunc("/health", mailHandler.HealthCheck)
-	http.HandleFunc("/mail/inbox", mailHandler.GetInbox)
-	http.HandleFunc("/mail", mailHandler.GetMail)
-	http.HandleFunc("/mail/send", mailHandler.SendMail)
+	router := handler.NewRouter(mailHandler, authHandler, middleware)
 
 	log.Printf("Server is running on port %s\n", PORT)
-	log.Fatal(http.ListenAndServe(PORT, nil))
+	log.Fatal(http.ListenAndServe(PORT, router))
 }
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"passwordless-mail-server/pkg/auth"
+)
+
+type AuthHandler interface {
+	Challenge(w http.ResponseWriter, r *http.Request)
+	Login(w http.ResponseWriter, r *http.Request)
+	Logout(w http.ResponseWriter, r *http.Request)
+	RevokeSessions(w http.ResponseWriter, r *http.Request)
+}
+
+type SessionHandler struct {
+	service auth.AuthService
+}
+
+func NewAuthHandler(service auth.AuthService) AuthHandler {
+	return &SessionHandler{
+		service: service,
+	}
+}
+
+// nonce to sign for login or revoke sessions
+func (h *SessionHandler) Challenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	result, err := h.service.Challenge()
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *SessionHandler) Login(w http.ResponseWriter, r *http.Request) {
+	body, publicKey, _, ok := readSignedRequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Login(body, publicKey)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// revoke the bearer token of the request
+func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	token, isBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !isBearer {
+		writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "bearer token is required")
+		return
+	}
+
+	result, err := h.service.Logout(strings.TrimSpace(token))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// revoke every session of the signer, it needs a signature and not a token
+func (h *SessionHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	body, publicKey, _, ok := readSignedRequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.RevokeSessions(body, publicKey)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
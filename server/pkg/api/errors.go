@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"passwordless-mail-client/pkg/request"
+
+	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/model"
+)
+
+// error codes of the response body, clients switch on the code
+// and show the message
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnsupportedVersion  = "unsupported_protocol_version"
+	CodeUnauthorized        = "unauthorized"
+	CodeValidationFailed    = "validation_failed"
+	CodeUUIDUsed            = "uuid_already_used"
+	CodeMessageTimeout      = "message_timeout"
+	CodeActionMismatch      = "action_mismatch"
+	CodeOriginMismatch      = "origin_mismatch"
+	CodeInvalidChallenge    = "invalid_challenge"
+	CodeInvalidToken        = "invalid_token"
+	CodeInsufficientScope   = "insufficient_scope"
+	CodeMailNotFound        = "mail_not_found"
+	CodeAttachmentNotFound  = "attachment_not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeInternalServerError = "internal_error"
+)
+
+var serviceErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{auth.ErrValidationFailed, http.StatusUnauthorized, CodeValidationFailed},
+	{auth.ErrUUIDUsed, http.StatusUnauthorized, CodeUUIDUsed},
+	{auth.ErrMessageTimeout, http.StatusUnauthorized, CodeMessageTimeout},
+	{auth.ErrActionMismatch, http.StatusUnauthorized, CodeActionMismatch},
+	{auth.ErrOriginMismatch, http.StatusUnauthorized, CodeOriginMismatch},
+	{auth.ErrInvalidChallenge, http.StatusUnauthorized, CodeInvalidChallenge},
+	{auth.ErrInvalidToken, http.StatusUnauthorized, CodeInvalidToken},
+	{auth.ErrInsufficientScope, http.StatusForbidden, CodeInsufficientScope},
+	{auth.ErrBadRequest, http.StatusBadRequest, CodeBadRequest},
+	{mail.ErrBadRequest, http.StatusBadRequest, CodeBadRequest},
+	{mail.ErrMailNotFound, http.StatusNotFound, CodeMailNotFound},
+	{mail.ErrAttachmentNotFound, http.StatusNotFound, CodeAttachmentNotFound},
+}
+
+// map service errors to status codes, any other error is logged
+// and answered as internal server error without its details
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, auth.ErrUnsupportedVersion) {
+		message := fmt.Sprintf("unsupported protocol version, please upgrade client to version %d", request.ProtocolVersion)
+		writeError(w, r, http.StatusBadRequest, CodeUnsupportedVersion, message)
+		return
+	}
+	for _, serviceError := range serviceErrors {
+		if errors.Is(err, serviceError.err) {
+			writeError(w, r, serviceError.status, serviceError.code, serviceError.err.Error())
+			return
+		}
+	}
+
+	log.Printf("request %s: %v", RequestIDFromContext(r.Context()), err)
+	writeError(w, r, http.StatusInternalServerError, CodeInternalServerError, "internal server error")
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(model.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: RequestIDFromContext(r.Context()),
+	})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+}
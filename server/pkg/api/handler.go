@@ -3,15 +3,12 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
 	"strconv"
-	"time"
 
 	mail "passwordless-mail-server/pkg/mail"
-	"passwordless-mail-server/pkg/model"
-
-	"github.com/google/uuid"
 )
 
 type Handler struct {
@@ -21,10 +18,24 @@ type Handler struct {
 type MailHandler interface {
 	HealthCheck(w http.ResponseWriter, r *http.Request)
 	GetInbox(w http.ResponseWriter, r *http.Request)
+	GetSent(w http.ResponseWriter, r *http.Request)
+	SearchMail(w http.ResponseWriter, r *http.Request)
 	GetMail(w http.ResponseWriter, r *http.Request)
+	GetThread(w http.ResponseWriter, r *http.Request)
 	SendMail(w http.ResponseWriter, r *http.Request)
+	DeleteMail(w http.ResponseWriter, r *http.Request)
+	RestoreMail(w http.ResponseWriter, r *http.Request)
+	MarkRead(w http.ResponseWriter, r *http.Request)
+	MarkUnread(w http.ResponseWriter, r *http.Request)
+	PurgeTrash(w http.ResponseWriter, r *http.Request)
+	UploadAttachment(w http.ResponseWriter, r *http.Request)
+	GetAttachment(w http.ResponseWriter, r *http.Request)
 }
 
+// signed upload request carries the attachment as base64 inside the signed data
+const MaxUploadBodySize = (request.MaxAttachmentSize+2)/3*4 + 64<<10
+
+// handlers except health check are registered behind the Middleware
 func NewHandler(service mail.MailService) MailHandler {
 	return &Handler{
 		service: service,
@@ -33,7 +44,7 @@ func NewHandler(service mail.MailService) MailHandler {
 
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -42,193 +53,284 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetInbox(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	caller, ok := requireCaller(w, r)
+	if !ok {
+		return
+	}
+
+	// read query params, page is not needed when paging with a cursor
+	var err error
+	params := r.URL.Query()
+	before := params.Get("before")
+	after := params.Get("after")
+	page := 0
+	if before == "" && after == "" {
+		page, err = strconv.Atoi(params.Get("page"))
+		if err != nil || page < 1 {
+			writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid query parameters")
+			return
+		}
+	}
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit < 1 {
+		writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid query parameters")
 		return
 	}
-	// validate public key in header
-	publicKey := r.Header.Get("x-public-key")
-	if publicKey == "" {
-		w.WriteHeader(http.StatusUnauthorized)
+	order := mail.NewestFirst
+	switch params.Get("order") {
+	case "", string(mail.NewestFirst):
+	case string(mail.OldestFirst):
+		order = mail.OldestFirst
+	default:
+		writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid query parameters")
 		return
 	}
+	serviceQuery := mail.ServiceGetInboxQuery{
+		Recipient: caller.Address,
+		Page:      page,
+		Limit:     limit,
+		Order:     order,
+		Before:    before,
+		After:     after,
+	}
 
-	// validate request body
-	body := model.RequestBody{}
-	err := json.NewDecoder(r.Body).Decode(&body)
+	inbox, err := h.service.GetInbox(caller.Body, serviceQuery)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeServiceError(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(inbox)
+}
 
-	// validate public key
-	parsePublicKey, err := account.HexToPublicKey(publicKey)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+func (h *Handler) GetSent(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
 
 	// read query params
 	params := r.URL.Query()
-	pageString := params.Get("page")
-	limitString := params.Get("limit")
-	page, err := strconv.Atoi(pageString)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	page, err := strconv.Atoi(params.Get("page"))
+	if err != nil || page < 1 {
+		writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid query parameters")
 		return
 	}
-	limit, err := strconv.Atoi(limitString)
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit < 1 {
+		writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid query parameters")
+		return
+	}
+	serviceQuery := mail.ServiceGetSentQuery{
+		Sender: caller.Address,
+		Page:   page,
+		Limit:  limit,
+	}
+
+	result, err := h.service.GetSent(caller.Body, serviceQuery)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeServiceError(w, r, err)
 		return
 	}
-	serviceQuery := mail.ServiceGetInboxQuery{
-		Recipient: publicKey,
-		Page:      page,
-		Limit:     limit,
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) SearchMail(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
+		return
 	}
 
-	inbox, err := h.service.GetInbox(body, parsePublicKey, serviceQuery)
-	if err == nil {
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(inbox)
+	// read query params
+	params := r.URL.Query()
+	page, err := strconv.Atoi(params.Get("page"))
+	if err != nil || page < 1 {
+		writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid query parameters")
 		return
 	}
-	if err.Error() == "validation failed" ||
-		err.Error() == "uuid is already used" ||
-		err.Error() == "message timeout" {
-		w.WriteHeader(http.StatusUnauthorized)
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit < 1 {
+		writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid query parameters")
 		return
 	}
-	if err.Error() == "bad request" {
-		w.WriteHeader(http.StatusBadRequest)
+	serviceQuery := mail.ServiceSearchMailQuery{
+		Recipient: caller.Address,
+		Page:      page,
+		Limit:     limit,
+	}
+
+	result, err := h.service.SearchMail(caller.Body, serviceQuery)
+	if err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
-	w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
 }
 
 func (h *Handler) GetMail(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
 
-	userAddress := r.Header.Get("x-public-key")
-	if userAddress == "" {
-		w.WriteHeader(http.StatusUnauthorized)
+	result, err := h.service.GetMail(caller.Body, caller.Address)
+	if err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
 
-	// validate public key
-	publicKey, err := account.HexToPublicKey(userAddress)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+func (h *Handler) SendMail(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
 
-	// validate request body
-	body := model.RequestBody{}
-	err = json.NewDecoder(r.Body).Decode(&body)
+	result, err := h.service.SendMail(caller.Body, caller.Address)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeServiceError(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
 
-	// validate message
-	var message map[string]interface{}
-	err = json.Unmarshal([]byte(body.Data), &message)
-	if err != nil {
-		fmt.Println("err", err)
-		w.WriteHeader(http.StatusBadRequest)
+func (h *Handler) GetThread(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
 
-	_, err = uuid.Parse(message["email_id"].(string))
+	result, err := h.service.GetThread(caller.Body, caller.Address)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeServiceError(w, r, err)
 		return
 	}
-	_, err = uuid.Parse(message["id"].(string))
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) DeleteMail(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
-	_, err = time.Parse(time.RFC3339, message["timestamp"].(string))
+
+	result, err := h.service.DeleteMail(caller.Body, caller.Address)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeServiceError(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
 
-	result, err := h.service.GetMail(body, publicKey, userAddress)
-	if err == nil {
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(result)
+func (h *Handler) RestoreMail(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
-	if err.Error() == "validation failed" ||
-		err.Error() == "uuid is already used" ||
-		err.Error() == "message timeout" {
-		w.WriteHeader(http.StatusUnauthorized)
+
+	result, err := h.service.RestoreMail(caller.Body, caller.Address)
+	if err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
-	if err.Error() == "bad request" {
-		w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
-	if err.Error() == "mail not found" {
-		w.WriteHeader(http.StatusNotFound)
+
+	result, err := h.service.MarkRead(caller.Body, caller.Address)
+	if err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
-
-	w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
 }
 
-func (h *Handler) SendMail(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+func (h *Handler) MarkUnread(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
-	// validate public key in header
-	hexPublicKey := r.Header.Get("x-public-key")
-	if hexPublicKey == "" {
-		w.WriteHeader(http.StatusUnauthorized)
+
+	result, err := h.service.MarkUnread(caller.Body, caller.Address)
+	if err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
 
-	// validate public key
-	publicKey, err := account.HexToPublicKey(hexPublicKey)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+func (h *Handler) PurgeTrash(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
 
-	// validate request body
-	body := model.RequestBody{}
-	err = json.NewDecoder(r.Body).Decode(&body)
+	result, err := h.service.PurgeTrash(caller.Body, caller.Address)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeServiceError(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
 
-	result, err := h.service.SendMail(body, publicKey)
-	if err == nil {
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(result)
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
 		return
 	}
-	if err.Error() == "validation failed" ||
-		err.Error() == "uuid is already used" ||
-		err.Error() == "message timeout" {
-		w.WriteHeader(http.StatusUnauthorized)
+
+	result, err := h.service.UploadAttachment(caller.Body)
+	if err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
-	if err.Error() == "invalid recipient public key" ||
-		err.Error() == "bad request" {
-		w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// response body is the encrypted attachment as uploaded by the sender
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	caller, ok := requireCaller(w, r)
+	if !ok {
+		return
+	}
+
+	content, attachment, err := h.service.GetAttachment(caller.Body, caller.Address)
+	if err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
+	defer content.Close()
 
-	// fmt.Println(result)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+// caller injected by the middleware, a handler that is registered
+// without it answers internal server error
+func requireCaller(w http.ResponseWriter, r *http.Request) (Caller, bool) {
+	caller, ok := CallerFromContext(r.Context())
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternalServerError, "internal server error")
+	}
 
-	w.WriteHeader(http.StatusInternalServerError)
+	return caller, ok
 }
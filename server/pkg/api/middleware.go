@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"strings"
+
+	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/model"
+
+	"github.com/google/uuid"
+)
+
+// user of a verified request, handlers behind the middleware read it
+// from the request context with CallerFromContext
+type Caller struct {
+	Address   string
+	PublicKey *ecdsa.PublicKey
+	Body      model.RequestBody
+}
+
+type callerKey struct{}
+
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+
+	return caller, ok
+}
+
+type requestIDKey struct{}
+
+// give every request an id that error responses and the server log refer to
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := uuid.New().String()
+		w.Header().Set("X-Request-Id", requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+	})
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+
+	return requestID
+}
+
+type Middleware struct {
+	verifier auth.RequestVerifier
+	sessions auth.AuthService
+}
+
+// verifier checks signed requests, sessions checks bearer tokens
+// that are accepted in place of a signature
+func NewMiddleware(verifier auth.RequestVerifier, sessions auth.AuthService) *Middleware {
+	return &Middleware{
+		verifier: verifier,
+		sessions: sessions,
+	}
+}
+
+// accept a request signed for action or sent with a session token that has scope
+func (m *Middleware) Authenticated(action request.ActionName, scope request.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, isBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !isBearer {
+			m.serveSigned(w, r, action, next)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		userAddress, err := m.sessions.Authenticate(strings.TrimSpace(token), scope)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		publicKey, err := account.ParseAddress(userAddress)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternalServerError, "internal server error")
+			return
+		}
+
+		body := model.RequestBody{}
+		err = json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid request body")
+			return
+		}
+		body.Authenticated = true
+
+		m.serve(w, r, action, Caller{Address: userAddress, PublicKey: publicKey, Body: body}, next)
+	}
+}
+
+// accept only a request signed for action, for requests that are
+// stored with their signature such as send mail
+func (m *Middleware) Signed(action request.ActionName, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.serveSigned(w, r, action, next)
+	}
+}
+
+func (m *Middleware) serveSigned(w http.ResponseWriter, r *http.Request, action request.ActionName, next http.HandlerFunc) {
+	body, publicKey, userAddress, ok := readSignedRequest(w, r)
+	if !ok {
+		return
+	}
+
+	m.serve(w, r, action, Caller{Address: userAddress, PublicKey: publicKey, Body: body}, next)
+}
+
+func (m *Middleware) serve(w http.ResponseWriter, r *http.Request, action request.ActionName, caller Caller, next http.HandlerFunc) {
+	err := m.verifier.Verify(caller.Body, caller.PublicKey, action)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	next(w, r.WithContext(WithCaller(r.Context(), caller)))
+}
+
+// limit the body a handler reads, wraps the middleware so it
+// applies before the request is decoded
+func LimitBody(limit int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	}
+}
+
+// check method, public key header and body of a signed POST request,
+// the response is already written when ok is false
+func readSignedRequest(w http.ResponseWriter, r *http.Request) (model.RequestBody, *ecdsa.PublicKey, string, bool) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return model.RequestBody{}, nil, "", false
+	}
+
+	userAddress := r.Header.Get("x-public-key")
+	if userAddress == "" {
+		writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "x-public-key header is required")
+		return model.RequestBody{}, nil, "", false
+	}
+
+	publicKey, err := account.ParseAddress(userAddress)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "x-public-key header is not a valid address")
+		return model.RequestBody{}, nil, "", false
+	}
+	userAddress = account.PublicKeyToAddress(publicKey).String()
+
+	body := model.RequestBody{}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeBadRequest, "invalid request body")
+		return model.RequestBody{}, nil, "", false
+	}
+
+	return body, publicKey, userAddress, true
+}
@@ -0,0 +1,209 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/api"
+	"passwordless-mail-server/pkg/auth"
+	authmocks "passwordless-mail-server/pkg/auth/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMiddleware(t *testing.T) {
+
+	const (
+		TestOrigin     = "http://localhost:8080"
+		TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+	)
+
+	var (
+		testAccount *account.Account
+		err         error
+
+		mockUUIDStore    authmocks.UuidStore
+		mockSessionStore authmocks.SessionStore
+		middleware       *api.Middleware
+		caller           *api.Caller
+		handler          http.HandlerFunc
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockUUIDStore = authmocks.UuidStore{}
+		mockSessionStore = authmocks.SessionStore{}
+		mockUUIDStore.On("InsertUsedUUID", mock.Anything, mock.Anything).Return(nil)
+		middleware = api.NewMiddleware(
+			auth.NewVerifier(&mockUUIDStore, TestOrigin),
+			auth.NewService(&mockSessionStore, TestOrigin, time.Hour),
+		)
+
+		// remember the caller the handler was reached with
+		caller = nil
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			found, ok := api.CallerFromContext(r.Context())
+			if ok {
+				caller = &found
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+
+	newRequest := func(body model.RequestBody) *http.Request {
+		requestBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		return httptest.NewRequest(http.MethodPost, "/mail/inbox", strings.NewReader(string(requestBody)))
+	}
+
+	signedRequest := func(message []byte) *http.Request {
+		signature, err := testAccount.Sign(message)
+		assert.NoError(t, err)
+		httpRequest := newRequest(model.RequestBody{Data: string(message), Signature: signature})
+		httpRequest.Header.Add("x-public-key", testAccount.GetAddress())
+
+		return httpRequest
+	}
+
+	bearerRequest := func(message []byte, scopes []string) *http.Request {
+		session := &model.SessionEntity{
+			Address:   testAccount.GetAddress(),
+			Scopes:    scopes,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+		mockSessionStore.On("GetSession", mock.Anything).Return(session, nil)
+		httpRequest := newRequest(model.RequestBody{Data: string(message)})
+		httpRequest.Header.Add("Authorization", "Bearer test-token")
+
+		return httpRequest
+	}
+
+	t.Run("should pass caller of a verified signed request to the handler", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		middleware.Authenticated(request.GetInbox, request.ScopeRead, handler)(recorder, signedRequest(message))
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, testAccount.GetAddress(), caller.Address)
+		assert.Equal(t, string(message), caller.Body.Data)
+		assert.False(t, caller.Body.Authenticated)
+		mockUUIDStore.AssertNumberOfCalls(t, "InsertUsedUUID", 1)
+	})
+
+	t.Run("should return unauthorized without calling handler when request is signed for another action", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewPurgeTrash(TestOrigin)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		middleware.Authenticated(request.GetInbox, request.ScopeRead, handler)(recorder, signedRequest(message))
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, caller)
+	})
+
+	t.Run("should answer error code, message and request id of a failed verification", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox("https://other.server")
+		recorder := httptest.NewRecorder()
+		withRequestID := api.RequestID(middleware.Authenticated(request.GetInbox, request.ScopeRead, handler))
+
+		// Act
+		withRequestID.ServeHTTP(recorder, signedRequest(message))
+		var errorResponse model.ErrorResponse
+		decodeErr := json.NewDecoder(recorder.Body).Decode(&errorResponse)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, decodeErr)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Equal(t, api.CodeOriginMismatch, errorResponse.Code)
+		assert.Equal(t, "origin mismatch", errorResponse.Message)
+		assert.Equal(t, recorder.Header().Get("X-Request-Id"), errorResponse.RequestID)
+		assert.NotEmpty(t, errorResponse.RequestID)
+	})
+
+	t.Run("should return unauthorized when public key header is missing", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		httpRequest := signedRequest(message)
+		httpRequest.Header.Del("x-public-key")
+		recorder := httptest.NewRecorder()
+
+		// Act
+		middleware.Authenticated(request.GetInbox, request.ScopeRead, handler)(recorder, httpRequest)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, caller)
+	})
+
+	t.Run("should pass caller of a session token with the scope without using a uuid", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		middleware.Authenticated(request.GetInbox, request.ScopeRead, handler)(recorder, bearerRequest(message, []string{"read"}))
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, testAccount.GetAddress(), caller.Address)
+		assert.True(t, caller.Body.Authenticated)
+		mockUUIDStore.AssertNotCalled(t, "InsertUsedUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return forbidden when session token does not have the scope", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewPurgeTrash(TestOrigin)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		middleware.Authenticated(request.PurgeTrash, request.ScopeWrite, handler)(recorder, bearerRequest(message, []string{"read"}))
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Nil(t, caller)
+	})
+
+	t.Run("should not accept session token where a signature is required", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		recorder := httptest.NewRecorder()
+
+		// Act
+		middleware.Signed(request.GetInbox, handler)(recorder, bearerRequest(message, []string{"read", "write"}))
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, caller)
+	})
+}
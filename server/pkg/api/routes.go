@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"passwordless-mail-client/pkg/request"
+)
+
+// every route of the server, mail routes are verified by the middleware for their action
+func NewRouter(mailHandler MailHandler, authHandler AuthHandler, middleware *Middleware) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", mailHandler.HealthCheck)
+	mux.HandleFunc("/auth/challenge", authHandler.Challenge)
+	mux.HandleFunc("/auth/login", authHandler.Login)
+	mux.HandleFunc("/auth/logout", authHandler.Logout)
+	mux.HandleFunc("/auth/revoke", authHandler.RevokeSessions)
+	mux.HandleFunc("/mail/inbox", middleware.Authenticated(request.GetInbox, request.ScopeRead, mailHandler.GetInbox))
+	mux.HandleFunc("/mail/sent", middleware.Authenticated(request.GetSent, request.ScopeRead, mailHandler.GetSent))
+	mux.HandleFunc("/mail/search", middleware.Authenticated(request.SearchEmail, request.ScopeRead, mailHandler.SearchMail))
+	mux.HandleFunc("/mail", middleware.Authenticated(request.GetEmail, request.ScopeRead, mailHandler.GetMail))
+	mux.HandleFunc("/mail/thread", middleware.Authenticated(request.GetThread, request.ScopeRead, mailHandler.GetThread))
+	mux.HandleFunc("/mail/send", middleware.Signed(request.SendEmail, mailHandler.SendMail))
+	mux.HandleFunc("/mail/delete", middleware.Authenticated(request.DeleteEmail, request.ScopeWrite, mailHandler.DeleteMail))
+	mux.HandleFunc("/mail/restore", middleware.Authenticated(request.RestoreEmail, request.ScopeWrite, mailHandler.RestoreMail))
+	mux.HandleFunc("/mail/trash/purge", middleware.Authenticated(request.PurgeTrash, request.ScopeWrite, mailHandler.PurgeTrash))
+	mux.HandleFunc("/mail/read", middleware.Authenticated(request.MarkRead, request.ScopeWrite, mailHandler.MarkRead))
+	mux.HandleFunc("/mail/unread", middleware.Authenticated(request.MarkUnread, request.ScopeWrite, mailHandler.MarkUnread))
+	mux.HandleFunc("/mail/attachment/upload", LimitBody(MaxUploadBodySize,
+		middleware.Authenticated(request.UploadAttachment, request.ScopeWrite, mailHandler.UploadAttachment)))
+	mux.HandleFunc("/mail/attachment", middleware.Authenticated(request.GetAttachment, request.ScopeRead, mailHandler.GetAttachment))
+
+	return RequestID(mux)
+}
@@ -0,0 +1,18 @@
+package auth
+
+import "errors"
+
+// errors of signed requests and sessions, the api maps each of them
+// to a status code and an error code of the response
+var (
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+	ErrValidationFailed   = errors.New("validation failed")
+	ErrUUIDUsed           = errors.New("uuid is already used")
+	ErrMessageTimeout     = errors.New("message timeout")
+	ErrActionMismatch     = errors.New("action mismatch")
+	ErrOriginMismatch     = errors.New("origin mismatch")
+	ErrInvalidChallenge   = errors.New("invalid challenge")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInsufficientScope  = errors.New("insufficient scope")
+	ErrBadRequest         = errors.New("bad request")
+)
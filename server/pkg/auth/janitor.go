@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"log"
+	"time"
+)
+
+// delete used uuids past their expiry every interval until stop is called
+func StartUUIDPrune(uuidStore UuidStore, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				pruned, err := uuidStore.DeleteExpiredUUIDs(time.Now())
+				if err != nil {
+					log.Printf("failed to prune expired uuids: %v\n", err)
+					continue
+				}
+				if pruned > 0 {
+					log.Printf("pruned %d expired uuids\n", pruned)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// delete expired challenges and expired or revoked sessions every
+// interval until stop is called
+func StartSessionPrune(sessionStore SessionStore, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				now := time.Now()
+				challenges, err := sessionStore.DeleteExpiredChallenges(now)
+				if err != nil {
+					log.Printf("failed to prune expired challenges: %v\n", err)
+				}
+				sessions, err := sessionStore.DeleteExpiredSessions(now)
+				if err != nil {
+					log.Printf("failed to prune expired sessions: %v\n", err)
+				}
+				if challenges > 0 || sessions > 0 {
+					log.Printf("pruned %d expired challenges and %d expired or revoked sessions\n", challenges, sessions)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"passwordless-mail-server/pkg/model"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UuidStore and SessionStore kept in the process, everything is lost when
+// it stops. it behaves like Store, e.g. a used uuid is ErrUUIDUsed
+type MemoryStore struct {
+	mu         sync.Mutex
+	usedUUIDs  map[uuid.UUID]model.UsedUUIDEntity
+	challenges map[string]model.ChallengeEntity
+	sessions   map[string]model.SessionEntity
+	// timestamps have the precision of a postgres timestamp
+	now func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		usedUUIDs:  map[uuid.UUID]model.UsedUUIDEntity{},
+		challenges: map[string]model.ChallengeEntity{},
+		sessions:   map[string]model.SessionEntity{},
+		now: func() time.Time {
+			return time.Now().UTC().Truncate(time.Microsecond)
+		},
+	}
+}
+
+func (s *MemoryStore) GetUsedUUID(uuid uuid.UUID) (*model.UsedUUIDEntity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entity, ok := s.usedUUIDs[uuid]
+	if !ok {
+		return nil, nil
+	}
+
+	return &entity, nil
+}
+
+func (s *MemoryStore) InsertUsedUUID(uuid uuid.UUID, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.usedUUIDs[uuid]; ok {
+		return ErrUUIDUsed
+	}
+	s.usedUUIDs[uuid] = model.UsedUUIDEntity{
+		UUID:      uuid,
+		CreatedAt: s.now().Format(time.RFC3339Nano),
+		ExpiresAt: expiresAt.UTC().Truncate(time.Microsecond),
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpiredUUIDs(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for id, entity := range s.usedUUIDs {
+		if entity.ExpiresAt.Before(before) {
+			delete(s.usedUUIDs, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *MemoryStore) InsertChallenge(challenge model.ChallengeEntity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.challenges[challenge.Nonce]; ok {
+		return fmt.Errorf("challenge %s already exists", challenge.Nonce)
+	}
+	challenge.ExpiresAt = challenge.ExpiresAt.UTC().Truncate(time.Microsecond)
+	s.challenges[challenge.Nonce] = challenge
+
+	return nil
+}
+
+// see Store.ConsumeChallenge
+func (s *MemoryStore) ConsumeChallenge(nonce string) (*model.ChallengeEntity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, ok := s.challenges[nonce]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.challenges, nonce)
+
+	return &challenge, nil
+}
+
+func (s *MemoryStore) DeleteExpiredChallenges(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for nonce, challenge := range s.challenges {
+		if challenge.ExpiresAt.Before(before) {
+			delete(s.challenges, nonce)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *MemoryStore) InsertSession(session model.SessionEntity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[session.TokenHash]; ok {
+		return fmt.Errorf("session already exists")
+	}
+	session.Scopes = append([]string{}, session.Scopes...)
+	session.CreatedAt = s.now()
+	session.ExpiresAt = session.ExpiresAt.UTC().Truncate(time.Microsecond)
+	session.RevokedAt = nil
+	s.sessions[session.TokenHash] = session
+
+	return nil
+}
+
+// see Store.GetSession
+func (s *MemoryStore) GetSession(tokenHash string) (*model.SessionEntity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	session.Scopes = append([]string{}, session.Scopes...)
+	if session.RevokedAt != nil {
+		revokedAt := *session.RevokedAt
+		session.RevokedAt = &revokedAt
+	}
+
+	return &session, nil
+}
+
+// sql.ErrNoRows when there is no session that is not revoked yet
+func (s *MemoryStore) RevokeSession(tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[tokenHash]
+	if !ok || session.RevokedAt != nil {
+		return sql.ErrNoRows
+	}
+	revokedAt := s.now()
+	session.RevokedAt = &revokedAt
+	s.sessions[tokenHash] = session
+
+	return nil
+}
+
+// revoke every session of address that is not revoked yet
+func (s *MemoryStore) RevokeSessions(address string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revoked := 0
+	revokedAt := s.now()
+	for tokenHash, session := range s.sessions {
+		if session.Address != address || session.RevokedAt != nil {
+			continue
+		}
+		session.RevokedAt = &revokedAt
+		s.sessions[tokenHash] = session
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// see Store.DeleteExpiredSessions
+func (s *MemoryStore) DeleteExpiredSessions(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for tokenHash, session := range s.sessions {
+		revoked := session.RevokedAt != nil && session.RevokedAt.Before(before)
+		if session.ExpiresAt.Before(before) || revoked {
+			delete(s.sessions, tokenHash)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
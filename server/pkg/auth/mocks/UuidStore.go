@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	time "time"
+
 	uuid "github.com/google/uuid"
 )
 
@@ -15,6 +17,34 @@ type UuidStore struct {
 	mock.Mock
 }
 
+// DeleteExpiredUUIDs provides a mock function with given fields: before
+func (_m *UuidStore) DeleteExpiredUUIDs(before time.Time) (int, error) {
+	ret := _m.Called(before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpiredUUIDs")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time) (int, error)); ok {
+		return rf(before)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time) int); ok {
+		r0 = rf(before)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = rf(before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUsedUUID provides a mock function with given fields: _a0
 func (_m *UuidStore) GetUsedUUID(_a0 uuid.UUID) (*model.UsedUUIDEntity, error) {
 	ret := _m.Called(_a0)
@@ -45,17 +75,17 @@ func (_m *UuidStore) GetUsedUUID(_a0 uuid.UUID) (*model.UsedUUIDEntity, error) {
 	return r0, r1
 }
 
-// InsertUsedUUID provides a mock function with given fields: _a0
-func (_m *UuidStore) InsertUsedUUID(_a0 uuid.UUID) error {
-	ret := _m.Called(_a0)
+// InsertUsedUUID provides a mock function with given fields: _a0, expiresAt
+func (_m *UuidStore) InsertUsedUUID(_a0 uuid.UUID, expiresAt time.Time) error {
+	ret := _m.Called(_a0, expiresAt)
 
 	if len(ret) == 0 {
 		panic("no return value specified for InsertUsedUUID")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(uuid.UUID, time.Time) error); ok {
+		r0 = rf(_a0, expiresAt)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"slices"
+	"time"
+)
+
+// how long a challenge nonce can be signed for login
+const ChallengeTimeout = 2 * time.Minute
+
+type AuthService interface {
+	Challenge() (model.ChallengeResponse, error)
+	Login(request model.RequestBody, publicKey *ecdsa.PublicKey) (model.LoginResponse, error)
+	Authenticate(token string, scope request.Scope) (string, error)
+	Logout(token string) (model.RevokeSessionsResponse, error)
+	RevokeSessions(request model.RequestBody, publicKey *ecdsa.PublicKey) (model.RevokeSessionsResponse, error)
+}
+
+type Service struct {
+	sessionStore SessionStore
+	origin       string
+	sessionTTL   time.Duration
+}
+
+// origin is the base url clients sign requests for, e.g. http://localhost:8080,
+// session ttl is how long a token is valid after login
+func NewService(sessionStore SessionStore, origin string, sessionTTL time.Duration) AuthService {
+	return &Service{
+		sessionStore: sessionStore,
+		origin:       origin,
+		sessionTTL:   sessionTTL,
+	}
+}
+
+// new random nonce for login or revoke sessions
+func (s *Service) Challenge() (model.ChallengeResponse, error) {
+	now := time.Now().UTC()
+	_, err := s.sessionStore.DeleteExpiredChallenges(now)
+	if err != nil {
+		return model.ChallengeResponse{}, err
+	}
+
+	nonce, err := randomToken()
+	if err != nil {
+		return model.ChallengeResponse{}, err
+	}
+	challenge := model.ChallengeEntity{
+		Nonce:     nonce,
+		ExpiresAt: now.Add(ChallengeTimeout),
+	}
+	err = s.sessionStore.InsertChallenge(challenge)
+	if err != nil {
+		return model.ChallengeResponse{}, err
+	}
+
+	return model.ChallengeResponse{
+		Nonce:     challenge.Nonce,
+		ExpiresAt: challenge.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// exchange a signed challenge for a bearer token limited to the signed scopes
+func (s *Service) Login(payload model.RequestBody, publicKey *ecdsa.PublicKey) (model.LoginResponse, error) {
+	var message request.LoginRequest
+	err := s.verifyChallenge(payload, publicKey, request.Login, &message)
+	if err != nil {
+		return model.LoginResponse{}, err
+	}
+	err = request.CheckScopes(message.Scopes)
+	if err != nil {
+		return model.LoginResponse{}, ErrBadRequest
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return model.LoginResponse{}, err
+	}
+	var scopes []string
+	for _, scope := range message.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+	session := model.SessionEntity{
+		TokenHash: hashToken(token),
+		Address:   account.PublicKeyToAddress(publicKey).String(),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().UTC().Add(s.sessionTTL),
+	}
+	err = s.sessionStore.InsertSession(session)
+	if err != nil {
+		return model.LoginResponse{}, err
+	}
+
+	return model.LoginResponse{
+		Token:     token,
+		Address:   session.Address,
+		Scopes:    session.Scopes,
+		ExpiresAt: session.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// address of the session when token is valid and has the scope
+func (s *Service) Authenticate(token string, scope request.Scope) (string, error) {
+	session, err := s.sessionStore.GetSession(hashToken(token))
+	if err != nil {
+		return "", err
+	}
+	if session == nil ||
+		session.RevokedAt != nil ||
+		!time.Now().Before(session.ExpiresAt) {
+		return "", ErrInvalidToken
+	}
+	if !slices.Contains(session.Scopes, string(scope)) {
+		return "", ErrInsufficientScope
+	}
+
+	return session.Address, nil
+}
+
+// revoke the session of token
+func (s *Service) Logout(token string) (model.RevokeSessionsResponse, error) {
+	err := s.sessionStore.RevokeSession(hashToken(token))
+	if err == sql.ErrNoRows {
+		return model.RevokeSessionsResponse{}, ErrInvalidToken
+	}
+	if err != nil {
+		return model.RevokeSessionsResponse{}, err
+	}
+
+	return model.RevokeSessionsResponse{Revoked: 1}, nil
+}
+
+// revoke every session of the signer
+func (s *Service) RevokeSessions(payload model.RequestBody, publicKey *ecdsa.PublicKey) (model.RevokeSessionsResponse, error) {
+	var message request.RevokeSessionsRequest
+	err := s.verifyChallenge(payload, publicKey, request.RevokeSessions, &message)
+	if err != nil {
+		return model.RevokeSessionsResponse{}, err
+	}
+
+	revoked, err := s.sessionStore.RevokeSessions(account.PublicKeyToAddress(publicKey).String())
+	if err != nil {
+		return model.RevokeSessionsResponse{}, err
+	}
+
+	return model.RevokeSessionsResponse{Revoked: revoked}, nil
+}
+
+// check version, signature, action and origin of a request signed over
+// a challenge nonce and use the nonce up, message is filled on success
+func (s *Service) verifyChallenge(
+	payload model.RequestBody,
+	publicKey *ecdsa.PublicKey,
+	action request.ActionName,
+	message any,
+) error {
+	err := checkProtocolVersion(payload.Data)
+	if err != nil {
+		return err
+	}
+
+	isVerify := account.Verify(
+		publicKey,
+		[]byte(payload.Data),
+		[]byte(payload.Signature),
+	)
+	if !isVerify {
+		return ErrValidationFailed
+	}
+
+	var header struct {
+		Action request.ActionName `json:"action"`
+		Origin string             `json:"origin"`
+		Nonce  string             `json:"nonce"`
+	}
+	err = json.Unmarshal([]byte(payload.Data), &header)
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	err = checkTarget(header.Action, action, header.Origin, s.origin)
+	if err != nil {
+		return err
+	}
+
+	challenge, err := s.sessionStore.ConsumeChallenge(header.Nonce)
+	if err != nil {
+		return err
+	}
+	if challenge == nil || !time.Now().Before(challenge.ExpiresAt) {
+		return ErrInvalidChallenge
+	}
+
+	err = json.Unmarshal([]byte(payload.Data), message)
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	return nil
+}
+
+// 32 random bytes, url safe so a token fits in a header
+func randomToken() (string, error) {
+	random := make([]byte, 32)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"database/sql"
+	"passwordless-mail-server/pkg/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type SessionStore interface {
+	InsertChallenge(challenge model.ChallengeEntity) error
+	ConsumeChallenge(nonce string) (*model.ChallengeEntity, error)
+	DeleteExpiredChallenges(before time.Time) (int, error)
+	InsertSession(session model.SessionEntity) error
+	GetSession(tokenHash string) (*model.SessionEntity, error)
+	RevokeSession(tokenHash string) error
+	RevokeSessions(address string) (int, error)
+	DeleteExpiredSessions(before time.Time) (int, error)
+}
+
+func NewSessionStore(database *sql.DB) SessionStore {
+	return &Store{
+		db: database,
+	}
+}
+
+func (s *Store) InsertChallenge(challenge model.ChallengeEntity) error {
+	queryScript := "INSERT INTO auth_challenge (nonce, expires_at) VALUES ($1, $2)"
+	_, err := s.db.Exec(queryScript, challenge.Nonce, challenge.ExpiresAt.UTC())
+
+	return err
+}
+
+// a challenge is deleted when it is read, so it can only be used once
+// not found 		-> nil, nil
+// found 			-> entity, nil (expiry is checked by the caller)
+func (s *Store) ConsumeChallenge(nonce string) (*model.ChallengeEntity, error) {
+	queryScript := "DELETE FROM auth_challenge WHERE nonce = $1 RETURNING nonce, expires_at"
+	challenge := &model.ChallengeEntity{}
+	err := s.db.QueryRow(queryScript, nonce).Scan(&challenge.Nonce, &challenge.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (s *Store) DeleteExpiredChallenges(before time.Time) (int, error) {
+	queryScript := "DELETE FROM auth_challenge WHERE expires_at < $1"
+	result, err := s.db.Exec(queryScript, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
+
+func (s *Store) InsertSession(session model.SessionEntity) error {
+	queryScript := `
+		INSERT INTO session (token_hash, address, scopes, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := s.db.Exec(
+		queryScript,
+		session.TokenHash,
+		session.Address,
+		pq.Array(session.Scopes),
+		session.ExpiresAt.UTC(),
+	)
+
+	return err
+}
+
+// not found 		-> nil, nil
+// found 			-> entity, nil (expired and revoked sessions included)
+func (s *Store) GetSession(tokenHash string) (*model.SessionEntity, error) {
+	queryScript := `
+		SELECT token_hash, address, scopes, created_at, expires_at, revoked_at
+		FROM session
+		WHERE token_hash = $1
+	`
+	session := &model.SessionEntity{}
+	err := s.db.QueryRow(queryScript, tokenHash).Scan(
+		&session.TokenHash,
+		&session.Address,
+		pq.Array(&session.Scopes),
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// sql.ErrNoRows when there is no session that is not revoked yet
+func (s *Store) RevokeSession(tokenHash string) error {
+	queryScript := `
+		UPDATE session SET revoked_at = NOW()
+		WHERE token_hash = $1
+		AND revoked_at IS NULL
+	`
+	result, err := s.db.Exec(queryScript, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// revoke every session of address that is not revoked yet
+func (s *Store) RevokeSessions(address string) (int, error) {
+	queryScript := `
+		UPDATE session SET revoked_at = NOW()
+		WHERE address = $1
+		AND revoked_at IS NULL
+	`
+	result, err := s.db.Exec(queryScript, address)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(revoked), nil
+}
+
+// delete sessions that expired or were revoked before the given time,
+// their token is rejected the same way when the row is gone
+func (s *Store) DeleteExpiredSessions(before time.Time) (int, error) {
+	queryScript := "DELETE FROM session WHERE expires_at < $1 OR revoked_at < $1"
+	result, err := s.db.Exec(queryScript, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
@@ -0,0 +1,164 @@
+package auth_test
+
+import (
+	"database/sql"
+	"fmt"
+	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStore_Challenge(t *testing.T) {
+	var store auth.SessionStore
+
+	beforeEach := func() {
+		store = newSessionStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("auth_challenge")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should return challenge only once", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := store.InsertChallenge(model.ChallengeEntity{Nonce: "nonce", ExpiresAt: time.Now().Add(time.Minute)})
+
+		// Act
+		first, firstErr := store.ConsumeChallenge("nonce")
+		second, secondErr := store.ConsumeChallenge("nonce")
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, firstErr, secondErr)
+		assert.Equal(t, "nonce", first.Nonce)
+		assert.Nil(t, second)
+	})
+
+	t.Run("should delete only expired challenges", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		expiredErr := store.InsertChallenge(model.ChallengeEntity{Nonce: "expired", ExpiresAt: time.Now().Add(-time.Minute)})
+		validErr := store.InsertChallenge(model.ChallengeEntity{Nonce: "valid", ExpiresAt: time.Now().Add(time.Minute)})
+
+		// Act
+		deleted, deleteErr := store.DeleteExpiredChallenges(time.Now())
+		valid, validConsumeErr := store.ConsumeChallenge("valid")
+
+		// Assert
+		util.AssertNoAnyError(t, expiredErr, validErr, deleteErr, validConsumeErr)
+		assert.Equal(t, 1, deleted)
+		assert.NotNil(t, valid)
+	})
+}
+
+func TestSessionStore_Session(t *testing.T) {
+	var store auth.SessionStore
+
+	beforeEach := func() {
+		store = newSessionStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("session")
+		fmt.Println("delete table items error", err)
+	}
+
+	newSession := func(tokenHash string, address string) model.SessionEntity {
+		return model.SessionEntity{
+			TokenHash: tokenHash,
+			Address:   address,
+			Scopes:    []string{"read", "write"},
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	t.Run("should return stored session with scopes", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := store.InsertSession(newSession("hash", "alice"))
+
+		// Act
+		session, getErr := store.GetSession("hash")
+		missing, missingErr := store.GetSession("other hash")
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, getErr, missingErr)
+		assert.Equal(t, "alice", session.Address)
+		assert.Equal(t, []string{"read", "write"}, session.Scopes)
+		assert.Nil(t, session.RevokedAt)
+		assert.Nil(t, missing)
+	})
+
+	t.Run("should revoke one session and return no rows when it is already revoked", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := store.InsertSession(newSession("hash", "alice"))
+
+		// Act
+		revokeErr := store.RevokeSession("hash")
+		againErr := store.RevokeSession("hash")
+		session, getErr := store.GetSession("hash")
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, revokeErr, getErr)
+		assert.Equal(t, sql.ErrNoRows, againErr)
+		assert.NotNil(t, session.RevokedAt)
+	})
+
+	t.Run("should revoke every active session of the address", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErrs := []error{
+			store.InsertSession(newSession("hash-1", "alice")),
+			store.InsertSession(newSession("hash-2", "alice")),
+			store.InsertSession(newSession("hash-3", "bob")),
+		}
+
+		// Act
+		revoked, revokeErr := store.RevokeSessions("alice")
+		bob, getErr := store.GetSession("hash-3")
+
+		// Assert
+		util.AssertNoAnyError(t, append(insertErrs, revokeErr, getErr)...)
+		assert.Equal(t, 2, revoked)
+		assert.Nil(t, bob.RevokedAt)
+	})
+
+	t.Run("should delete only expired and revoked sessions", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		expired := newSession("expired", "alice")
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		insertErrs := []error{
+			store.InsertSession(expired),
+			store.InsertSession(newSession("revoked", "alice")),
+			store.InsertSession(newSession("active", "alice")),
+		}
+		revokeErr := store.RevokeSession("revoked")
+
+		// Act
+		deleted, deleteErr := store.DeleteExpiredSessions(time.Now().Add(time.Second))
+		expiredSession, expiredErr := store.GetSession("expired")
+		revokedSession, revokedErr := store.GetSession("revoked")
+		activeSession, activeErr := store.GetSession("active")
+
+		// Assert
+		util.AssertNoAnyError(t, append(insertErrs, revokeErr, deleteErr, expiredErr, revokedErr, activeErr)...)
+		assert.Equal(t, 2, deleted)
+		assert.Nil(t, expiredSession)
+		assert.Nil(t, revokedSession)
+		assert.NotNil(t, activeSession)
+	})
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"database/sql"
+	"passwordless-mail-server/pkg/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// UuidStore and SessionStore of a sqlite database migrated by
+// infra/migration/sqlite, timestamps are set by the store in UTC
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteUUIDStore(database *sql.DB) UuidStore {
+	return &SQLiteStore{
+		db: database,
+	}
+}
+
+func NewSQLiteSessionStore(database *sql.DB) SessionStore {
+	return &SQLiteStore{
+		db: database,
+	}
+}
+
+// see Store.GetUsedUUID
+func (s *SQLiteStore) GetUsedUUID(uuid uuid.UUID) (*model.UsedUUIDEntity, error) {
+	queryScript := "SELECT uuid, created_at, expires_at FROM used_uuid WHERE uuid = ?1"
+	row := s.db.QueryRow(queryScript, uuid)
+	entity := &model.UsedUUIDEntity{}
+	err := row.Scan(&entity.UUID, &entity.CreatedAt, &entity.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+// see Store.InsertUsedUUID
+func (s *SQLiteStore) InsertUsedUUID(uuid uuid.UUID, expiresAt time.Time) error {
+	queryScript := "INSERT INTO used_uuid (uuid, created_at, expires_at) VALUES (?1, ?2, ?3) ON CONFLICT (uuid) DO NOTHING"
+	result, err := s.db.Exec(queryScript, uuid, sqliteNow(), expiresAt.UTC())
+	if err != nil {
+		return err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return ErrUUIDUsed
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) DeleteExpiredUUIDs(before time.Time) (int, error) {
+	queryScript := "DELETE FROM used_uuid WHERE expires_at < ?1"
+
+	return s.execAffected(queryScript, before.UTC())
+}
+
+func (s *SQLiteStore) InsertChallenge(challenge model.ChallengeEntity) error {
+	queryScript := "INSERT INTO auth_challenge (nonce, expires_at) VALUES (?1, ?2)"
+	_, err := s.db.Exec(queryScript, challenge.Nonce, challenge.ExpiresAt.UTC())
+
+	return err
+}
+
+// see Store.ConsumeChallenge
+func (s *SQLiteStore) ConsumeChallenge(nonce string) (*model.ChallengeEntity, error) {
+	queryScript := "DELETE FROM auth_challenge WHERE nonce = ?1 RETURNING nonce, expires_at"
+	challenge := &model.ChallengeEntity{}
+	err := s.db.QueryRow(queryScript, nonce).Scan(&challenge.Nonce, &challenge.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (s *SQLiteStore) DeleteExpiredChallenges(before time.Time) (int, error) {
+	queryScript := "DELETE FROM auth_challenge WHERE expires_at < ?1"
+
+	return s.execAffected(queryScript, before.UTC())
+}
+
+// scopes are stored in the postgres array format, the same as Store
+func (s *SQLiteStore) InsertSession(session model.SessionEntity) error {
+	queryScript := `
+		INSERT INTO session (token_hash, address, scopes, created_at, expires_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+	`
+
+	_, err := s.db.Exec(
+		queryScript,
+		session.TokenHash,
+		session.Address,
+		pq.Array(session.Scopes),
+		sqliteNow(),
+		session.ExpiresAt.UTC(),
+	)
+
+	return err
+}
+
+// see Store.GetSession
+func (s *SQLiteStore) GetSession(tokenHash string) (*model.SessionEntity, error) {
+	queryScript := `
+		SELECT token_hash, address, scopes, created_at, expires_at, revoked_at
+		FROM session
+		WHERE token_hash = ?1
+	`
+
+	session := &model.SessionEntity{}
+	err := s.db.QueryRow(queryScript, tokenHash).Scan(
+		&session.TokenHash,
+		&session.Address,
+		pq.Array(&session.Scopes),
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// sql.ErrNoRows when there is no session that is not revoked yet
+func (s *SQLiteStore) RevokeSession(tokenHash string) error {
+	queryScript := `
+		UPDATE session SET revoked_at = ?2
+		WHERE token_hash = ?1
+		AND revoked_at IS NULL
+	`
+
+	revoked, err := s.execAffected(queryScript, tokenHash, sqliteNow())
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// revoke every session of address that is not revoked yet
+func (s *SQLiteStore) RevokeSessions(address string) (int, error) {
+	queryScript := `
+		UPDATE session SET revoked_at = ?2
+		WHERE address = ?1
+		AND revoked_at IS NULL
+	`
+
+	return s.execAffected(queryScript, address, sqliteNow())
+}
+
+// see Store.DeleteExpiredSessions
+func (s *SQLiteStore) DeleteExpiredSessions(before time.Time) (int, error) {
+	queryScript := "DELETE FROM session WHERE expires_at < ?1 OR revoked_at < ?1"
+
+	return s.execAffected(queryScript, before.UTC())
+}
+
+// execute queryScript and return the amount of rows it changed
+func (s *SQLiteStore) execAffected(queryScript string, args ...any) (int, error) {
+	result, err := s.db.Exec(queryScript, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), nil
+}
+
+// NOW() of postgres, with the precision of a postgres timestamp
+func sqliteNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
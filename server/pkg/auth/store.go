@@ -2,15 +2,16 @@ package auth
 
 import (
 	"database/sql"
-	"errors"
 	"passwordless-mail-server/pkg/model"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type UuidStore interface {
 	GetUsedUUID(uuid uuid.UUID) (*model.UsedUUIDEntity, error)
-	InsertUsedUUID(uuid uuid.UUID) error
+	InsertUsedUUID(uuid uuid.UUID, expiresAt time.Time) error
+	DeleteExpiredUUIDs(before time.Time) (int, error)
 }
 
 type Store struct {
@@ -27,10 +28,10 @@ func NewUUIDStore(database *sql.DB) UuidStore {
 // found 			-> entity, nil (uuid is used)
 // side effect err	-> nil, error (error occurred)
 func (s *Store) GetUsedUUID(uuid uuid.UUID) (*model.UsedUUIDEntity, error) {
-	queryScript := "SELECT * FROM used_uuid WHERE uuid = $1"
+	queryScript := "SELECT uuid, created_at, expires_at FROM used_uuid WHERE uuid = $1"
 	row := s.db.QueryRow(queryScript, uuid)
 	entity := &model.UsedUUIDEntity{}
-	err := row.Scan(&entity.UUID, &entity.CreatedAt)
+	err := row.Scan(&entity.UUID, &entity.CreatedAt, &entity.ExpiresAt)
 	if err != nil && err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -41,18 +42,41 @@ func (s *Store) GetUsedUUID(uuid uuid.UUID) (*model.UsedUUIDEntity, error) {
 	return entity, nil
 }
 
+// check and insert in one statement, so two requests with the same uuid
+// can not both see it unused
 // success 			-> no error
-// duplicate uuid 	-> error 'uuid ... is already used'
+// duplicate uuid 	-> ErrUUIDUsed
 // side effect err	-> error <error details>
-func (s *Store) InsertUsedUUID(uuid uuid.UUID) error {
-	queryScript := "INSERT INTO used_uuid (uuid) VALUES ($1)"
-	_, err := s.db.Exec(queryScript, uuid)
-	if err != nil && err.Error() == "pq: duplicate key value violates unique constraint \"used_uuid_pkey\"" {
-		return errors.New("uuid " + uuid.String() + " is already used")
+func (s *Store) InsertUsedUUID(uuid uuid.UUID, expiresAt time.Time) error {
+	queryScript := "INSERT INTO used_uuid (uuid, expires_at) VALUES ($1, $2) ON CONFLICT (uuid) DO NOTHING"
+	result, err := s.db.Exec(queryScript, uuid, expiresAt.UTC())
+	if err != nil {
+		return err
 	}
+
+	inserted, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if inserted == 0 {
+		return ErrUUIDUsed
+	}
 
 	return nil
 }
+
+// a request of an expired uuid is rejected by its timestamp, its row is not needed anymore
+func (s *Store) DeleteExpiredUUIDs(before time.Time) (int, error) {
+	queryScript := "DELETE FROM used_uuid WHERE expires_at < $1"
+	result, err := s.db.Exec(queryScript, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
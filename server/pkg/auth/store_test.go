@@ -7,8 +7,11 @@ import (
 	"os"
 	"passwordless-mail-server/pkg/auth"
 	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/storage/storagetest"
 	"passwordless-mail-server/pkg/util"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -30,7 +33,7 @@ func TestStore_GetUsedUUID(t *testing.T) {
 	var store auth.UuidStore
 
 	beforeEach := func() {
-		store = auth.NewUUIDStore(testDatabase.DB)
+		store = newUUIDStore()
 	}
 
 	afterEach := func() {
@@ -94,7 +97,7 @@ func TestStore_InsertUsedUUID(t *testing.T) {
 
 	beforeEach := func() {
 		testDatabase, err = util.NewTestDatabase()
-		store = auth.NewUUIDStore(testDatabase.DB)
+		store = newUUIDStore()
 	}
 
 	afterEach := func() {
@@ -109,9 +112,10 @@ func TestStore_InsertUsedUUID(t *testing.T) {
 		beforeEach()
 		defer afterEach()
 		uuid := uuid.New()
+		expiresAt := time.Now().Add(auth.RequestTimeout).UTC().Truncate(time.Second)
 
 		// Act
-		insertErr := store.InsertUsedUUID(uuid)
+		insertErr := store.InsertUsedUUID(uuid, expiresAt)
 
 		// Assert
 		usedUUIDs := retrieveUsedUUIDs(testDatabase.DB)
@@ -119,6 +123,7 @@ func TestStore_InsertUsedUUID(t *testing.T) {
 		assert.Equal(t, nil, insertErr)
 		assert.Equal(t, 1, len(usedUUIDs))
 		assert.Equal(t, uuid, usedUUIDs[0].UUID)
+		assert.True(t, expiresAt.Equal(usedUUIDs[0].ExpiresAt))
 	})
 
 	t.Run("should return error when uuid is already stored", func(t *testing.T) {
@@ -129,16 +134,48 @@ func TestStore_InsertUsedUUID(t *testing.T) {
 		insertUsedUUIDs([]model.UsedUUIDEntity{{UUID: uuid}}, testDatabase.DB)
 
 		// Act
-		insertErr := store.InsertUsedUUID(uuid)
+		insertErr := store.InsertUsedUUID(uuid, time.Now().Add(auth.RequestTimeout))
 
 		// Assert
 		usedUUIDs := retrieveUsedUUIDs(testDatabase.DB)
-		expectErr := fmt.Errorf("uuid %s is already used", uuid)
-		assert.Equal(t, expectErr, insertErr)
+		assert.ErrorIs(t, insertErr, auth.ErrUUIDUsed)
 		assert.Equal(t, 1, len(usedUUIDs))
 		assert.Equal(t, uuid, usedUUIDs[0].UUID)
 	})
 
+	t.Run("should accept only one of concurrent inserts of the same uuid", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		uuid := uuid.New()
+		const concurrency = 10
+		insertErrs := make(chan error, concurrency)
+		var wg sync.WaitGroup
+
+		// Act
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				insertErrs <- store.InsertUsedUUID(uuid, time.Now().Add(auth.RequestTimeout))
+			}()
+		}
+		wg.Wait()
+		close(insertErrs)
+
+		// Assert
+		accepted := 0
+		for insertErr := range insertErrs {
+			if insertErr == nil {
+				accepted++
+				continue
+			}
+			assert.ErrorIs(t, insertErr, auth.ErrUUIDUsed)
+		}
+		assert.Equal(t, 1, accepted)
+		assert.Equal(t, 1, len(retrieveUsedUUIDs(testDatabase.DB)))
+	})
+
 	t.Run("should return error when error occurred", func(t *testing.T) {
 		// Arrange
 		beforeEach()
@@ -148,7 +185,7 @@ func TestStore_InsertUsedUUID(t *testing.T) {
 		// Act
 		testDatabase.DropTestTable() // drop table to force error
 		defer testDatabase.CreateTestTable()
-		insertErr := store.InsertUsedUUID(uuid)
+		insertErr := store.InsertUsedUUID(uuid, time.Now().Add(auth.RequestTimeout))
 
 		// Assert
 		usedUUIDs := retrieveUsedUUIDs(testDatabase.DB)
@@ -157,6 +194,70 @@ func TestStore_InsertUsedUUID(t *testing.T) {
 	})
 }
 
+func TestStore_DeleteExpiredUUIDs(t *testing.T) {
+	var store auth.UuidStore
+
+	beforeEach := func() {
+		store = newUUIDStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("used_uuid")
+		fmt.Println("drop table error", err)
+	}
+
+	t.Run("should delete only uuids that expired before the given time", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		expired := uuid.New()
+		fresh := uuid.New()
+		insertErr1 := store.InsertUsedUUID(expired, time.Now().Add(-time.Minute))
+		insertErr2 := store.InsertUsedUUID(fresh, time.Now().Add(auth.RequestTimeout))
+
+		// Act
+		deleted, deleteErr := store.DeleteExpiredUUIDs(time.Now())
+
+		// Assert
+		usedUUIDs := retrieveUsedUUIDs(testDatabase.DB)
+		assert.NoError(t, insertErr1)
+		assert.NoError(t, insertErr2)
+		assert.NoError(t, deleteErr)
+		assert.Equal(t, 1, deleted)
+		assert.Equal(t, 1, len(usedUUIDs))
+		assert.Equal(t, fresh, usedUUIDs[0].UUID)
+	})
+}
+
+func TestStore_Conformance(t *testing.T) {
+	storagetest.TestUuidStore(t, func(t *testing.T) auth.UuidStore {
+		err := testDatabase.DeleteItemsFromTable("used_uuid")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return newUUIDStore()
+	})
+}
+
+// stores of the test database backend, postgres or sqlite
+// by the scheme of DATABASE_CONNECTION_STRING
+func newUUIDStore() auth.UuidStore {
+	if testDatabase.Driver == util.SQLiteDriver {
+		return auth.NewSQLiteUUIDStore(testDatabase.DB)
+	}
+
+	return auth.NewUUIDStore(testDatabase.DB)
+}
+
+func newSessionStore() auth.SessionStore {
+	if testDatabase.Driver == util.SQLiteDriver {
+		return auth.NewSQLiteSessionStore(testDatabase.DB)
+	}
+
+	return auth.NewSessionStore(testDatabase.DB)
+}
+
 func mockUsedUUID(amount int) []model.UsedUUIDEntity {
 	if amount < 1 || amount > 100 {
 		log.Fatal("amount should be between 1 and 99, heehee! ow!")
@@ -189,7 +290,7 @@ func insertUsedUUIDs(uuids []model.UsedUUIDEntity, db *sql.DB) error {
 }
 
 func retrieveUsedUUIDs(db *sql.DB) []model.UsedUUIDEntity {
-	queryScript := "SELECT * FROM used_uuid"
+	queryScript := "SELECT uuid, created_at, expires_at FROM used_uuid"
 	var uuids []model.UsedUUIDEntity
 	rows, err := db.Query(queryScript)
 	if err != nil {
@@ -198,7 +299,7 @@ func retrieveUsedUUIDs(db *sql.DB) []model.UsedUUIDEntity {
 	defer rows.Close()
 	for rows.Next() {
 		var uuid model.UsedUUIDEntity
-		err := rows.Scan(&uuid.UUID, &uuid.CreatedAt)
+		err := rows.Scan(&uuid.UUID, &uuid.CreatedAt, &uuid.ExpiresAt)
 		if err != nil {
 			return []model.UsedUUIDEntity{}
 		}
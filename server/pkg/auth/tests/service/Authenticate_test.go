@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/auth"
+	authmocks "passwordless-mail-server/pkg/auth/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuthenticate(t *testing.T) {
+
+	const (
+		TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+		TestNonce      = "test nonce"
+	)
+
+	var (
+		testAccount *account.Account
+		err         error
+		token       string
+		session     model.SessionEntity
+
+		mockSessionStore authmocks.SessionStore
+		authService      auth.AuthService
+	)
+
+	// log in with read scope and keep the stored session
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockSessionStore = authmocks.SessionStore{}
+		authService = auth.NewService(&mockSessionStore, TestOrigin, time.Hour)
+
+		mockSessionStore.On("ConsumeChallenge", TestNonce).Return(&model.ChallengeEntity{Nonce: TestNonce, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+		mockSessionStore.On("InsertSession", mock.Anything).Run(func(args mock.Arguments) {
+			session = args.Get(0).(model.SessionEntity)
+		}).Return(nil)
+		message, err := request.NewLogin(TestOrigin, TestNonce, []request.Scope{request.ScopeRead})
+		assert.NoError(t, err)
+		signature, err := testAccount.Sign(message)
+		assert.NoError(t, err)
+		result, err := authService.Login(model.RequestBody{Data: string(message), Signature: signature}, testAccount.PublicKey)
+		assert.NoError(t, err)
+		token = result.Token
+	}
+
+	t.Run("should return address of a valid token with the scope", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockSessionStore.On("GetSession", session.TokenHash).Return(&session, nil)
+
+		// Act
+		address, authErr := authService.Authenticate(token, request.ScopeRead)
+
+		// Assert
+		util.AssertNoAnyError(t, authErr)
+		assert.Equal(t, testAccount.GetAddress(), address)
+	})
+
+	t.Run("should return insufficient scope when token does not have the scope", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockSessionStore.On("GetSession", session.TokenHash).Return(&session, nil)
+
+		// Act
+		_, authErr := authService.Authenticate(token, request.ScopeWrite)
+
+		// Assert
+		assert.EqualError(t, authErr, "insufficient scope")
+	})
+
+	t.Run("should return invalid token when token is unknown, expired or revoked", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		expired := session
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+		revoked := session
+		revokedAt := time.Now()
+		revoked.RevokedAt = &revokedAt
+		mockSessionStore.On("GetSession", mock.Anything).Return(nil, nil).Once()
+		mockSessionStore.On("GetSession", session.TokenHash).Return(&expired, nil).Once()
+		mockSessionStore.On("GetSession", session.TokenHash).Return(&revoked, nil).Once()
+
+		// Act
+		_, unknownErr := authService.Authenticate("unknown token", request.ScopeRead)
+		_, expiredErr := authService.Authenticate(token, request.ScopeRead)
+		_, revokedErr := authService.Authenticate(token, request.ScopeRead)
+
+		// Assert
+		assert.EqualError(t, unknownErr, "invalid token")
+		assert.EqualError(t, expiredErr, "invalid token")
+		assert.EqualError(t, revokedErr, "invalid token")
+	})
+}
@@ -0,0 +1,167 @@
+package service_test
+
+import (
+	"encoding/json"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/auth"
+	authmocks "passwordless-mail-server/pkg/auth/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLogin(t *testing.T) {
+
+	const (
+		TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+		TestNonce      = "test nonce"
+		TestSessionTTL = 15 * time.Minute
+	)
+
+	var (
+		testAccount *account.Account
+		err         error
+
+		mockSessionStore authmocks.SessionStore
+		authService      auth.AuthService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockSessionStore = authmocks.SessionStore{}
+		authService = auth.NewService(&mockSessionStore, TestOrigin, TestSessionTTL)
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	validChallenge := &model.ChallengeEntity{Nonce: TestNonce, ExpiresAt: time.Now().Add(time.Minute)}
+
+	t.Run("should create challenge and clean up expired ones", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockSessionStore.On("DeleteExpiredChallenges", mock.Anything).Return(0, nil)
+		mockSessionStore.On("InsertChallenge", mock.Anything).Return(nil)
+
+		// Act
+		first, firstErr := authService.Challenge()
+		second, secondErr := authService.Challenge()
+
+		// Assert
+		util.AssertNoAnyError(t, firstErr, secondErr)
+		assert.NotEmpty(t, first.Nonce)
+		assert.NotEqual(t, first.Nonce, second.Nonce)
+		mockSessionStore.AssertCalled(t, "InsertChallenge", mock.MatchedBy(func(challenge model.ChallengeEntity) bool {
+			return challenge.Nonce == first.Nonce && challenge.ExpiresAt.After(time.Now())
+		}))
+	})
+
+	t.Run("should return token of the signer with the signed scopes and store only its hash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockSessionStore.On("ConsumeChallenge", TestNonce).Return(validChallenge, nil)
+		mockSessionStore.On("InsertSession", mock.Anything).Return(nil)
+		message, newMsgErr := request.NewLogin(TestOrigin, TestNonce, []request.Scope{request.ScopeRead})
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		result, loginErr := authService.Login(requestBody, testAccount.PublicKey)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, loginErr)
+		assert.NotEmpty(t, result.Token)
+		assert.Equal(t, testAccount.GetAddress(), result.Address)
+		assert.Equal(t, []string{"read"}, result.Scopes)
+		mockSessionStore.AssertCalled(t, "InsertSession", mock.MatchedBy(func(session model.SessionEntity) bool {
+			return session.TokenHash != "" &&
+				session.TokenHash != result.Token &&
+				session.Address == testAccount.GetAddress() &&
+				session.ExpiresAt.After(time.Now().Add(TestSessionTTL-time.Minute))
+		}))
+	})
+
+	t.Run("should return invalid challenge when nonce is unknown, used or expired", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		expired := &model.ChallengeEntity{Nonce: TestNonce, ExpiresAt: time.Now().Add(-time.Second)}
+		mockSessionStore.On("ConsumeChallenge", TestNonce).Return(nil, nil).Once()
+		mockSessionStore.On("ConsumeChallenge", TestNonce).Return(expired, nil).Once()
+		message1, newMsgErr1 := request.NewLogin(TestOrigin, TestNonce, []request.Scope{request.ScopeRead})
+		requestBody1, signErr1 := signedRequest(message1)
+		message2, newMsgErr2 := request.NewLogin(TestOrigin, TestNonce, []request.Scope{request.ScopeRead})
+		requestBody2, signErr2 := signedRequest(message2)
+
+		// Act
+		_, unknownErr := authService.Login(requestBody1, testAccount.PublicKey)
+		_, expiredErr := authService.Login(requestBody2, testAccount.PublicKey)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr1, signErr1, newMsgErr2, signErr2)
+		assert.EqualError(t, unknownErr, "invalid challenge")
+		assert.EqualError(t, expiredErr, "invalid challenge")
+		mockSessionStore.AssertNotCalled(t, "InsertSession", mock.Anything)
+	})
+
+	t.Run("should return validation failed when signature is not of the public key", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		other, connectErr := account.ConnectAccount("923cebb3d8809d3caf09faa74ae2a39c23824a6fe75c44cab2a73dc6a0f3b606")
+		message, newMsgErr := request.NewLogin(TestOrigin, TestNonce, []request.Scope{request.ScopeRead})
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, loginErr := authService.Login(requestBody, other.PublicKey)
+
+		// Assert
+		util.AssertNoAnyError(t, connectErr, newMsgErr, signErr)
+		assert.EqualError(t, loginErr, "validation failed")
+		mockSessionStore.AssertNotCalled(t, "ConsumeChallenge", mock.Anything)
+	})
+
+	t.Run("should return bad request when scope is unknown", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockSessionStore.On("ConsumeChallenge", TestNonce).Return(validChallenge, nil)
+		message, newMsgErr := request.NewLogin(TestOrigin, TestNonce, []request.Scope{request.ScopeRead})
+		var login request.LoginRequest
+		unmarshalErr := json.Unmarshal(message, &login)
+		login.Scopes = []request.Scope{"admin"}
+		message, marshalErr := json.Marshal(login)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, loginErr := authService.Login(requestBody, testAccount.PublicKey)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, unmarshalErr, marshalErr, signErr)
+		assert.EqualError(t, loginErr, "bad request")
+		mockSessionStore.AssertNotCalled(t, "InsertSession", mock.Anything)
+	})
+
+	t.Run("should return action mismatch when revoke sessions request is used to login", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewRevokeSessions(TestOrigin, TestNonce)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, loginErr := authService.Login(requestBody, testAccount.PublicKey)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, loginErr, "action mismatch")
+		mockSessionStore.AssertNotCalled(t, "ConsumeChallenge", mock.Anything)
+	})
+}
@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"database/sql"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/auth"
+	authmocks "passwordless-mail-server/pkg/auth/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLogout(t *testing.T) {
+
+	const (
+		TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+		TestNonce      = "test nonce"
+	)
+
+	var (
+		testAccount *account.Account
+		err         error
+
+		mockSessionStore authmocks.SessionStore
+		authService      auth.AuthService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockSessionStore = authmocks.SessionStore{}
+		authService = auth.NewService(&mockSessionStore, TestOrigin, time.Hour)
+	}
+
+	t.Run("should revoke session of the token", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockSessionStore.On("RevokeSession", mock.Anything).Return(nil)
+
+		// Act
+		result, logoutErr := authService.Logout("test token")
+
+		// Assert
+		util.AssertNoAnyError(t, logoutErr)
+		assert.Equal(t, 1, result.Revoked)
+		mockSessionStore.AssertCalled(t, "RevokeSession", mock.MatchedBy(func(tokenHash string) bool {
+			return tokenHash != "test token" && len(tokenHash) == 64
+		}))
+	})
+
+	t.Run("should return invalid token when there is no session to revoke", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockSessionStore.On("RevokeSession", mock.Anything).Return(sql.ErrNoRows)
+
+		// Act
+		_, logoutErr := authService.Logout("test token")
+
+		// Assert
+		assert.EqualError(t, logoutErr, "invalid token")
+	})
+
+	t.Run("should revoke every session of the signer", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockSessionStore.On("ConsumeChallenge", TestNonce).Return(&model.ChallengeEntity{Nonce: TestNonce, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+		mockSessionStore.On("RevokeSessions", testAccount.GetAddress()).Return(3, nil)
+		message, newMsgErr := request.NewRevokeSessions(TestOrigin, TestNonce)
+		signature, signErr := testAccount.Sign(message)
+
+		// Act
+		result, revokeErr := authService.RevokeSessions(model.RequestBody{Data: string(message), Signature: signature}, testAccount.PublicKey)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, revokeErr)
+		assert.Equal(t, 3, result.Revoked)
+	})
+}
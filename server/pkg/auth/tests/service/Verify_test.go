@@ -0,0 +1,203 @@
+package service_test
+
+import (
+	"encoding/json"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/auth"
+	authmocks "passwordless-mail-server/pkg/auth/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVerify(t *testing.T) {
+
+	const TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+
+	var (
+		testAccount *account.Account
+		err         error
+
+		mockUUIDStore authmocks.UuidStore
+		verifier      auth.RequestVerifier
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockUUIDStore = authmocks.UuidStore{}
+		verifier = auth.NewVerifier(&mockUUIDStore, TestOrigin)
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	t.Run("should accept signed request and consume its uuid", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockUUIDStore.On("InsertUsedUUID", mock.Anything, mock.Anything).Return(nil)
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		requestBody, signErr := signedRequest(message)
+		var header request.Header
+		unmarshalErr := json.Unmarshal(message, &header)
+		timestamp, parseErr := time.Parse(time.RFC3339, header.Timestamp)
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, testAccount.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, unmarshalErr, parseErr, verifyErr)
+		mockUUIDStore.AssertCalled(t, "InsertUsedUUID", header.ID, timestamp.Add(auth.RequestTimeout))
+	})
+
+	t.Run("should return uuid is already used when request is replayed", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockUUIDStore.On("InsertUsedUUID", mock.Anything, mock.Anything).Return(auth.ErrUUIDUsed)
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, testAccount.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.ErrorIs(t, verifyErr, auth.ErrUUIDUsed)
+	})
+
+	t.Run("should return message timeout when request is older than the timeout", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		header := request.Header{
+			Version:   request.ProtocolVersion,
+			Action:    request.GetInbox,
+			Origin:    TestOrigin,
+			ID:        uuid.New(),
+			Timestamp: time.Now().Add(-auth.RequestTimeout - time.Minute).Format(time.RFC3339),
+		}
+		message, marshalErr := json.Marshal(header)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, testAccount.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, marshalErr, signErr)
+		assert.ErrorIs(t, verifyErr, auth.ErrMessageTimeout)
+		mockUUIDStore.AssertNotCalled(t, "InsertUsedUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return validation failed when signature is not of the public key", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		other, connectErr := account.ConnectAccount("923cebb3d8809d3caf09faa74ae2a39c23824a6fe75c44cab2a73dc6a0f3b606")
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, other.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, connectErr, newMsgErr, signErr)
+		assert.ErrorIs(t, verifyErr, auth.ErrValidationFailed)
+		mockUUIDStore.AssertNotCalled(t, "InsertUsedUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return action mismatch when request is signed for another action", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetEmail(TestOrigin, uuid.New())
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, testAccount.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.ErrorIs(t, verifyErr, auth.ErrActionMismatch)
+		mockUUIDStore.AssertNotCalled(t, "InsertUsedUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return origin mismatch when request is signed for another server", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox("https://other.server")
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, testAccount.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.ErrorIs(t, verifyErr, auth.ErrOriginMismatch)
+		mockUUIDStore.AssertNotCalled(t, "InsertUsedUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return unsupported protocol version when request is from old client", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		oldMessage := struct {
+			ID        uuid.UUID `json:"id"`
+			Timestamp string    `json:"timestamp"`
+		}{
+			ID:        uuid.New(),
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+		message, marshalErr := json.Marshal(oldMessage)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, testAccount.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, marshalErr, signErr)
+		assert.ErrorIs(t, verifyErr, auth.ErrUnsupportedVersion)
+		mockUUIDStore.AssertNotCalled(t, "InsertUsedUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should skip signature and used uuid check of a session request", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		requestBody := model.RequestBody{
+			Data:          string(message),
+			Authenticated: true,
+		}
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, testAccount.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, verifyErr)
+		mockUUIDStore.AssertNotCalled(t, "InsertUsedUUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should still check action of a session request", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetEmail(TestOrigin, uuid.New())
+		requestBody := model.RequestBody{
+			Data:          string(message),
+			Authenticated: true,
+		}
+
+		// Act
+		verifyErr := verifier.Verify(requestBody, testAccount.PublicKey, request.GetInbox)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr)
+		assert.ErrorIs(t, verifyErr, auth.ErrActionMismatch)
+	})
+}
@@ -0,0 +1,4 @@
+package service_test
+
+// origin the test service is configured with and test requests are signed for
+const TestOrigin = "http://localhost:8080"
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"time"
+)
+
+// how long a signed request is accepted after its timestamp
+const RequestTimeout = 3 * time.Minute
+
+type RequestVerifier interface {
+	Verify(request model.RequestBody, publicKey *ecdsa.PublicKey, action request.ActionName) error
+}
+
+type Verifier struct {
+	uuidStore UuidStore
+	origin    string
+}
+
+// origin is the base url clients sign requests for, e.g. http://localhost:8080
+func NewVerifier(uuidStore UuidStore, origin string) RequestVerifier {
+	return &Verifier{
+		uuidStore: uuidStore,
+		origin:    origin,
+	}
+}
+
+// run every check of a signed request for action and consume its uuid,
+// a request of a session only needs the version, action and origin
+func (v *Verifier) Verify(payload model.RequestBody, publicKey *ecdsa.PublicKey, action request.ActionName) error {
+	err := checkProtocolVersion(payload.Data)
+	if err != nil {
+		return err
+	}
+
+	// a session token already proved who the user is
+	if !payload.Authenticated {
+		isVerify := account.Verify(
+			publicKey,
+			[]byte(payload.Data),
+			[]byte(payload.Signature),
+		)
+		if !isVerify {
+			return ErrValidationFailed
+		}
+	}
+
+	var header request.Header
+	err = json.Unmarshal([]byte(payload.Data), &header)
+	if err != nil {
+		return ErrBadRequest
+	}
+
+	err = checkTarget(header.Action, action, header.Origin, v.origin)
+	if err != nil {
+		return err
+	}
+
+	if payload.Authenticated {
+		return nil
+	}
+
+	return v.checkReplay(header)
+}
+
+// signed requests are only valid for a short time and only once
+func (v *Verifier) checkReplay(header request.Header) error {
+	timestamp, err := time.Parse(time.RFC3339, header.Timestamp)
+	if err != nil {
+		return ErrBadRequest
+	}
+	isTimeout := time.Since(timestamp) > RequestTimeout
+	if isTimeout {
+		return ErrMessageTimeout
+	}
+
+	// the uuid is kept as long as its request is accepted
+	err = v.uuidStore.InsertUsedUUID(header.ID, timestamp.Add(RequestTimeout))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// reject requests from clients that sign another protocol version,
+// data that is not json is left to signature verification
+func checkProtocolVersion(data string) error {
+	var message struct {
+		Version int `json:"version"`
+	}
+	err := json.Unmarshal([]byte(data), &message)
+	if err != nil {
+		return nil
+	}
+	if message.Version != request.ProtocolVersion {
+		return ErrUnsupportedVersion
+	}
+
+	return nil
+}
+
+// signed action and origin must match the endpoint that received the request
+func checkTarget(action request.ActionName, expectedAction request.ActionName, origin string, expectedOrigin string) error {
+	if action != expectedAction {
+		return ErrActionMismatch
+	}
+	if origin != expectedOrigin {
+		return ErrOriginMismatch
+	}
+
+	return nil
+}
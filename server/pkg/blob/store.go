@@ -0,0 +1,124 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// content addressed storage, a blob is keyed by the hex SHA-256 of its content
+type BlobStore interface {
+	Put(content []byte) (string, error)
+	Open(hash string) (io.ReadCloser, error)
+	Size(hash string) (int64, error)
+}
+
+// blobs are files in dir named by hash, sharded by the first two hex characters
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (BlobStore, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		dir: dir,
+	}, nil
+}
+
+// storing the same content again is a no-op
+func (s *FileStore) Put(content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	path := s.path(hash)
+
+	_, err := os.Stat(path)
+	if err == nil {
+		return hash, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first so a blob is never read half written
+	file, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+func (s *FileStore) Open(hash string) (io.ReadCloser, error) {
+	if !validHash(hash) {
+		return nil, ErrNotFound
+	}
+
+	file, err := os.Open(s.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (s *FileStore) Size(hash string) (int64, error) {
+	if !validHash(hash) {
+		return 0, ErrNotFound
+	}
+
+	info, err := os.Stat(s.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+func (s *FileStore) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// hash is used as a file name, so only lower case hex SHA-256 is accepted
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
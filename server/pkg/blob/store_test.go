@@ -0,0 +1,74 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	var store BlobStore
+
+	beforeEach := func() {
+		var err error
+		store, err = NewFileStore(t.TempDir())
+		assert.NoError(t, err)
+	}
+
+	t.Run("should store content by its SHA-256 and read it back", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		content := []byte("encrypted attachment")
+		sum := sha256.Sum256(content)
+
+		// Act
+		hash, putErr := store.Put(content)
+		size, sizeErr := store.Size(hash)
+		file, openErr := store.Open(hash)
+		stored, readErr := io.ReadAll(file)
+		file.Close()
+
+		// Assert
+		assert.NoError(t, putErr)
+		assert.NoError(t, sizeErr)
+		assert.NoError(t, openErr)
+		assert.NoError(t, readErr)
+		assert.Equal(t, hex.EncodeToString(sum[:]), hash)
+		assert.Equal(t, int64(len(content)), size)
+		assert.Equal(t, content, stored)
+	})
+
+	t.Run("should keep one blob when the same content is stored twice", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		content := []byte("same content")
+
+		// Act
+		hash1, putErr1 := store.Put(content)
+		hash2, putErr2 := store.Put(content)
+
+		// Assert
+		assert.NoError(t, putErr1)
+		assert.NoError(t, putErr2)
+		assert.Equal(t, hash1, hash2)
+	})
+
+	t.Run("should return not found for unknown or malformed hash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		sum := sha256.Sum256([]byte("never stored"))
+
+		// Act
+		_, unknownErr := store.Open(hex.EncodeToString(sum[:]))
+		_, traversalErr := store.Open("../../etc/passwd")
+		_, sizeErr := store.Size("not a hash")
+
+		// Assert
+		assert.Equal(t, ErrNotFound, unknownErr)
+		assert.Equal(t, ErrNotFound, traversalErr)
+		assert.Equal(t, ErrNotFound, sizeErr)
+	})
+}
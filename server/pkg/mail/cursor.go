@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"encoding/base64"
+	"fmt"
+	"passwordless-mail-server/pkg/model"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SortOrder string
+
+const (
+	NewestFirst SortOrder = "newest"
+	OldestFirst SortOrder = "oldest"
+)
+
+// position of a mail in the sent_at ordered listing,
+// id breaks the tie between mails sent at the same time
+type Cursor struct {
+	SentAt time.Time
+	ID     uuid.UUID
+}
+
+// opaque to clients, they only pass it back as before or after
+func (c Cursor) Encode() string {
+	raw := c.SentAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	sentAtString, idString, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	sentAt, err := time.Parse(time.RFC3339Nano, sentAtString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	id, err := uuid.Parse(idString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &Cursor{
+		SentAt: sentAt.UTC(),
+		ID:     id,
+	}, nil
+}
+
+func cursorOf(mail model.MailEntity) (Cursor, error) {
+	sentAt, err := time.Parse(time.RFC3339Nano, mail.SentAt)
+	if err != nil {
+		return Cursor{}, err
+	}
+
+	return Cursor{
+		SentAt: sentAt.UTC(),
+		ID:     mail.ID,
+	}, nil
+}
@@ -0,0 +1,11 @@
+package mail
+
+import "errors"
+
+// errors of the mail service, the api maps each of them to a status
+// code and an error code of the response
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrMailNotFound       = errors.New("mail not found")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"log"
+	"time"
+)
+
+// purge expired trash every interval until stop is called
+func StartTrashPurge(service MailService, retention time.Duration, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				purged, err := service.PurgeExpiredTrash(retention)
+				if err != nil {
+					log.Printf("failed to purge expired trash: %v\n", err)
+					continue
+				}
+				if purged > 0 {
+					log.Printf("purged %d expired mails from trash\n", purged)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,449 @@
+package mail
+
+import (
+	"bytes"
+	"database/sql"
+	"passwordless-mail-server/pkg/model"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MailStore kept in the process, mails are lost when it stops.
+// it behaves like Store, e.g. missing mails are sql.ErrNoRows
+type MemoryStore struct {
+	mu    sync.RWMutex
+	mails map[uuid.UUID]model.MailEntity
+	// sent_at has the precision of a postgres timestamp
+	now func() time.Time
+}
+
+func NewMemoryStore() MailStore {
+	return &MemoryStore{
+		mails: map[uuid.UUID]model.MailEntity{},
+		now: func() time.Time {
+			return time.Now().UTC().Truncate(time.Microsecond)
+		},
+	}
+}
+
+func (s *MemoryStore) GetInbox(query StoreGetInboxQuery) ([]model.MailEntity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	inbox := s.filter(func(mail model.MailEntity) bool {
+		if mail.Recipient != query.Recipient || mail.DeletedAt != nil {
+			return false
+		}
+		if query.Before != nil {
+			return compareCursor(mail, *query.Before) < 0
+		}
+		if query.After != nil {
+			return compareCursor(mail, *query.After) > 0
+		}
+		return true
+	})
+
+	// read from the cursor outward, then flip back to the requested order
+	oldestFirst := query.Order == OldestFirst
+	reverse := false
+	if query.Before != nil {
+		reverse = oldestFirst
+		oldestFirst = false
+	} else if query.After != nil {
+		reverse = !oldestFirst
+		oldestFirst = true
+	}
+	sortBySentAt(inbox, oldestFirst)
+	inbox = page(inbox, query.Offset, query.Limit)
+	if reverse {
+		for i, j := 0, len(inbox)-1; i < j; i, j = i+1, j-1 {
+			inbox[i], inbox[j] = inbox[j], inbox[i]
+		}
+	}
+
+	return inbox, nil
+}
+
+func (s *MemoryStore) GetTotalMailsReceived(user string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.filter(func(mail model.MailEntity) bool {
+		return mail.Recipient == user && mail.DeletedAt == nil
+	})), nil
+}
+
+func (s *MemoryStore) GetTotalUnread(recipient string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.filter(func(mail model.MailEntity) bool {
+		return mail.Recipient == recipient && mail.DeletedAt == nil && mail.ReadAt == nil
+	})), nil
+}
+
+// see Store.GetSent
+func (s *MemoryStore) GetSent(query StoreGetSentQuery) ([]model.MailEntity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sent := onePerMessage(s.filter(func(mail model.MailEntity) bool {
+		return mail.Sender == query.Sender
+	}), "")
+	sortBySentAt(sent, false)
+
+	return page(sent, query.Offset, query.Limit), nil
+}
+
+func (s *MemoryStore) GetTotalMailsSent(sender string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(onePerMessage(s.filter(func(mail model.MailEntity) bool {
+		return mail.Sender == sender
+	}), "")), nil
+}
+
+// see Store.SearchMail
+func (s *MemoryStore) SearchMail(query StoreSearchMailQuery) (StoreSearchMailResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mails := s.filter(func(mail model.MailEntity) bool {
+		if mail.Recipient != query.Recipient || mail.DeletedAt != nil {
+			return false
+		}
+		if query.Sender != "" && mail.Sender != query.Sender {
+			return false
+		}
+		sentAt := parseTimestamp(mail.SentAt)
+		if query.Since != nil && sentAt.Before(*query.Since) {
+			return false
+		}
+		if query.Until != nil && !sentAt.Before(*query.Until) {
+			return false
+		}
+		return true
+	})
+
+	result := StoreSearchMailResult{Total: len(mails)}
+	for _, mail := range mails {
+		if mail.ReadAt == nil {
+			result.Unread++
+		}
+	}
+	sortBySentAt(mails, false)
+	result.Mails = page(mails, query.Offset, query.Limit)
+
+	return result, nil
+}
+
+func (s *MemoryStore) GetMail(id uuid.UUID, user string) (*model.MailEntity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mail, ok := s.mails[id]
+	if !ok || (mail.Recipient != user && mail.Sender != user) {
+		return nil, sql.ErrNoRows
+	}
+	mail = cloneMail(mail)
+
+	return &mail, nil
+}
+
+// see Store.GetThread
+func (s *MemoryStore) GetThread(threadID uuid.UUID, user string) ([]model.MailEntity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	thread := onePerMessage(s.filter(func(mail model.MailEntity) bool {
+		if mail.ThreadID != threadID {
+			return false
+		}
+		return (mail.Recipient == user && mail.DeletedAt == nil) || mail.Sender == user
+	}), user)
+	sortBySentAt(thread, true)
+
+	return thread, nil
+}
+
+// see Store.InsertMail, every delivery is inserted or none
+func (s *MemoryStore) InsertMail(mail model.OutgoingMail) ([]model.MailEntity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recipients := map[string]bool{}
+	for _, delivery := range mail.Deliveries {
+		if recipients[delivery.Recipient] {
+			return nil, ErrMessageConflict
+		}
+		recipients[delivery.Recipient] = true
+	}
+	for _, stored := range s.mails {
+		if stored.MessageID != mail.MessageID {
+			continue
+		}
+		if stored.Sender != mail.From || recipients[stored.Recipient] {
+			return nil, ErrMessageConflict
+		}
+	}
+
+	threadId := mail.ThreadID
+	if threadId == uuid.Nil {
+		threadId = mail.MessageID
+	}
+
+	sentAt := s.now().Format(time.RFC3339Nano)
+	var inserted []model.MailEntity
+	for _, delivery := range mail.Deliveries {
+		entity := model.MailEntity{
+			ID:             uuid.New(),
+			MessageID:      mail.MessageID,
+			Recipient:      delivery.Recipient,
+			RecipientType:  delivery.Type,
+			ToRecipients:   append([]string{}, mail.To...),
+			CcRecipients:   append([]string{}, mail.Cc...),
+			Sender:         mail.From,
+			MailSubject:    delivery.Subject,
+			Body:           delivery.Body,
+			EphemeralKey:   delivery.EphemeralKey,
+			SignedData:     mail.SignedData,
+			Signature:      mail.Signature,
+			InReplyTo:      mail.InReplyTo,
+			ThreadID:       threadId,
+			Attachments:    append(model.Attachments{}, mail.Attachments...),
+			AttachmentKeys: delivery.AttachmentKeys,
+		}
+
+		stored := cloneMail(entity)
+		stored.SentAt = sentAt
+		s.mails[entity.ID] = stored
+		inserted = append(inserted, entity)
+	}
+
+	return inserted, nil
+}
+
+// return amount of mails that were unread before
+func (s *MemoryStore) MarkRead(ids []uuid.UUID, recipient string) (int, error) {
+	readAt := s.now().Format(time.RFC3339Nano)
+
+	return s.updateMails(ids, recipient, func(mail *model.MailEntity) bool {
+		if mail.ReadAt != nil {
+			return false
+		}
+		mail.ReadAt = &readAt
+		return true
+	}), nil
+}
+
+// return amount of mails that were read before
+func (s *MemoryStore) MarkUnread(ids []uuid.UUID, recipient string) (int, error) {
+	return s.updateMails(ids, recipient, func(mail *model.MailEntity) bool {
+		if mail.ReadAt == nil {
+			return false
+		}
+		mail.ReadAt = nil
+		return true
+	}), nil
+}
+
+func (s *MemoryStore) TrashMail(id uuid.UUID, recipient string) error {
+	deletedAt := s.now().Format(time.RFC3339Nano)
+	updated := s.updateMails([]uuid.UUID{id}, recipient, func(mail *model.MailEntity) bool {
+		if mail.DeletedAt != nil {
+			return false
+		}
+		mail.DeletedAt = &deletedAt
+		return true
+	})
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) RestoreMail(id uuid.UUID, recipient string) error {
+	updated := s.updateMails([]uuid.UUID{id}, recipient, func(mail *model.MailEntity) bool {
+		if mail.DeletedAt == nil {
+			return false
+		}
+		mail.DeletedAt = nil
+		return true
+	})
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// permanently delete every trashed mail of the recipient
+func (s *MemoryStore) PurgeTrash(recipient string) (int, error) {
+	return s.deleteMails(func(mail model.MailEntity) bool {
+		return mail.Recipient == recipient && mail.DeletedAt != nil
+	}), nil
+}
+
+// permanently delete mails of every recipient trashed before the given time
+func (s *MemoryStore) PurgeTrashBefore(before time.Time) (int, error) {
+	return s.deleteMails(func(mail model.MailEntity) bool {
+		return mail.DeletedAt != nil && parseTimestamp(*mail.DeletedAt).Before(before)
+	}), nil
+}
+
+// copies of the mails that match, callers hold the lock
+func (s *MemoryStore) filter(match func(mail model.MailEntity) bool) []model.MailEntity {
+	var mails []model.MailEntity
+	for _, mail := range s.mails {
+		if match(mail) {
+			mails = append(mails, cloneMail(mail))
+		}
+	}
+
+	return mails
+}
+
+// update is called with every mail of ids owned by the recipient
+// and returns whether it changed the mail
+func (s *MemoryStore) updateMails(ids []uuid.UUID, recipient string, update func(mail *model.MailEntity) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := 0
+	for _, id := range ids {
+		mail, ok := s.mails[id]
+		if !ok || mail.Recipient != recipient {
+			continue
+		}
+		if update(&mail) {
+			s.mails[id] = mail
+			updated++
+		}
+	}
+
+	return updated
+}
+
+func (s *MemoryStore) deleteMails(match func(mail model.MailEntity) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for id, mail := range s.mails {
+		if match(mail) {
+			delete(s.mails, id)
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// one delivery per message like DISTINCT ON (message_id): the delivery to
+// user when there is one, then a to or cc delivery, then the lowest id
+func onePerMessage(mails []model.MailEntity, user string) []model.MailEntity {
+	rank := func(mail model.MailEntity) int {
+		rank := 0
+		if mail.Recipient != user {
+			rank += 2
+		}
+		if mail.RecipientType == model.RecipientBcc {
+			rank++
+		}
+		return rank
+	}
+
+	chosen := map[uuid.UUID]model.MailEntity{}
+	for _, mail := range mails {
+		key := mail.MessageID
+		if key == uuid.Nil {
+			key = mail.ID
+		}
+		current, ok := chosen[key]
+		if !ok || rank(mail) < rank(current) || (rank(mail) == rank(current) && compareID(mail.ID, current.ID) < 0) {
+			chosen[key] = mail
+		}
+	}
+
+	unique := make([]model.MailEntity, 0, len(chosen))
+	for _, mail := range chosen {
+		unique = append(unique, mail)
+	}
+
+	return unique
+}
+
+// order by sent_at then id, like ORDER BY sent_at, id
+func sortBySentAt(mails []model.MailEntity, oldestFirst bool) {
+	sort.Slice(mails, func(i, j int) bool {
+		compared := compareCursor(mails[i], Cursor{SentAt: parseTimestamp(mails[j].SentAt), ID: mails[j].ID})
+		if oldestFirst {
+			return compared < 0
+		}
+		return compared > 0
+	})
+}
+
+func compareCursor(mail model.MailEntity, cursor Cursor) int {
+	sentAt := parseTimestamp(mail.SentAt)
+	if !sentAt.Equal(cursor.SentAt) {
+		if sentAt.Before(cursor.SentAt) {
+			return -1
+		}
+		return 1
+	}
+
+	return compareID(mail.ID, cursor.ID)
+}
+
+// postgres compares uuids byte by byte
+func compareID(a uuid.UUID, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// LIMIT and OFFSET, see pageBounds
+func page(mails []model.MailEntity, offset int, limit int) []model.MailEntity {
+	offset, limit = pageBounds(offset, limit)
+	if offset >= len(mails) || limit == 0 {
+		return nil
+	}
+	mails = mails[offset:]
+	if limit < len(mails) {
+		mails = mails[:limit]
+	}
+
+	return mails
+}
+
+func parseTimestamp(timestamp string) time.Time {
+	parsed, _ := time.Parse(time.RFC3339Nano, timestamp)
+
+	return parsed
+}
+
+// stored mails never share slices or pointers with callers
+func cloneMail(mail model.MailEntity) model.MailEntity {
+	mail.Signature = append([]byte(nil), mail.Signature...)
+	mail.ToRecipients = append([]string{}, mail.ToRecipients...)
+	mail.CcRecipients = append([]string{}, mail.CcRecipients...)
+	mail.Attachments = append(model.Attachments{}, mail.Attachments...)
+	if mail.DeletedAt != nil {
+		deletedAt := *mail.DeletedAt
+		mail.DeletedAt = &deletedAt
+	}
+	if mail.ReadAt != nil {
+		readAt := *mail.ReadAt
+		mail.ReadAt = &readAt
+	}
+	if mail.InReplyTo != nil {
+		inReplyTo := *mail.InReplyTo
+		mail.InReplyTo = &inReplyTo
+	}
+
+	return mail
+}
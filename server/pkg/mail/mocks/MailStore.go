@@ -9,6 +9,8 @@ import (
 
 	model "passwordless-mail-server/pkg/model"
 
+	time "time"
+
 	uuid "github.com/google/uuid"
 )
 
@@ -77,6 +79,66 @@ func (_m *MailStore) GetMail(id uuid.UUID, user string) (*model.MailEntity, erro
 	return r0, r1
 }
 
+// GetSent provides a mock function with given fields: query
+func (_m *MailStore) GetSent(query mail.StoreGetSentQuery) ([]model.MailEntity, error) {
+	ret := _m.Called(query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSent")
+	}
+
+	var r0 []model.MailEntity
+	var r1 error
+	if rf, ok := ret.Get(0).(func(mail.StoreGetSentQuery) ([]model.MailEntity, error)); ok {
+		return rf(query)
+	}
+	if rf, ok := ret.Get(0).(func(mail.StoreGetSentQuery) []model.MailEntity); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.MailEntity)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(mail.StoreGetSentQuery) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetThread provides a mock function with given fields: threadID, user
+func (_m *MailStore) GetThread(threadID uuid.UUID, user string) ([]model.MailEntity, error) {
+	ret := _m.Called(threadID, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetThread")
+	}
+
+	var r0 []model.MailEntity
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, string) ([]model.MailEntity, error)); ok {
+		return rf(threadID, user)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID, string) []model.MailEntity); ok {
+		r0 = rf(threadID, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.MailEntity)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID, string) error); ok {
+		r1 = rf(threadID, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTotalMailsReceived provides a mock function with given fields: user
 func (_m *MailStore) GetTotalMailsReceived(user string) (int, error) {
 	ret := _m.Called(user)
@@ -105,28 +167,84 @@ func (_m *MailStore) GetTotalMailsReceived(user string) (int, error) {
 	return r0, r1
 }
 
+// GetTotalMailsSent provides a mock function with given fields: sender
+func (_m *MailStore) GetTotalMailsSent(sender string) (int, error) {
+	ret := _m.Called(sender)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTotalMailsSent")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(sender)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(sender)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(sender)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTotalUnread provides a mock function with given fields: recipient
+func (_m *MailStore) GetTotalUnread(recipient string) (int, error) {
+	ret := _m.Called(recipient)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTotalUnread")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(recipient)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(recipient)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(recipient)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // InsertMail provides a mock function with given fields: _a0
-func (_m *MailStore) InsertMail(_a0 model.Mail) (*model.MailEntity, error) {
+func (_m *MailStore) InsertMail(_a0 model.OutgoingMail) ([]model.MailEntity, error) {
 	ret := _m.Called(_a0)
 
 	if len(ret) == 0 {
 		panic("no return value specified for InsertMail")
 	}
 
-	var r0 *model.MailEntity
+	var r0 []model.MailEntity
 	var r1 error
-	if rf, ok := ret.Get(0).(func(model.Mail) (*model.MailEntity, error)); ok {
+	if rf, ok := ret.Get(0).(func(model.OutgoingMail) ([]model.MailEntity, error)); ok {
 		return rf(_a0)
 	}
-	if rf, ok := ret.Get(0).(func(model.Mail) *model.MailEntity); ok {
+	if rf, ok := ret.Get(0).(func(model.OutgoingMail) []model.MailEntity); ok {
 		r0 = rf(_a0)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.MailEntity)
+			r0 = ret.Get(0).([]model.MailEntity)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(model.Mail) error); ok {
+	if rf, ok := ret.Get(1).(func(model.OutgoingMail) error); ok {
 		r1 = rf(_a0)
 	} else {
 		r1 = ret.Error(1)
@@ -135,6 +253,182 @@ func (_m *MailStore) InsertMail(_a0 model.Mail) (*model.MailEntity, error) {
 	return r0, r1
 }
 
+// MarkRead provides a mock function with given fields: ids, recipient
+func (_m *MailStore) MarkRead(ids []uuid.UUID, recipient string) (int, error) {
+	ret := _m.Called(ids, recipient)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkRead")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]uuid.UUID, string) (int, error)); ok {
+		return rf(ids, recipient)
+	}
+	if rf, ok := ret.Get(0).(func([]uuid.UUID, string) int); ok {
+		r0 = rf(ids, recipient)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func([]uuid.UUID, string) error); ok {
+		r1 = rf(ids, recipient)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkUnread provides a mock function with given fields: ids, recipient
+func (_m *MailStore) MarkUnread(ids []uuid.UUID, recipient string) (int, error) {
+	ret := _m.Called(ids, recipient)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkUnread")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]uuid.UUID, string) (int, error)); ok {
+		return rf(ids, recipient)
+	}
+	if rf, ok := ret.Get(0).(func([]uuid.UUID, string) int); ok {
+		r0 = rf(ids, recipient)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func([]uuid.UUID, string) error); ok {
+		r1 = rf(ids, recipient)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeTrash provides a mock function with given fields: recipient
+func (_m *MailStore) PurgeTrash(recipient string) (int, error) {
+	ret := _m.Called(recipient)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeTrash")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(recipient)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(recipient)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(recipient)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeTrashBefore provides a mock function with given fields: before
+func (_m *MailStore) PurgeTrashBefore(before time.Time) (int, error) {
+	ret := _m.Called(before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeTrashBefore")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time) (int, error)); ok {
+		return rf(before)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time) int); ok {
+		r0 = rf(before)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = rf(before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreMail provides a mock function with given fields: id, recipient
+func (_m *MailStore) RestoreMail(id uuid.UUID, recipient string) error {
+	ret := _m.Called(id, recipient)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreMail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, string) error); ok {
+		r0 = rf(id, recipient)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SearchMail provides a mock function with given fields: query
+func (_m *MailStore) SearchMail(query mail.StoreSearchMailQuery) (mail.StoreSearchMailResult, error) {
+	ret := _m.Called(query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchMail")
+	}
+
+	var r0 mail.StoreSearchMailResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(mail.StoreSearchMailQuery) (mail.StoreSearchMailResult, error)); ok {
+		return rf(query)
+	}
+	if rf, ok := ret.Get(0).(func(mail.StoreSearchMailQuery) mail.StoreSearchMailResult); ok {
+		r0 = rf(query)
+	} else {
+		r0 = ret.Get(0).(mail.StoreSearchMailResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(mail.StoreSearchMailQuery) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TrashMail provides a mock function with given fields: id, recipient
+func (_m *MailStore) TrashMail(id uuid.UUID, recipient string) error {
+	ret := _m.Called(id, recipient)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TrashMail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, string) error); ok {
+		r0 = rf(id, recipient)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewMailStore creates a new instance of MailStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMailStore(t interface {
@@ -1,236 +1,670 @@
 package mail
 
 import (
-	"crypto/ecdsa"
+	"database/sql"
 	"encoding/json"
-	"fmt"
+	"io"
 	"passwordless-mail-client/pkg/account"
+	clientmodel "passwordless-mail-client/pkg/model"
 	"passwordless-mail-client/pkg/request"
-	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/blob"
 	"passwordless-mail-server/pkg/model"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// before and after are opaque cursors from a previous inbox response,
+// when one is set page is ignored
 type ServiceGetInboxQuery struct {
 	Recipient string
 	Page      int
 	Limit     int
+	Order     SortOrder
+	Before    string
+	After     string
+}
+
+type ServiceGetSentQuery struct {
+	Sender string
+	Page   int
+	Limit  int
+}
+
+type ServiceSearchMailQuery struct {
+	Recipient string
+	Page      int
+	Limit     int
 }
 
+// requests are verified by the api middleware before they reach the
+// service, it only reads the data signed for the action of the method
 type MailService interface {
-	GetInbox(request model.RequestBody, publicKey *ecdsa.PublicKey, query ServiceGetInboxQuery) (model.InboxResponse, error)
-	GetMail(request model.RequestBody, publicKey *ecdsa.PublicKey, user string) (model.Mail, error)
-	SendMail(request model.RequestBody, publicKey *ecdsa.PublicKey) (model.SendMailResponse, error)
+	GetInbox(request model.RequestBody, query ServiceGetInboxQuery) (model.InboxResponse, error)
+	GetSent(request model.RequestBody, query ServiceGetSentQuery) (model.SentResponse, error)
+	SearchMail(request model.RequestBody, query ServiceSearchMailQuery) (model.InboxResponse, error)
+	GetMail(request model.RequestBody, user string) (model.Mail, error)
+	GetThread(request model.RequestBody, user string) (model.ThreadResponse, error)
+	SendMail(request model.RequestBody, sender string) (model.SendMailResponse, error)
+	DeleteMail(request model.RequestBody, user string) (model.TrashMailResponse, error)
+	RestoreMail(request model.RequestBody, user string) (model.TrashMailResponse, error)
+	MarkRead(request model.RequestBody, user string) (model.MarkMailsResponse, error)
+	MarkUnread(request model.RequestBody, user string) (model.MarkMailsResponse, error)
+	PurgeTrash(request model.RequestBody, user string) (model.PurgeTrashResponse, error)
+	PurgeExpiredTrash(retention time.Duration) (int, error)
+	UploadAttachment(request model.RequestBody) (model.UploadAttachmentResponse, error)
+	GetAttachment(request model.RequestBody, user string) (io.ReadCloser, model.Attachment, error)
 }
 
 type Service struct {
 	mailStore MailStore
-	uuidStore auth.UuidStore
+	blobStore blob.BlobStore
 }
 
-func NewService(mailStore MailStore, uuidStore auth.UuidStore) MailService {
+func NewService(mailStore MailStore, blobStore blob.BlobStore) MailService {
 	return &Service{
 		mailStore: mailStore,
-		uuidStore: uuidStore,
+		blobStore: blobStore,
+	}
+}
+
+// data of a request the api middleware already verified for its action
+func decodeRequest(payload model.RequestBody, message any) error {
+	err := json.Unmarshal([]byte(payload.Data), message)
+	if err != nil {
+		return ErrBadRequest
 	}
+
+	return nil
 }
 
 func (s *Service) GetInbox(
 	requestBody model.RequestBody,
-	publicKey *ecdsa.PublicKey,
 	query ServiceGetInboxQuery,
 ) (model.InboxResponse, error) {
-	const TIMEOUT = time.Duration(3 * time.Minute)
-
-	isVerify := account.Verify(
-		publicKey,
-		[]byte(requestBody.Data),
-		[]byte(requestBody.Signature),
-	)
-
-	if !isVerify {
-		return model.InboxResponse{}, fmt.Errorf("validation failed")
+	var message request.GetInboxRequest
+	err := decodeRequest(requestBody, &message)
+	if err != nil {
+		return model.InboxResponse{}, err
 	}
 
-	var message request.GetInboxRequest
-	err := json.Unmarshal([]byte(requestBody.Data), &message)
+	storeQuery := StoreGetInboxQuery{
+		Recipient: query.Recipient,
+		Limit:     query.Limit,
+		Order:     query.Order,
+	}
+	if storeQuery.Order == "" {
+		storeQuery.Order = NewestFirst
+	}
+	switch {
+	case query.Before != "" && query.After != "":
+		return model.InboxResponse{}, ErrBadRequest
+	case query.Before != "":
+		storeQuery.Before, err = DecodeCursor(query.Before)
+	case query.After != "":
+		storeQuery.After, err = DecodeCursor(query.After)
+	default:
+		storeQuery.Offset = (query.Page - 1) * query.Limit
+	}
 	if err != nil {
-		return model.InboxResponse{}, fmt.Errorf("bad request")
+		return model.InboxResponse{}, ErrBadRequest
 	}
 
-	// turn message.Timestamp string into time.Time
-	timestamp, err := time.Parse(time.RFC3339, message.Timestamp)
+	inbox, err := s.mailStore.GetInbox(storeQuery)
 	if err != nil {
-		return model.InboxResponse{}, fmt.Errorf("bad request")
+		return model.InboxResponse{}, err
 	}
-	isTimeout := time.Since(timestamp) > TIMEOUT
-	if isTimeout {
-		return model.InboxResponse{}, fmt.Errorf("message timeout")
+
+	parsedInbox := toInboxMails(inbox)
+
+	total, err := s.mailStore.GetTotalMailsReceived(query.Recipient)
+	if err != nil {
+		return model.InboxResponse{}, err
 	}
 
-	usedUUID, err := s.uuidStore.GetUsedUUID(message.ID)
+	unread, err := s.mailStore.GetTotalUnread(query.Recipient)
 	if err != nil {
 		return model.InboxResponse{}, err
 	}
-	if usedUUID != nil {
-		return model.InboxResponse{}, fmt.Errorf("uuid is already used")
+
+	inboxResponse := model.InboxResponse{
+		Inbox:  parsedInbox,
+		Total:  total,
+		Unread: unread,
+	}
+
+	// cursors of the oldest and newest mail on this page
+	if len(inbox) > 0 {
+		oldest, newest := inbox[len(inbox)-1], inbox[0]
+		if storeQuery.Order == OldestFirst {
+			oldest, newest = newest, oldest
+		}
+		oldestCursor, err := cursorOf(oldest)
+		if err != nil {
+			return model.InboxResponse{}, err
+		}
+		newestCursor, err := cursorOf(newest)
+		if err != nil {
+			return model.InboxResponse{}, err
+		}
+		inboxResponse.Before = oldestCursor.Encode()
+		inboxResponse.After = newestCursor.Encode()
 	}
-	err = s.uuidStore.InsertUsedUUID(message.ID)
+
+	return inboxResponse, nil
+}
+
+// delivery as shown to its recipient, the sender sees the same
+// mail without read state. bcc is only known to the delivery of a
+// bcc recipient, other recipients never get that delivery
+func toMail(entity model.MailEntity) model.Mail {
+	var bcc []string
+	if entity.RecipientType == model.RecipientBcc {
+		bcc = []string{entity.Recipient}
+	}
+
+	return model.Mail{
+		ID:             entity.ID,
+		MessageID:      entity.MessageID,
+		From:           entity.Sender,
+		Recipient:      entity.Recipient,
+		To:             entity.ToRecipients,
+		Cc:             entity.CcRecipients,
+		Bcc:            bcc,
+		EphemeralKey:   entity.EphemeralKey,
+		Subject:        entity.MailSubject,
+		Body:           entity.Body,
+		SignedData:     entity.SignedData,
+		Signature:      entity.Signature,
+		SentAt:         entity.SentAt,
+		ReadAt:         entity.ReadAt,
+		InReplyTo:      entity.InReplyTo,
+		ThreadID:       entity.ThreadID,
+		Attachments:    append([]model.Attachment{}, entity.Attachments...),
+		AttachmentKeys: entity.AttachmentKeys,
+	}
+}
+
+// received mails as shown to the recipient
+func toInboxMails(entities []model.MailEntity) []model.Mail {
+	mails := []model.Mail{}
+	for _, mailEntity := range entities {
+		mails = append(mails, toMail(mailEntity))
+	}
+
+	return mails
+}
+
+func (s *Service) SearchMail(
+	payload model.RequestBody,
+	query ServiceSearchMailQuery,
+) (model.InboxResponse, error) {
+	var message request.SearchEmailRequest
+	err := decodeRequest(payload, &message)
 	if err != nil {
 		return model.InboxResponse{}, err
 	}
 
-	storeQuery := StoreGetInboxQuery{
+	storeQuery := StoreSearchMailQuery{
 		Recipient: query.Recipient,
 		Limit:     query.Limit,
 		Offset:    (query.Page - 1) * query.Limit,
 	}
+	if message.From != "" {
+		senderPublicKey, err := account.ParseAddress(message.From)
+		if err != nil || account.PublicKeyToAddress(senderPublicKey).String() != message.From {
+			return model.InboxResponse{}, ErrBadRequest
+		}
+		storeQuery.Sender = message.From
+	}
+	if message.Since != "" {
+		since, err := time.Parse(time.RFC3339, message.Since)
+		if err != nil {
+			return model.InboxResponse{}, ErrBadRequest
+		}
+		storeQuery.Since = &since
+	}
+	if message.Until != "" {
+		until, err := time.Parse(time.RFC3339, message.Until)
+		if err != nil {
+			return model.InboxResponse{}, ErrBadRequest
+		}
+		storeQuery.Until = &until
+	}
 
-	inbox, err := s.mailStore.GetInbox(storeQuery)
+	result, err := s.mailStore.SearchMail(storeQuery)
 	if err != nil {
 		return model.InboxResponse{}, err
 	}
 
-	parsedInbox := []model.Mail{}
-	for _, mailEntity := range inbox {
-		parsedInbox = append(parsedInbox, model.Mail{
-			ID:      mailEntity.ID,
-			From:    mailEntity.Sender,
-			To:      mailEntity.Recipient,
-			Subject: mailEntity.MailSubject,
-			Body:    mailEntity.Body,
-		})
+	return model.InboxResponse{
+		Inbox:  toInboxMails(result.Mails),
+		Total:  result.Total,
+		Unread: result.Unread,
+	}, nil
+}
+
+// list mails sent by the user, content is still encrypted to each recipient
+func (s *Service) GetSent(
+	payload model.RequestBody,
+	query ServiceGetSentQuery,
+) (model.SentResponse, error) {
+	var message request.GetSentRequest
+	err := decodeRequest(payload, &message)
+	if err != nil {
+		return model.SentResponse{}, err
 	}
 
-	total, err := s.mailStore.GetTotalMailsReceived(query.Recipient)
+	storeQuery := StoreGetSentQuery{
+		Sender: query.Sender,
+		Limit:  query.Limit,
+		Offset: (query.Page - 1) * query.Limit,
+	}
+
+	sent, err := s.mailStore.GetSent(storeQuery)
 	if err != nil {
-		return model.InboxResponse{}, err
+		return model.SentResponse{}, err
 	}
 
-	inboxResponse := model.InboxResponse{
-		Inbox: parsedInbox,
+	// read state belongs to the recipient and is not shown to the sender
+	parsedSent := []model.Mail{}
+	for _, mailEntity := range sent {
+		mail := toMail(mailEntity)
+		mail.ReadAt = nil
+		parsedSent = append(parsedSent, mail)
+	}
+
+	total, err := s.mailStore.GetTotalMailsSent(query.Sender)
+	if err != nil {
+		return model.SentResponse{}, err
+	}
+
+	return model.SentResponse{
+		Sent:  parsedSent,
 		Total: total,
+	}, nil
+}
+
+func (s *Service) GetMail(payload model.RequestBody, user string) (model.Mail, error) {
+	var message request.GetEmailRequest
+	err := decodeRequest(payload, &message)
+	if err != nil {
+		return model.Mail{}, err
 	}
-	return inboxResponse, nil
+	if message.EmailID == uuid.Nil {
+		return model.Mail{}, ErrBadRequest
+	}
+
+	mail, err := s.mailStore.GetMail(message.EmailID, user)
+	if err == nil {
+		// only the recipient opening the mail marks it as read
+		if mail.Recipient == user && mail.ReadAt == nil {
+			_, err = s.mailStore.MarkRead([]uuid.UUID{mail.ID}, user)
+			if err != nil {
+				return model.Mail{}, err
+			}
+			readAt := time.Now().UTC().Format(time.RFC3339Nano)
+			mail.ReadAt = &readAt
+		}
+
+		return toMail(*mail), nil
+	}
+	if err == sql.ErrNoRows {
+		return model.Mail{}, ErrMailNotFound
+	}
+
+	return model.Mail{}, err
 }
 
-func (s *Service) GetMail(payload model.RequestBody, publicKey *ecdsa.PublicKey, user string) (model.Mail, error) {
-	const TIMEOUT = time.Duration(3 * time.Minute)
+// whole conversation of a mail the user can see, oldest first
+func (s *Service) GetThread(payload model.RequestBody, user string) (model.ThreadResponse, error) {
+	var message request.GetThreadRequest
+	err := decodeRequest(payload, &message)
+	if err != nil {
+		return model.ThreadResponse{}, err
+	}
 
-	isVerify := account.Verify(
-		publicKey,
-		[]byte(payload.Data),
-		[]byte(payload.Signature),
-	)
-	if !isVerify {
-		return model.Mail{}, fmt.Errorf("validation failed")
+	mail, err := s.mailStore.GetMail(message.EmailID, user)
+	if err == sql.ErrNoRows {
+		return model.ThreadResponse{}, ErrMailNotFound
+	}
+	if err != nil {
+		return model.ThreadResponse{}, err
 	}
 
-	var message request.GetEmailRequest
-	err := json.Unmarshal([]byte(payload.Data), &message)
+	thread, err := s.mailStore.GetThread(mail.ThreadID, user)
 	if err != nil {
-		return model.Mail{}, fmt.Errorf("internal server error")
+		return model.ThreadResponse{}, err
 	}
 
-	timestamp, err := time.Parse(time.RFC3339, message.Timestamp)
+	mails := toInboxMails(thread)
+	// read state belongs to the recipient and is not shown to the sender
+	for i := range mails {
+		if mails[i].Recipient != user {
+			mails[i].ReadAt = nil
+		}
+	}
+
+	return model.ThreadResponse{
+		ThreadID: mail.ThreadID,
+		Mails:    mails,
+	}, nil
+}
+
+// the signed request is stored with the mail so recipients can verify
+// the sender, the middleware only lets signed requests reach it
+func (s *Service) SendMail(requestBody model.RequestBody, sender string) (model.SendMailResponse, error) {
+	var message request.SendEmailRequest
+	err := decodeRequest(requestBody, &message)
 	if err != nil {
-		return model.Mail{}, fmt.Errorf("bad request")
+		return model.SendMailResponse{}, err
 	}
-	isTimeout := time.Since(timestamp) > TIMEOUT
-	if isTimeout {
-		return model.Mail{}, fmt.Errorf("message timeout")
+
+	deliveries, err := toDeliveries(message)
+	if err != nil {
+		return model.SendMailResponse{}, err
 	}
 
-	usedUUID, err := s.uuidStore.GetUsedUUID(message.ID)
+	attachments, err := s.checkAttachments(message.Attachments)
 	if err != nil {
-		return model.Mail{}, err
+		return model.SendMailResponse{}, err
 	}
-	if usedUUID != nil {
-		return model.Mail{}, fmt.Errorf("uuid is already used")
+
+	// a reply joins the thread of a mail the sender can read
+	var threadID uuid.UUID
+	if message.InReplyTo != nil {
+		parent, err := s.mailStore.GetMail(*message.InReplyTo, sender)
+		if err == sql.ErrNoRows {
+			return model.SendMailResponse{}, ErrBadRequest
+		}
+		if err != nil {
+			return model.SendMailResponse{}, err
+		}
+		threadID = parent.ThreadID
+		if threadID == uuid.Nil {
+			threadID = parent.ID
+		}
+	}
+
+	insertedMails, err := s.mailStore.InsertMail(model.OutgoingMail{
+		MessageID:   message.MessageID,
+		From:        sender,
+		To:          message.To,
+		Cc:          message.Cc,
+		Deliveries:  deliveries,
+		SignedData:  requestBody.Data,
+		Signature:   requestBody.Signature,
+		InReplyTo:   message.InReplyTo,
+		ThreadID:    threadID,
+		Attachments: attachments,
+	})
+	if err == ErrMessageConflict {
+		return model.SendMailResponse{}, ErrBadRequest
 	}
-	err = s.uuidStore.InsertUsedUUID(message.ID)
 	if err != nil {
-		return model.Mail{}, err
+		return model.SendMailResponse{}, err
+	}
+
+	ids := map[string]uuid.UUID{}
+	for _, mail := range insertedMails {
+		ids[mail.Recipient] = mail.ID
+	}
+
+	return model.SendMailResponse{
+		ID:        insertedMails[0].ID,
+		MessageID: message.MessageID,
+		IDs:       ids,
+	}, nil
+}
+
+// a request without bcc must have a delivery for every to and cc
+// recipient, a request with bcc is the copy of exactly one bcc
+// recipient and has only its delivery
+func toDeliveries(message request.SendEmailRequest) ([]model.Delivery, error) {
+	if message.MessageID == uuid.Nil {
+		return nil, ErrBadRequest
+	}
+	err := request.CheckRecipients(message.To, message.Cc, message.Bcc)
+	if err != nil {
+		return nil, ErrBadRequest
+	}
+
+	err = request.CheckAttachments(message.Attachments)
+	if err != nil {
+		return nil, ErrBadRequest
+	}
+
+	recipientTypes := map[string]model.RecipientType{}
+	if len(message.Bcc) == 0 {
+		for _, address := range message.To {
+			recipientTypes[address] = model.RecipientTo
+		}
+		for _, address := range message.Cc {
+			recipientTypes[address] = model.RecipientCc
+		}
+	} else if len(message.Bcc) == 1 {
+		recipientTypes[message.Bcc[0]] = model.RecipientBcc
+	} else {
+		return nil, ErrBadRequest
+	}
+	if len(message.Deliveries) != len(recipientTypes) {
+		return nil, ErrBadRequest
+	}
+
+	deliveries := []model.Delivery{}
+	for _, delivery := range message.Deliveries {
+		recipientType, ok := recipientTypes[delivery.Recipient]
+		// subject and body are opaque ciphertext, only require the key to decrypt them
+		if !ok || delivery.EphemeralKey == "" {
+			return nil, ErrBadRequest
+		}
+		// every recipient needs the keys of the attachments, and only then
+		if (len(message.Attachments) > 0) != (delivery.AttachmentKeys != "") {
+			return nil, ErrBadRequest
+		}
+		delete(recipientTypes, delivery.Recipient)
+
+		deliveries = append(deliveries, model.Delivery{
+			Recipient:      delivery.Recipient,
+			Type:           recipientType,
+			EphemeralKey:   delivery.EphemeralKey,
+			Subject:        delivery.Subject,
+			Body:           delivery.Body,
+			AttachmentKeys: delivery.AttachmentKeys,
+		})
+	}
+
+	return deliveries, nil
+}
+
+// every attachment must be uploaded with the size the sender signed,
+// limits are already checked by toDeliveries
+func (s *Service) checkAttachments(references []clientmodel.Attachment) ([]model.Attachment, error) {
+	var attachments []model.Attachment
+	for _, reference := range references {
+		size, err := s.blobStore.Size(reference.Hash)
+		if err == blob.ErrNotFound {
+			return nil, ErrBadRequest
+		}
+		if err != nil {
+			return nil, err
+		}
+		if size != reference.Size {
+			return nil, ErrBadRequest
+		}
+
+		attachments = append(attachments, model.Attachment{
+			Hash:     reference.Hash,
+			Size:     reference.Size,
+			MimeType: reference.MimeType,
+		})
+	}
+
+	return attachments, nil
+}
+
+// store an encrypted attachment, it can be referenced by a mail
+// once uploaded and uploading the same content again is a no-op
+func (s *Service) UploadAttachment(payload model.RequestBody) (model.UploadAttachmentResponse, error) {
+	var message request.UploadAttachmentRequest
+	err := decodeRequest(payload, &message)
+	if err != nil {
+		return model.UploadAttachmentResponse{}, err
+	}
+	if len(message.Content) == 0 || len(message.Content) > request.MaxAttachmentSize {
+		return model.UploadAttachmentResponse{}, ErrBadRequest
+	}
+
+	hash, err := s.blobStore.Put(message.Content)
+	if err != nil {
+		return model.UploadAttachmentResponse{}, err
+	}
+
+	return model.UploadAttachmentResponse{
+		Hash: hash,
+		Size: int64(len(message.Content)),
+	}, nil
+}
+
+// attachment of a mail the user sent or received, same rule as GetMail.
+// the caller must close the returned content
+func (s *Service) GetAttachment(payload model.RequestBody, user string) (io.ReadCloser, model.Attachment, error) {
+	var message request.GetAttachmentRequest
+	err := decodeRequest(payload, &message)
+	if err != nil {
+		return nil, model.Attachment{}, err
 	}
 
 	mail, err := s.mailStore.GetMail(message.EmailID, user)
-	if err == nil {
-		return model.Mail{
-			ID:      mail.ID,
-			From:    mail.Sender,
-			To:      mail.Recipient,
-			Subject: mail.MailSubject,
-			Body:    mail.Body,
-		}, nil
+	if err == sql.ErrNoRows {
+		return nil, model.Attachment{}, ErrMailNotFound
 	}
-	if err.Error() == "sql: no rows in result set" {
-		return model.Mail{}, fmt.Errorf("mail not found")
+	if err != nil {
+		return nil, model.Attachment{}, err
 	}
 
-	return model.Mail{}, nil
+	for _, attachment := range mail.Attachments {
+		if attachment.Hash != message.Hash {
+			continue
+		}
+
+		content, err := s.blobStore.Open(attachment.Hash)
+		if err == blob.ErrNotFound {
+			return nil, model.Attachment{}, ErrAttachmentNotFound
+		}
+		if err != nil {
+			return nil, model.Attachment{}, err
+		}
+
+		return content, attachment, nil
+	}
+
+	return nil, model.Attachment{}, ErrAttachmentNotFound
 }
 
-func (s *Service) SendMail(
-	requestBody model.RequestBody,
-	publicKey *ecdsa.PublicKey,
-) (model.SendMailResponse, error) {
-	const TIMEOUT = time.Duration(3 * time.Minute)
+// move mail to trash of the user, only the recipient can delete a mail
+func (s *Service) DeleteMail(payload model.RequestBody, user string) (model.TrashMailResponse, error) {
+	var message request.TrashEmailRequest
+	err := decodeRequest(payload, &message)
+	if err != nil {
+		return model.TrashMailResponse{}, err
+	}
 
-	isVerify := account.Verify(
-		publicKey,
-		[]byte(requestBody.Data),
-		[]byte(requestBody.Signature),
-	)
-	if !isVerify {
-		return model.SendMailResponse{}, fmt.Errorf("validation failed")
+	err = s.mailStore.TrashMail(message.EmailID, user)
+	if err == sql.ErrNoRows {
+		return model.TrashMailResponse{}, ErrMailNotFound
+	}
+	if err != nil {
+		return model.TrashMailResponse{}, err
 	}
 
-	var message request.SendEmailRequest
-	err := json.Unmarshal([]byte(requestBody.Data), &message)
+	return model.TrashMailResponse{
+		ID: message.EmailID,
+	}, nil
+}
+
+// move mail from trash back to inbox of the user
+func (s *Service) RestoreMail(payload model.RequestBody, user string) (model.TrashMailResponse, error) {
+	var message request.TrashEmailRequest
+	err := decodeRequest(payload, &message)
 	if err != nil {
-		return model.SendMailResponse{}, fmt.Errorf("bad request")
+		return model.TrashMailResponse{}, err
 	}
 
-	// check if recipient is a valid public key
-	_, err = account.HexToPublicKey(message.Recipient)
+	err = s.mailStore.RestoreMail(message.EmailID, user)
+	if err == sql.ErrNoRows {
+		return model.TrashMailResponse{}, ErrMailNotFound
+	}
 	if err != nil {
-		return model.SendMailResponse{}, fmt.Errorf("bad request")
+		return model.TrashMailResponse{}, err
 	}
 
-	timestamp, err := time.Parse(time.RFC3339, message.Timestamp)
+	return model.TrashMailResponse{
+		ID: message.EmailID,
+	}, nil
+}
+
+// mark received mails of the user as read, mails of other users are ignored
+func (s *Service) MarkRead(payload model.RequestBody, user string) (model.MarkMailsResponse, error) {
+	var message request.MarkEmailsRequest
+	err := decodeRequest(payload, &message)
 	if err != nil {
-		return model.SendMailResponse{}, fmt.Errorf("bad request")
+		return model.MarkMailsResponse{}, err
 	}
-	isTimeout := time.Since(timestamp) > TIMEOUT
-	if isTimeout {
-		return model.SendMailResponse{}, fmt.Errorf("message timeout")
+	if len(message.EmailIDs) == 0 || len(message.EmailIDs) > request.MaxMarkEmails {
+		return model.MarkMailsResponse{}, ErrBadRequest
 	}
 
-	usedUUID, err := s.uuidStore.GetUsedUUID(message.ID)
+	updated, err := s.mailStore.MarkRead(message.EmailIDs, user)
 	if err != nil {
-		return model.SendMailResponse{}, err
+		return model.MarkMailsResponse{}, err
+	}
+
+	return model.MarkMailsResponse{
+		Updated: updated,
+	}, nil
+}
+
+// mark received mails of the user as unread, mails of other users are ignored
+func (s *Service) MarkUnread(payload model.RequestBody, user string) (model.MarkMailsResponse, error) {
+	var message request.MarkEmailsRequest
+	err := decodeRequest(payload, &message)
+	if err != nil {
+		return model.MarkMailsResponse{}, err
 	}
-	if usedUUID != nil {
-		return model.SendMailResponse{}, fmt.Errorf("uuid is already used")
+	if len(message.EmailIDs) == 0 || len(message.EmailIDs) > request.MaxMarkEmails {
+		return model.MarkMailsResponse{}, ErrBadRequest
 	}
-	err = s.uuidStore.InsertUsedUUID(message.ID)
+
+	updated, err := s.mailStore.MarkUnread(message.EmailIDs, user)
 	if err != nil {
-		return model.SendMailResponse{}, err
+		return model.MarkMailsResponse{}, err
 	}
 
-	sender := account.PublicKeyToHex(publicKey)
+	return model.MarkMailsResponse{
+		Updated: updated,
+	}, nil
+}
 
-	insertedMail, err := s.mailStore.InsertMail(model.Mail{
-		From:    sender,
-		To:      message.Recipient,
-		Subject: message.Subject,
-		Body:    message.Body,
-	})
+// permanently delete every mail in trash of the user
+func (s *Service) PurgeTrash(payload model.RequestBody, user string) (model.PurgeTrashResponse, error) {
+	var message request.PurgeTrashRequest
+	err := decodeRequest(payload, &message)
 	if err != nil {
-		return model.SendMailResponse{}, err
+		return model.PurgeTrashResponse{}, err
 	}
 
-	return model.SendMailResponse{
-		ID: insertedMail.ID,
+	purged, err := s.mailStore.PurgeTrash(user)
+	if err != nil {
+		return model.PurgeTrashResponse{}, err
+	}
+
+	return model.PurgeTrashResponse{
+		Purged: purged,
 	}, nil
 }
+
+// permanently delete mails that stayed in trash longer than retention
+func (s *Service) PurgeExpiredTrash(retention time.Duration) (int, error) {
+	return s.mailStore.PurgeTrashBefore(time.Now().Add(-retention))
+}
@@ -0,0 +1,474 @@
+package mail
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"passwordless-mail-server/pkg/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// MailStore of a sqlite database migrated by infra/migration/sqlite.
+// it has the queries of Store, sqlite has no DISTINCT ON so one delivery
+// per message is picked with ROW_NUMBER, and timestamps are set by the
+// store in UTC so they keep their order when compared as text
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(database *sql.DB) MailStore {
+	return &SQLiteStore{
+		db: database,
+	}
+}
+
+func (s *SQLiteStore) GetInbox(query StoreGetInboxQuery) ([]model.MailEntity, error) {
+	direction := "DESC"
+	if query.Order == OldestFirst {
+		direction = "ASC"
+	}
+
+	// read from the cursor outward, then flip back to the requested order
+	args := []any{query.Recipient}
+	cursorCondition := ""
+	reverse := false
+	if query.Before != nil {
+		cursorCondition = "AND (sent_at, id) < (?2, ?3)"
+		args = append(args, query.Before.SentAt.UTC(), query.Before.ID)
+		reverse = direction == "ASC"
+		direction = "DESC"
+	} else if query.After != nil {
+		cursorCondition = "AND (sent_at, id) > (?2, ?3)"
+		args = append(args, query.After.SentAt.UTC(), query.After.ID)
+		reverse = direction == "DESC"
+		direction = "ASC"
+	}
+	offset, limit := pageBounds(query.Offset, query.Limit)
+	args = append(args, limit, offset)
+
+	getInboxQuery := fmt.Sprintf(`
+		SELECT `+mailColumns+` FROM mail
+		WHERE recipient = ?1
+		AND deleted_at IS NULL
+		%s
+		ORDER BY sent_at %s, id %s
+		LIMIT ?%d
+		OFFSET ?%d
+	`, cursorCondition, direction, direction, len(args)-1, len(args))
+
+	inbox, err := s.queryMails(getInboxQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if reverse {
+		for i, j := 0, len(inbox)-1; i < j; i, j = i+1, j-1 {
+			inbox[i], inbox[j] = inbox[j], inbox[i]
+		}
+	}
+
+	return inbox, nil
+}
+
+func (s *SQLiteStore) GetTotalMailsReceived(user string) (int, error) {
+	queryScript := `
+		SELECT COUNT(*) FROM mail
+		WHERE recipient = ?1
+		AND deleted_at IS NULL
+	`
+
+	var total int
+	err := s.db.QueryRow(queryScript, user).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (s *SQLiteStore) GetTotalUnread(recipient string) (int, error) {
+	queryScript := `
+		SELECT COUNT(*) FROM mail
+		WHERE recipient = ?1
+		AND deleted_at IS NULL
+		AND read_at IS NULL
+	`
+
+	var total int
+	err := s.db.QueryRow(queryScript, recipient).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// see Store.GetSent
+func (s *SQLiteStore) GetSent(query StoreGetSentQuery) ([]model.MailEntity, error) {
+	getSentQuery := `
+		SELECT ` + mailColumns + ` FROM (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY COALESCE(message_id, id)
+				ORDER BY recipient_type = 'bcc', id
+			) AS delivery FROM mail
+			WHERE sender = ?1
+		) sent
+		WHERE delivery = 1
+		ORDER BY sent_at DESC, id DESC
+		LIMIT ?2
+		OFFSET ?3
+	`
+
+	offset, limit := pageBounds(query.Offset, query.Limit)
+
+	return s.queryMails(getSentQuery, query.Sender, limit, offset)
+}
+
+func (s *SQLiteStore) GetTotalMailsSent(sender string) (int, error) {
+	queryScript := `
+		SELECT COUNT(DISTINCT COALESCE(message_id, id)) FROM mail
+		WHERE sender = ?1
+	`
+
+	var total int
+	err := s.db.QueryRow(queryScript, sender).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// see Store.SearchMail
+func (s *SQLiteStore) SearchMail(query StoreSearchMailQuery) (StoreSearchMailResult, error) {
+	conditions := "recipient = ?1 AND deleted_at IS NULL"
+	args := []any{query.Recipient}
+	if query.Sender != "" {
+		args = append(args, query.Sender)
+		conditions += fmt.Sprintf(" AND sender = ?%d", len(args))
+	}
+	if query.Since != nil {
+		args = append(args, query.Since.UTC())
+		conditions += fmt.Sprintf(" AND sent_at >= ?%d", len(args))
+	}
+	if query.Until != nil {
+		args = append(args, query.Until.UTC())
+		conditions += fmt.Sprintf(" AND sent_at < ?%d", len(args))
+	}
+
+	countQuery := `
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN read_at IS NULL THEN 1 ELSE 0 END), 0) FROM mail
+		WHERE ` + conditions
+
+	var result StoreSearchMailResult
+	err := s.db.QueryRow(countQuery, args...).Scan(&result.Total, &result.Unread)
+	if err != nil {
+		return StoreSearchMailResult{}, err
+	}
+
+	offset, limit := pageBounds(query.Offset, query.Limit)
+	args = append(args, limit, offset)
+	searchQuery := fmt.Sprintf(`
+		SELECT `+mailColumns+` FROM mail
+		WHERE %s
+		ORDER BY sent_at DESC, id DESC
+		LIMIT ?%d
+		OFFSET ?%d
+	`, conditions, len(args)-1, len(args))
+
+	result.Mails, err = s.queryMails(searchQuery, args...)
+	if err != nil {
+		return StoreSearchMailResult{}, err
+	}
+
+	return result, nil
+}
+
+func (s *SQLiteStore) GetMail(id uuid.UUID, user string) (*model.MailEntity, error) {
+	queryScript := `
+		SELECT ` + mailColumns + ` FROM mail
+		WHERE id = ?1
+		AND (recipient = ?2 OR sender = ?2)
+	`
+
+	mail, err := scanMail(s.db.QueryRow(queryScript, id, user))
+	if err != nil {
+		return nil, err
+	}
+
+	return mail, nil
+}
+
+// see Store.InsertMail, the transaction takes the write lock when it
+// begins so the conflict check and the inserts are not interleaved
+func (s *SQLiteStore) InsertMail(mail model.OutgoingMail) ([]model.MailEntity, error) {
+	recipients, err := json.Marshal(deliveryRecipients(mail.Deliveries))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var conflicts int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM mail
+		WHERE message_id = ?1
+		AND (sender <> ?2 OR recipient IN (SELECT value FROM json_each(?3)))
+	`, mail.MessageID, mail.From, string(recipients)).Scan(&conflicts)
+	if err != nil {
+		return nil, err
+	}
+	if conflicts > 0 {
+		return nil, ErrMessageConflict
+	}
+
+	queryScript := `
+		INSERT INTO mail (
+			id, message_id, recipient, recipient_type, to_recipients, cc_recipients, sender,
+			mail_subject, body, ephemeral_key, signed_data, signature, in_reply_to, thread_id,
+			attachments, attachment_keys, sent_at
+		)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16, ?17)
+	`
+
+	threadId := mail.ThreadID
+	if threadId == uuid.Nil {
+		threadId = mail.MessageID
+	}
+	sentAt := sqliteNow()
+
+	var inserted []model.MailEntity
+	for _, delivery := range mail.Deliveries {
+		entity := model.MailEntity{
+			ID:             uuid.New(),
+			MessageID:      mail.MessageID,
+			Recipient:      delivery.Recipient,
+			RecipientType:  delivery.Type,
+			ToRecipients:   append([]string{}, mail.To...),
+			CcRecipients:   append([]string{}, mail.Cc...),
+			Sender:         mail.From,
+			MailSubject:    delivery.Subject,
+			Body:           delivery.Body,
+			EphemeralKey:   delivery.EphemeralKey,
+			SignedData:     mail.SignedData,
+			Signature:      mail.Signature,
+			InReplyTo:      mail.InReplyTo,
+			ThreadID:       threadId,
+			Attachments:    append(model.Attachments{}, mail.Attachments...),
+			AttachmentKeys: delivery.AttachmentKeys,
+		}
+
+		_, err = tx.Exec(
+			queryScript,
+			entity.ID,
+			entity.MessageID,
+			entity.Recipient,
+			entity.RecipientType,
+			pq.Array(entity.ToRecipients),
+			pq.Array(entity.CcRecipients),
+			entity.Sender,
+			entity.MailSubject,
+			entity.Body,
+			entity.EphemeralKey,
+			entity.SignedData,
+			entity.Signature,
+			entity.InReplyTo,
+			entity.ThreadID,
+			entity.Attachments,
+			entity.AttachmentKeys,
+			sentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		inserted = append(inserted, entity)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+// see Store.GetThread
+func (s *SQLiteStore) GetThread(threadID uuid.UUID, user string) ([]model.MailEntity, error) {
+	queryScript := `
+		SELECT ` + mailColumns + ` FROM (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY COALESCE(message_id, id)
+				ORDER BY recipient <> ?2, recipient_type = 'bcc', id
+			) AS delivery FROM mail
+			WHERE thread_id = ?1
+			AND (
+				(recipient = ?2 AND deleted_at IS NULL)
+				OR sender = ?2
+			)
+		) thread
+		WHERE delivery = 1
+		ORDER BY sent_at ASC, id ASC
+	`
+
+	return s.queryMails(queryScript, threadID, user)
+}
+
+// return amount of mails that were unread before
+func (s *SQLiteStore) MarkRead(ids []uuid.UUID, recipient string) (int, error) {
+	queryScript := `
+		UPDATE mail SET read_at = ?3
+		WHERE id IN (SELECT value FROM json_each(?1))
+		AND recipient = ?2
+		AND read_at IS NULL
+	`
+
+	return s.updateMails(queryScript, ids, recipient, sqliteNow())
+}
+
+// return amount of mails that were read before
+func (s *SQLiteStore) MarkUnread(ids []uuid.UUID, recipient string) (int, error) {
+	queryScript := `
+		UPDATE mail SET read_at = NULL
+		WHERE id IN (SELECT value FROM json_each(?1))
+		AND recipient = ?2
+		AND read_at IS NOT NULL
+	`
+
+	return s.updateMails(queryScript, ids, recipient)
+}
+
+// ids are passed as one json array, the query reads them with json_each
+func (s *SQLiteStore) updateMails(queryScript string, ids []uuid.UUID, recipient string, args ...any) (int, error) {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+	idArray, err := json.Marshal(idStrings)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.Exec(queryScript, append([]any{string(idArray), recipient}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(updated), nil
+}
+
+// soft delete, the mail stays in trash of the recipient until purged
+func (s *SQLiteStore) TrashMail(id uuid.UUID, recipient string) error {
+	queryScript := `
+		UPDATE mail SET deleted_at = ?3
+		WHERE id = ?1
+		AND recipient = ?2
+		AND deleted_at IS NULL
+	`
+
+	result, err := s.db.Exec(queryScript, id, recipient, sqliteNow())
+	if err != nil {
+		return err
+	}
+
+	return requireAffectedRow(result)
+}
+
+func (s *SQLiteStore) RestoreMail(id uuid.UUID, recipient string) error {
+	queryScript := `
+		UPDATE mail SET deleted_at = NULL
+		WHERE id = ?1
+		AND recipient = ?2
+		AND deleted_at IS NOT NULL
+	`
+
+	result, err := s.db.Exec(queryScript, id, recipient)
+	if err != nil {
+		return err
+	}
+
+	return requireAffectedRow(result)
+}
+
+// permanently delete every trashed mail of the recipient
+func (s *SQLiteStore) PurgeTrash(recipient string) (int, error) {
+	queryScript := `
+		DELETE FROM mail
+		WHERE recipient = ?1
+		AND deleted_at IS NOT NULL
+	`
+
+	result, err := s.db.Exec(queryScript, recipient)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), nil
+}
+
+// permanently delete mails of every recipient trashed before the given time
+func (s *SQLiteStore) PurgeTrashBefore(before time.Time) (int, error) {
+	queryScript := `
+		DELETE FROM mail
+		WHERE deleted_at IS NOT NULL
+		AND deleted_at < ?1
+	`
+
+	result, err := s.db.Exec(queryScript, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), nil
+}
+
+func (s *SQLiteStore) queryMails(queryScript string, args ...any) ([]model.MailEntity, error) {
+	rows, err := s.db.Query(queryScript, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var mails []model.MailEntity
+	for rows.Next() {
+		mail, err := scanMail(rows)
+		if err != nil {
+			return nil, err
+		}
+		mails = append(mails, *mail)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return mails, nil
+}
+
+// NOW() of postgres, with the precision of a postgres timestamp
+func sqliteNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
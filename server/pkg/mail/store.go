@@ -2,24 +2,71 @@ package mail
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"passwordless-mail-server/pkg/model"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// with before or after cursor, offset is not used
 type StoreGetInboxQuery struct {
 	Recipient string
 	Offset    int
 	Limit     int
+	Order     SortOrder
+	Before    *Cursor
+	After     *Cursor
+}
+
+type StoreGetSentQuery struct {
+	Sender string
+	Offset int
+	Limit  int
+}
+
+// empty sender and nil since/until match every mail of the recipient
+type StoreSearchMailQuery struct {
+	Recipient string
+	Sender    string
+	Since     *time.Time
+	Until     *time.Time
+	Offset    int
+	Limit     int
+}
+
+type StoreSearchMailResult struct {
+	Mails  []model.MailEntity
+	Total  int
+	Unread int
 }
 
 type MailStore interface {
 	GetInbox(query StoreGetInboxQuery) ([]model.MailEntity, error)
 	GetTotalMailsReceived(user string) (int, error)
+	GetTotalUnread(recipient string) (int, error)
+	GetSent(query StoreGetSentQuery) ([]model.MailEntity, error)
+	GetTotalMailsSent(sender string) (int, error)
+	SearchMail(query StoreSearchMailQuery) (StoreSearchMailResult, error)
 	GetMail(id uuid.UUID, user string) (*model.MailEntity, error)
-	InsertMail(mail model.Mail) (*model.MailEntity, error)
+	GetThread(threadID uuid.UUID, user string) ([]model.MailEntity, error)
+	InsertMail(mail model.OutgoingMail) ([]model.MailEntity, error)
+	MarkRead(ids []uuid.UUID, recipient string) (int, error)
+	MarkUnread(ids []uuid.UUID, recipient string) (int, error)
+	TrashMail(id uuid.UUID, recipient string) error
+	RestoreMail(id uuid.UUID, recipient string) error
+	PurgeTrash(recipient string) (int, error)
+	PurgeTrashBefore(before time.Time) (int, error)
 }
 
+// message id already has a delivery to the recipient or is used by another sender
+var ErrMessageConflict = errors.New("message conflict")
+
+// column order used by every mail query and scanMail
+const mailColumns = "id, recipient, sender, mail_subject, body, sent_at, ephemeral_key, signed_data, signature, deleted_at, read_at, in_reply_to, thread_id, message_id, recipient_type, to_recipients, cc_recipients, attachments, attachment_keys"
+
 type Store struct {
 	db *sql.DB
 }
@@ -31,14 +78,40 @@ func NewStore(database *sql.DB) MailStore {
 }
 
 func (s *Store) GetInbox(query StoreGetInboxQuery) ([]model.MailEntity, error) {
-	getInboxQuery := `
-		SELECT * FROM mail
+	direction := "DESC"
+	if query.Order == OldestFirst {
+		direction = "ASC"
+	}
+
+	// read from the cursor outward, then flip back to the requested order
+	args := []any{query.Recipient}
+	cursorCondition := ""
+	reverse := false
+	if query.Before != nil {
+		cursorCondition = "AND (sent_at, id) < ($2, $3)"
+		args = append(args, query.Before.SentAt, query.Before.ID)
+		reverse = direction == "ASC"
+		direction = "DESC"
+	} else if query.After != nil {
+		cursorCondition = "AND (sent_at, id) > ($2, $3)"
+		args = append(args, query.After.SentAt, query.After.ID)
+		reverse = direction == "DESC"
+		direction = "ASC"
+	}
+	offset, limit := pageBounds(query.Offset, query.Limit)
+	args = append(args, limit, offset)
+
+	getInboxQuery := fmt.Sprintf(`
+		SELECT `+mailColumns+` FROM mail
 		WHERE recipient = $1
-		LIMIT $2
-		OFFSET $3
-	`
+		AND deleted_at IS NULL
+		%s
+		ORDER BY sent_at %s, id %s
+		LIMIT $%d
+		OFFSET $%d
+	`, cursorCondition, direction, direction, len(args)-1, len(args))
 
-	rows, err := s.db.Query(getInboxQuery, query.Recipient, query.Limit, query.Offset)
+	rows, err := s.db.Query(getInboxQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -47,12 +120,21 @@ func (s *Store) GetInbox(query StoreGetInboxQuery) ([]model.MailEntity, error) {
 
 	var inbox []model.MailEntity
 	for rows.Next() {
-		var mail model.MailEntity
-		err := rows.Scan(&mail.ID, &mail.Recipient, &mail.Sender, &mail.MailSubject, &mail.Body, &mail.SentAt)
+		mail, err := scanMail(rows)
 		if err != nil {
 			return nil, err
 		}
-		inbox = append(inbox, mail)
+		inbox = append(inbox, *mail)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if reverse {
+		for i, j := 0, len(inbox)-1; i < j; i, j = i+1, j-1 {
+			inbox[i], inbox[j] = inbox[j], inbox[i]
+		}
 	}
 
 	return inbox, nil
@@ -62,6 +144,7 @@ func (s *Store) GetTotalMailsReceived(user string) (int, error) {
 	queryScript := `
 		SELECT COUNT(*) FROM mail
 		WHERE recipient = $1
+		AND deleted_at IS NULL
 	`
 
 	var total int
@@ -73,47 +156,460 @@ func (s *Store) GetTotalMailsReceived(user string) (int, error) {
 	return total, nil
 }
 
+func (s *Store) GetTotalUnread(recipient string) (int, error) {
+	queryScript := `
+		SELECT COUNT(*) FROM mail
+		WHERE recipient = $1
+		AND deleted_at IS NULL
+		AND read_at IS NULL
+	`
+
+	var total int
+	err := s.db.QueryRow(queryScript, recipient).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// mails sent by the sender, one delivery per message, a to or cc
+// delivery when the message has one.
+// trash of the recipient does not hide them
+func (s *Store) GetSent(query StoreGetSentQuery) ([]model.MailEntity, error) {
+	getSentQuery := `
+		SELECT ` + mailColumns + ` FROM (
+			SELECT DISTINCT ON (COALESCE(message_id, id)) * FROM mail
+			WHERE sender = $1
+			ORDER BY COALESCE(message_id, id), recipient_type = 'bcc', id
+		) sent
+		ORDER BY sent_at DESC, id DESC
+		LIMIT $2
+		OFFSET $3
+	`
+
+	offset, limit := pageBounds(query.Offset, query.Limit)
+	rows, err := s.db.Query(getSentQuery, query.Sender, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var sent []model.MailEntity
+	for rows.Next() {
+		mail, err := scanMail(rows)
+		if err != nil {
+			return nil, err
+		}
+		sent = append(sent, *mail)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return sent, nil
+}
+
+func (s *Store) GetTotalMailsSent(sender string) (int, error) {
+	queryScript := `
+		SELECT COUNT(DISTINCT COALESCE(message_id, id)) FROM mail
+		WHERE sender = $1
+	`
+
+	var total int
+	err := s.db.QueryRow(queryScript, sender).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// subject and body are ciphertext, so mails are matched
+// by sender and sent_at only, keywords are matched by the client
+func (s *Store) SearchMail(query StoreSearchMailQuery) (StoreSearchMailResult, error) {
+	conditions := "recipient = $1 AND deleted_at IS NULL"
+	args := []any{query.Recipient}
+	if query.Sender != "" {
+		args = append(args, query.Sender)
+		conditions += fmt.Sprintf(" AND sender = $%d", len(args))
+	}
+	if query.Since != nil {
+		args = append(args, query.Since.UTC())
+		conditions += fmt.Sprintf(" AND sent_at >= $%d", len(args))
+	}
+	if query.Until != nil {
+		args = append(args, query.Until.UTC())
+		conditions += fmt.Sprintf(" AND sent_at < $%d", len(args))
+	}
+
+	countQuery := `
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN read_at IS NULL THEN 1 ELSE 0 END), 0) FROM mail
+		WHERE ` + conditions
+
+	var result StoreSearchMailResult
+	err := s.db.QueryRow(countQuery, args...).Scan(&result.Total, &result.Unread)
+	if err != nil {
+		return StoreSearchMailResult{}, err
+	}
+
+	offset, limit := pageBounds(query.Offset, query.Limit)
+	args = append(args, limit, offset)
+	searchQuery := fmt.Sprintf(`
+		SELECT `+mailColumns+` FROM mail
+		WHERE %s
+		ORDER BY sent_at DESC, id DESC
+		LIMIT $%d
+		OFFSET $%d
+	`, conditions, len(args)-1, len(args))
+
+	rows, err := s.db.Query(searchQuery, args...)
+	if err != nil {
+		return StoreSearchMailResult{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		mail, err := scanMail(rows)
+		if err != nil {
+			return StoreSearchMailResult{}, err
+		}
+		result.Mails = append(result.Mails, *mail)
+	}
+	err = rows.Err()
+	if err != nil {
+		return StoreSearchMailResult{}, err
+	}
+
+	return result, nil
+}
+
 func (s *Store) GetMail(id uuid.UUID, user string) (*model.MailEntity, error) {
 	queryScript := `
-		SELECT * FROM mail
+		SELECT ` + mailColumns + ` FROM mail
 		WHERE id = $1
 		AND (recipient = $2 OR sender = $2)
 	`
 
-	var mail model.MailEntity
-	err := s.db.QueryRow(queryScript, id, user).Scan(&mail.ID, &mail.Recipient, &mail.Sender, &mail.MailSubject, &mail.Body, &mail.SentAt)
+	mail, err := scanMail(s.db.QueryRow(queryScript, id, user))
 	if err != nil {
 		return nil, err
 	}
 
-	return &mail, nil
+	return mail, nil
+}
+
+// insert one row per delivery of the mail in a single transaction.
+// every bcc recipient has its own send request, so more deliveries can
+// join the message later, but only from the same sender and only once
+// per recipient, otherwise ErrMessageConflict is returned.
+// a mail without a thread starts its own, the thread id is the message id
+func (s *Store) InsertMail(mail model.OutgoingMail) ([]model.MailEntity, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var conflicts int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM mail
+		WHERE message_id = $1
+		AND (sender <> $2 OR recipient = ANY($3::text[]))
+	`, mail.MessageID, mail.From, pq.Array(deliveryRecipients(mail.Deliveries))).Scan(&conflicts)
+	if err != nil {
+		return nil, err
+	}
+	if conflicts > 0 {
+		return nil, ErrMessageConflict
+	}
+
+	queryScript := `
+		INSERT INTO mail (
+			id, message_id, recipient, recipient_type, to_recipients, cc_recipients, sender,
+			mail_subject, body, ephemeral_key, signed_data, signature, in_reply_to, thread_id,
+			attachments, attachment_keys
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+
+	threadId := mail.ThreadID
+	if threadId == uuid.Nil {
+		threadId = mail.MessageID
+	}
+
+	var inserted []model.MailEntity
+	for _, delivery := range mail.Deliveries {
+		entity := model.MailEntity{
+			ID:             uuid.New(),
+			MessageID:      mail.MessageID,
+			Recipient:      delivery.Recipient,
+			RecipientType:  delivery.Type,
+			ToRecipients:   append([]string{}, mail.To...),
+			CcRecipients:   append([]string{}, mail.Cc...),
+			Sender:         mail.From,
+			MailSubject:    delivery.Subject,
+			Body:           delivery.Body,
+			EphemeralKey:   delivery.EphemeralKey,
+			SignedData:     mail.SignedData,
+			Signature:      mail.Signature,
+			InReplyTo:      mail.InReplyTo,
+			ThreadID:       threadId,
+			Attachments:    append(model.Attachments{}, mail.Attachments...),
+			AttachmentKeys: delivery.AttachmentKeys,
+		}
+
+		_, err = tx.Exec(
+			queryScript,
+			entity.ID,
+			entity.MessageID,
+			entity.Recipient,
+			entity.RecipientType,
+			pq.Array(entity.ToRecipients),
+			pq.Array(entity.CcRecipients),
+			entity.Sender,
+			entity.MailSubject,
+			entity.Body,
+			entity.EphemeralKey,
+			entity.SignedData,
+			entity.Signature,
+			entity.InReplyTo,
+			entity.ThreadID,
+			entity.Attachments,
+			entity.AttachmentKeys,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		inserted = append(inserted, entity)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+// OFFSET and LIMIT of a page, a negative offset is no offset and a negative
+// limit is an empty page, the same on every MailStore
+func pageBounds(offset int, limit int) (int, int) {
+	return max(offset, 0), max(limit, 0)
+}
+
+func deliveryRecipients(deliveries []model.Delivery) []string {
+	recipients := []string{}
+	for _, delivery := range deliveries {
+		recipients = append(recipients, delivery.Recipient)
+	}
+
+	return recipients
+}
+
+// mails of the thread visible to the user, oldest first, one delivery
+// per message, the delivery to the user when there is one.
+// mails the user moved to trash are left out
+func (s *Store) GetThread(threadID uuid.UUID, user string) ([]model.MailEntity, error) {
+	queryScript := `
+		SELECT ` + mailColumns + ` FROM (
+			SELECT DISTINCT ON (COALESCE(message_id, id)) * FROM mail
+			WHERE thread_id = $1
+			AND (
+				(recipient = $2 AND deleted_at IS NULL)
+				OR sender = $2
+			)
+			ORDER BY COALESCE(message_id, id), recipient <> $2, recipient_type = 'bcc', id
+		) thread
+		ORDER BY sent_at ASC, id ASC
+	`
+
+	rows, err := s.db.Query(queryScript, threadID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var thread []model.MailEntity
+	for rows.Next() {
+		mail, err := scanMail(rows)
+		if err != nil {
+			return nil, err
+		}
+		thread = append(thread, *mail)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return thread, nil
+}
+
+// return amount of mails that were unread before
+func (s *Store) MarkRead(ids []uuid.UUID, recipient string) (int, error) {
+	queryScript := `
+		UPDATE mail SET read_at = NOW()
+		WHERE id = ANY($1::uuid[])
+		AND recipient = $2
+		AND read_at IS NULL
+	`
+
+	return s.updateMails(queryScript, ids, recipient)
+}
+
+// return amount of mails that were read before
+func (s *Store) MarkUnread(ids []uuid.UUID, recipient string) (int, error) {
+	queryScript := `
+		UPDATE mail SET read_at = NULL
+		WHERE id = ANY($1::uuid[])
+		AND recipient = $2
+		AND read_at IS NOT NULL
+	`
+
+	return s.updateMails(queryScript, ids, recipient)
+}
+
+func (s *Store) updateMails(queryScript string, ids []uuid.UUID, recipient string) (int, error) {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	result, err := s.db.Exec(queryScript, pq.Array(idStrings), recipient)
+	if err != nil {
+		return 0, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(updated), nil
+}
+
+// soft delete, the mail stays in trash of the recipient until purged
+func (s *Store) TrashMail(id uuid.UUID, recipient string) error {
+	queryScript := `
+		UPDATE mail SET deleted_at = NOW()
+		WHERE id = $1
+		AND recipient = $2
+		AND deleted_at IS NULL
+	`
+
+	result, err := s.db.Exec(queryScript, id, recipient)
+	if err != nil {
+		return err
+	}
+
+	return requireAffectedRow(result)
+}
+
+func (s *Store) RestoreMail(id uuid.UUID, recipient string) error {
+	queryScript := `
+		UPDATE mail SET deleted_at = NULL
+		WHERE id = $1
+		AND recipient = $2
+		AND deleted_at IS NOT NULL
+	`
+
+	result, err := s.db.Exec(queryScript, id, recipient)
+	if err != nil {
+		return err
+	}
+
+	return requireAffectedRow(result)
 }
 
-func (s *Store) InsertMail(mail model.Mail) (*model.MailEntity, error) {
+// permanently delete every trashed mail of the recipient
+func (s *Store) PurgeTrash(recipient string) (int, error) {
 	queryScript := `
-		INSERT INTO mail (recipient, sender, mail_subject, body)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
+		DELETE FROM mail
+		WHERE recipient = $1
+		AND deleted_at IS NOT NULL
 	`
 
-	var mailId uuid.UUID
-	err := s.db.QueryRow(
-		queryScript,
-		mail.To,
-		mail.From,
-		mail.Subject,
-		mail.Body,
-	).Scan(&mailId)
+	result, err := s.db.Exec(queryScript, recipient)
+	if err != nil {
+		return 0, err
+	}
 
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), nil
+}
+
+// permanently delete mails of every recipient trashed before the given time
+func (s *Store) PurgeTrashBefore(before time.Time) (int, error) {
+	queryScript := `
+		DELETE FROM mail
+		WHERE deleted_at IS NOT NULL
+		AND deleted_at < $1
+	`
+
+	result, err := s.db.Exec(queryScript, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(purged), nil
+}
+
+// no row updated means the mail does not exist, is not owned
+// by the recipient or is already in the requested state
+func requireAffectedRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// row is either *sql.Row or *sql.Rows, selected with mailColumns
+func scanMail(row interface{ Scan(dest ...any) error }) (*model.MailEntity, error) {
+	var mail model.MailEntity
+	err := row.Scan(
+		&mail.ID,
+		&mail.Recipient,
+		&mail.Sender,
+		&mail.MailSubject,
+		&mail.Body,
+		&mail.SentAt,
+		&mail.EphemeralKey,
+		&mail.SignedData,
+		&mail.Signature,
+		&mail.DeletedAt,
+		&mail.ReadAt,
+		&mail.InReplyTo,
+		&mail.ThreadID,
+		&mail.MessageID,
+		&mail.RecipientType,
+		pq.Array(&mail.ToRecipients),
+		pq.Array(&mail.CcRecipients),
+		&mail.Attachments,
+		&mail.AttachmentKeys,
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.MailEntity{
-		ID:          mailId,
-		Recipient:   mail.To,
-		Sender:      mail.From,
-		MailSubject: mail.Subject,
-		Body:        mail.Body,
-	}, nil
+	return &mail, nil
 }
@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"database/sql"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeleteMail(t *testing.T) {
+
+	const TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+
+	var (
+		testAccount *account.Account
+		err         error
+		testMailID  uuid.UUID
+
+		mockMailStore mailmock.MailStore
+		mailService   mail.MailService
+		errTrashMail  error
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+		testMailID = uuid.New()
+
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+
+		errTrashMail = nil
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	t.Run("should move mail of the user to trash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockMailStore.On("TrashMail", mock.Anything, mock.Anything).Return(errTrashMail)
+		message, newMsgErr := request.NewDeleteEmail(TestOrigin, testMailID)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		result, deleteErr := mailService.DeleteMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, deleteErr)
+		assert.Equal(t, testMailID, result.ID)
+		mockMailStore.AssertCalled(t, "TrashMail", testMailID, testAccount.GetAddress())
+	})
+
+	t.Run("should return mail not found when store updates no mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		errTrashMail = sql.ErrNoRows
+		mockMailStore.On("TrashMail", mock.Anything, mock.Anything).Return(errTrashMail)
+		message, newMsgErr := request.NewDeleteEmail(TestOrigin, testMailID)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, deleteErr := mailService.DeleteMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, deleteErr, "mail not found")
+	})
+
+}
@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"database/sql"
+	"io"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/blob"
+	blobmocks "passwordless-mail-server/pkg/blob/mocks"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetAttachment(t *testing.T) {
+
+	const (
+		TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+		TestHash       = "2f3ea5b9b5d8e6c6f3c1b0a0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0"
+	)
+
+	var (
+		testAccount *account.Account
+		err         error
+		testMailID  uuid.UUID
+		attachment  model.Attachment
+
+		mockMailStore mailmock.MailStore
+		mockBlobStore blobmocks.BlobStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+		testMailID = uuid.New()
+		attachment = model.Attachment{Hash: TestHash, Size: 20, MimeType: "text/plain"}
+
+		mockMailStore = mailmock.MailStore{}
+		mockBlobStore = blobmocks.BlobStore{}
+		mailService = mail.NewService(&mockMailStore, &mockBlobStore)
+
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	t.Run("should return attachment of a mail the user can read", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		user := testAccount.GetAddress()
+		mockMailStore.On("GetMail", testMailID, user).Return(&model.MailEntity{
+			ID:          testMailID,
+			Recipient:   user,
+			Attachments: model.Attachments{attachment},
+		}, nil)
+		mockBlobStore.On("Open", TestHash).Return(io.NopCloser(strings.NewReader("encrypted attachment")), nil)
+		message, newMsgErr := request.NewGetAttachment(TestOrigin, testMailID, TestHash)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		content, result, getErr := mailService.GetAttachment(requestBody, user)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, getErr)
+		assert.Equal(t, attachment, result)
+		stored, readErr := io.ReadAll(content)
+		assert.NoError(t, readErr)
+		assert.Equal(t, "encrypted attachment", string(stored))
+	})
+
+	t.Run("should return mail not found when user cannot see the mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockMailStore.On("GetMail", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+		message, newMsgErr := request.NewGetAttachment(TestOrigin, testMailID, TestHash)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, _, getErr := mailService.GetAttachment(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, getErr, "mail not found")
+		mockBlobStore.AssertNotCalled(t, "Open", mock.Anything)
+	})
+
+	t.Run("should return attachment not found when the mail does not reference the hash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		user := testAccount.GetAddress()
+		mockMailStore.On("GetMail", testMailID, user).Return(&model.MailEntity{ID: testMailID, Recipient: user}, nil)
+		message, newMsgErr := request.NewGetAttachment(TestOrigin, testMailID, TestHash)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, _, getErr := mailService.GetAttachment(requestBody, user)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, getErr, "attachment not found")
+		mockBlobStore.AssertNotCalled(t, "Open", mock.Anything)
+	})
+
+	t.Run("should return attachment not found when the blob is missing", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		user := testAccount.GetAddress()
+		mockMailStore.On("GetMail", testMailID, user).Return(&model.MailEntity{
+			ID:          testMailID,
+			Recipient:   user,
+			Attachments: model.Attachments{attachment},
+		}, nil)
+		mockBlobStore.On("Open", TestHash).Return(nil, blob.ErrNotFound)
+		message, newMsgErr := request.NewGetAttachment(TestOrigin, testMailID, TestHash)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, _, getErr := mailService.GetAttachment(requestBody, user)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, getErr, "attachment not found")
+	})
+}
@@ -3,12 +3,13 @@ package service_test
 import (
 	"passwordless-mail-client/pkg/account"
 	"passwordless-mail-client/pkg/request"
-	authmocks "passwordless-mail-server/pkg/auth/mocks"
 	"passwordless-mail-server/pkg/mail"
 	"passwordless-mail-server/pkg/mail/mocks"
 	mailmock "passwordless-mail-server/pkg/mail/mocks"
 	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -25,15 +26,12 @@ func TestGetInbox(t *testing.T) {
 		testUUID    uuid.UUID
 
 		mockMailStore        mailmock.MailStore
-		mockUUIDStore        authmocks.UuidStore
 		mailService          mail.MailService
 		resMailStoreGetInbox []model.MailEntity
 		errMailStoreGetInbox error
 		resMailStoreGetTotal int
 		errMailStoreGetTotal error
-		resUUIDStoreGetUUID  *model.UsedUUIDEntity
-		errUUIDStoreGetUUID  error
-		errInsertUsedUUID    error
+		resMailStoreUnread   int
 	)
 
 	beforeEach := func() {
@@ -48,8 +46,7 @@ func TestGetInbox(t *testing.T) {
 
 		// setup mail service
 		mockMailStore = mocks.MailStore{}
-		mockUUIDStore = authmocks.UuidStore{}
-		mailService = mail.NewService(&mockMailStore, &mockUUIDStore)
+		mailService = mail.NewService(&mockMailStore, nil)
 
 		resMailStoreGetInbox = []model.MailEntity{
 			{
@@ -58,31 +55,28 @@ func TestGetInbox(t *testing.T) {
 				Sender:      "sender",
 				MailSubject: "mail subject",
 				Body:        "mail body",
-				SentAt:      "2021-01-01 00:00:00",
+				SentAt:      "2021-01-01T00:00:00Z",
 			},
 		}
 		errMailStoreGetInbox = nil
-		resUUIDStoreGetUUID = nil
-		errUUIDStoreGetUUID = nil
-		errInsertUsedUUID = nil
+		resMailStoreGetTotal = 7
+		resMailStoreUnread = 2
 
 		mockMailStore.On("GetInbox", mock.Anything).Return(resMailStoreGetInbox, errMailStoreGetInbox)
 		mockMailStore.On("GetTotalMailsReceived", mock.Anything).Return(resMailStoreGetTotal, errMailStoreGetTotal)
-		mockUUIDStore.On("GetUsedUUID", mock.Anything).Return(resUUIDStoreGetUUID, errUUIDStoreGetUUID)
-		mockUUIDStore.On("InsertUsedUUID", mock.Anything).Return(errInsertUsedUUID)
+		mockMailStore.On("GetTotalUnread", mock.Anything).Return(resMailStoreUnread, nil)
 
 	}
 
 	t.Run("should have correct inbox query", func(t *testing.T) {
 		// Arrange
 		beforeEach()
-		message, newMsgErr := request.NewGetInbox()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
 		signedMassage, signErr := testAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
 			Signature: signedMassage,
 		}
-		publicKey := testAccount.PublicKey
 		serviceGetInboxQuery := mail.ServiceGetInboxQuery{
 			Recipient: testAccount.GetAddress(),
 			Page:      3,
@@ -90,7 +84,7 @@ func TestGetInbox(t *testing.T) {
 		}
 
 		// Act
-		mailService.GetInbox(requestBody, publicKey, serviceGetInboxQuery)
+		mailService.GetInbox(requestBody, serviceGetInboxQuery)
 
 		// Assert
 		assert.NoError(t, newMsgErr)
@@ -99,7 +93,87 @@ func TestGetInbox(t *testing.T) {
 			Recipient: testAccount.GetAddress(),
 			Offset:    20,
 			Limit:     10,
+			Order:     mail.NewestFirst,
 		}
 		mockMailStore.AssertCalled(t, "GetInbox", expectedStoreQuery)
 	})
+
+	t.Run("should return total and unread count of the recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		signedMassage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMassage,
+		}
+		query := mail.ServiceGetInboxQuery{Recipient: testAccount.GetAddress(), Page: 1, Limit: 10}
+
+		// Act
+		inbox, inboxErr := mailService.GetInbox(requestBody, query)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, inboxErr)
+		assert.Equal(t, 7, inbox.Total)
+		assert.Equal(t, 2, inbox.Unread)
+		mockMailStore.AssertCalled(t, "GetTotalUnread", testAccount.GetAddress())
+	})
+
+	t.Run("should page with decoded cursor and return cursors of the page", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		signedMassage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMassage,
+		}
+		cursor := mail.Cursor{SentAt: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), ID: uuid.New()}
+		query := mail.ServiceGetInboxQuery{
+			Recipient: testAccount.GetAddress(),
+			Page:      5,
+			Limit:     10,
+			Order:     mail.OldestFirst,
+			Before:    cursor.Encode(),
+		}
+
+		// Act
+		inbox, inboxErr := mailService.GetInbox(requestBody, query)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, inboxErr)
+		expectedStoreQuery := mail.StoreGetInboxQuery{
+			Recipient: testAccount.GetAddress(),
+			Limit:     10,
+			Order:     mail.OldestFirst,
+			Before:    &cursor,
+		}
+		mockMailStore.AssertCalled(t, "GetInbox", expectedStoreQuery)
+		pageCursor, decodeErr := mail.DecodeCursor(inbox.Before)
+		assert.NoError(t, decodeErr)
+		assert.Equal(t, testUUID, pageCursor.ID)
+		assert.Equal(t, inbox.Before, inbox.After)
+		assert.Equal(t, "2021-01-01T00:00:00Z", inbox.Inbox[0].SentAt)
+	})
+
+	t.Run("should return bad request when cursor is invalid", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetInbox(TestOrigin)
+		signedMassage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMassage,
+		}
+		query := mail.ServiceGetInboxQuery{Recipient: testAccount.GetAddress(), Limit: 10, After: "not-a-cursor"}
+
+		// Act
+		_, inboxErr := mailService.GetInbox(requestBody, query)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, inboxErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "GetInbox", mock.Anything)
+	})
+
 }
@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetMail(t *testing.T) {
+
+	const (
+		TestPrivateKey       = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+		TestSenderPrivateKey = "489bf3f950d71050677c53675d3d214d2a08af8453de702b972fa1b996c9ef79"
+	)
+
+	var (
+		recipientAccount *account.Account
+		senderAccount    *account.Account
+		err              error
+		storedMail       model.MailEntity
+
+		mockMailStore mailmock.MailStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		recipientAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+		senderAccount, err = account.ConnectAccount(TestSenderPrivateKey)
+		assert.NoError(t, err)
+
+		storedMail = model.MailEntity{
+			ID:        uuid.New(),
+			Recipient: recipientAccount.GetAddress(),
+			Sender:    senderAccount.GetAddress(),
+		}
+
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+
+		mockMailStore.On("MarkRead", mock.Anything, mock.Anything).Return(1, nil)
+	}
+
+	getMail := func(reader *account.Account) (model.Mail, error) {
+		message, err := request.NewGetEmail(TestOrigin, storedMail.ID)
+		if err != nil {
+			return model.Mail{}, err
+		}
+		signature, err := reader.Sign(message)
+		if err != nil {
+			return model.Mail{}, err
+		}
+		requestBody := model.RequestBody{Data: string(message), Signature: signature}
+
+		return mailService.GetMail(requestBody, reader.GetAddress())
+	}
+
+	t.Run("should mark unread mail as read when recipient opens it", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockMailStore.On("GetMail", mock.Anything, mock.Anything).Return(&storedMail, nil)
+
+		// Act
+		result, getErr := getMail(recipientAccount)
+
+		// Assert
+		assert.NoError(t, getErr)
+		assert.NotNil(t, result.ReadAt)
+		mockMailStore.AssertCalled(t, "MarkRead", []uuid.UUID{storedMail.ID}, recipientAccount.GetAddress())
+	})
+
+	t.Run("should not mark mail as read when sender opens it", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockMailStore.On("GetMail", mock.Anything, mock.Anything).Return(&storedMail, nil)
+
+		// Act
+		result, getErr := getMail(senderAccount)
+
+		// Assert
+		assert.NoError(t, getErr)
+		assert.Nil(t, result.ReadAt)
+		mockMailStore.AssertNotCalled(t, "MarkRead", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should keep first read time when mail was already read", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		readAt := "2026-01-01T00:00:00Z"
+		storedMail.ReadAt = &readAt
+		mockMailStore.On("GetMail", mock.Anything, mock.Anything).Return(&storedMail, nil)
+
+		// Act
+		result, getErr := getMail(recipientAccount)
+
+		// Assert
+		util.AssertNoAnyError(t, getErr)
+		assert.Equal(t, &readAt, result.ReadAt)
+		mockMailStore.AssertNotCalled(t, "MarkRead", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,96 @@
+package service_test
+
+import (
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetSent(t *testing.T) {
+
+	const TestPrivateKey = "489bf3f950d71050677c53675d3d214d2a08af8453de702b972fa1b996c9ef79"
+
+	var (
+		testAccount *account.Account
+		err         error
+		readAt      string
+
+		mockMailStore mailmock.MailStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+
+		readAt = "2026-01-01T00:00:00Z"
+		sent := []model.MailEntity{
+			{
+				ID:        uuid.New(),
+				Recipient: "recipient",
+				Sender:    testAccount.GetAddress(),
+				ReadAt:    &readAt,
+			},
+		}
+		mockMailStore.On("GetSent", mock.Anything).Return(sent, nil)
+		mockMailStore.On("GetTotalMailsSent", mock.Anything).Return(11, nil)
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	t.Run("should have correct sent query and total", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetSent(TestOrigin)
+		requestBody, signErr := signedRequest(message)
+		query := mail.ServiceGetSentQuery{Sender: testAccount.GetAddress(), Page: 3, Limit: 10}
+
+		// Act
+		result, sentErr := mailService.GetSent(requestBody, query)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, sentErr)
+		expectedStoreQuery := mail.StoreGetSentQuery{
+			Sender: testAccount.GetAddress(),
+			Offset: 20,
+			Limit:  10,
+		}
+		mockMailStore.AssertCalled(t, "GetSent", expectedStoreQuery)
+		mockMailStore.AssertCalled(t, "GetTotalMailsSent", testAccount.GetAddress())
+		assert.Equal(t, 11, result.Total)
+		assert.Equal(t, 1, len(result.Sent))
+	})
+
+	t.Run("should not show read state of the recipient to the sender", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewGetSent(TestOrigin)
+		requestBody, signErr := signedRequest(message)
+		query := mail.ServiceGetSentQuery{Sender: testAccount.GetAddress(), Page: 1, Limit: 10}
+
+		// Act
+		result, sentErr := mailService.GetSent(requestBody, query)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, sentErr)
+		assert.Nil(t, result.Sent[0].ReadAt)
+	})
+
+}
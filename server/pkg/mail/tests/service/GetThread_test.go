@@ -0,0 +1,93 @@
+package service_test
+
+import (
+	"database/sql"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetThread(t *testing.T) {
+
+	const TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+
+	var (
+		testAccount *account.Account
+		err         error
+		testMailID  uuid.UUID
+		threadID    uuid.UUID
+
+		mockMailStore mailmock.MailStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+		testMailID = uuid.New()
+		threadID = uuid.New()
+
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	t.Run("should return thread of the mail and hide read state of sent mails", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		user := testAccount.GetAddress()
+		readAt := "2026-01-01T00:00:00Z"
+		thread := []model.MailEntity{
+			{ID: uuid.New(), Sender: user, Recipient: "other", ThreadID: threadID, ReadAt: &readAt},
+			{ID: testMailID, Sender: "other", Recipient: user, ThreadID: threadID, ReadAt: &readAt},
+		}
+		mockMailStore.On("GetMail", testMailID, user).Return(&thread[1], nil)
+		mockMailStore.On("GetThread", threadID, user).Return(thread, nil)
+		message, newMsgErr := request.NewGetThread(TestOrigin, testMailID)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		result, threadErr := mailService.GetThread(requestBody, user)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, threadErr)
+		assert.Equal(t, threadID, result.ThreadID)
+		assert.Equal(t, 2, len(result.Mails))
+		assert.Equal(t, thread[0].ID, result.Mails[0].ID)
+		assert.Nil(t, result.Mails[0].ReadAt)
+		assert.Equal(t, &readAt, result.Mails[1].ReadAt)
+	})
+
+	t.Run("should return mail not found when user cannot see the mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockMailStore.On("GetMail", mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+		message, newMsgErr := request.NewGetThread(TestOrigin, testMailID)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, threadErr := mailService.GetThread(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, threadErr, "mail not found")
+		mockMailStore.AssertNotCalled(t, "GetThread", mock.Anything, mock.Anything)
+	})
+
+}
@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"encoding/json"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMarkRead(t *testing.T) {
+
+	const TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+
+	var (
+		testAccount *account.Account
+		err         error
+
+		mockMailStore mailmock.MailStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+
+		mockMailStore.On("MarkRead", mock.Anything, mock.Anything).Return(2, nil)
+		mockMailStore.On("MarkUnread", mock.Anything, mock.Anything).Return(1, nil)
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	t.Run("should mark every requested mail of the user as read", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		ids := []uuid.UUID{uuid.New(), uuid.New()}
+		message, newMsgErr := request.NewMarkRead(TestOrigin, ids)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		result, markErr := mailService.MarkRead(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, markErr)
+		assert.Equal(t, 2, result.Updated)
+		mockMailStore.AssertCalled(t, "MarkRead", ids, testAccount.GetAddress())
+	})
+
+	t.Run("should mark every requested mail of the user as unread", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		ids := []uuid.UUID{uuid.New()}
+		message, newMsgErr := request.NewMarkUnread(TestOrigin, ids)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		result, markErr := mailService.MarkUnread(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, markErr)
+		assert.Equal(t, 1, result.Updated)
+		mockMailStore.AssertCalled(t, "MarkUnread", ids, testAccount.GetAddress())
+	})
+
+	t.Run("should return bad request when too many mails are requested", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		ids := make([]uuid.UUID, request.MaxMarkEmails+1)
+		for i := range ids {
+			ids[i] = uuid.New()
+		}
+		message, marshalErr := json.Marshal(request.MarkEmailsRequest{
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.MarkRead,
+				Origin:    TestOrigin,
+				ID:        uuid.New(),
+				Timestamp: time.Now().Format(time.RFC3339),
+			},
+			EmailIDs: ids,
+		})
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, markErr := mailService.MarkRead(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, marshalErr, signErr)
+		assert.EqualError(t, markErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "MarkRead", mock.Anything, mock.Anything)
+	})
+}
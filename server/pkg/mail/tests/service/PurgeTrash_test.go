@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPurgeTrash(t *testing.T) {
+
+	const TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+
+	var (
+		testAccount *account.Account
+		err         error
+
+		mockMailStore mailmock.MailStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+
+		mockMailStore.On("PurgeTrash", mock.Anything).Return(3, nil)
+		mockMailStore.On("PurgeTrashBefore", mock.Anything).Return(5, nil)
+	}
+
+	t.Run("should purge trash of the user and return purged amount", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewPurgeTrash(TestOrigin)
+		signature, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{Data: string(message), Signature: signature}
+
+		// Act
+		result, purgeErr := mailService.PurgeTrash(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, purgeErr)
+		assert.Equal(t, 3, result.Purged)
+		mockMailStore.AssertCalled(t, "PurgeTrash", testAccount.GetAddress())
+	})
+
+	t.Run("should purge mails trashed before retention period", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		retention := 24 * time.Hour
+		expectedBefore := time.Now().Add(-retention)
+
+		// Act
+		purged, purgeErr := mailService.PurgeExpiredTrash(retention)
+
+		// Assert
+		assert.NoError(t, purgeErr)
+		assert.Equal(t, 5, purged)
+		before := mockMailStore.Calls[0].Arguments.Get(0).(time.Time)
+		assert.WithinDuration(t, expectedBefore, before, time.Second)
+	})
+}
@@ -0,0 +1,73 @@
+package service_test
+
+import (
+	"database/sql"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRestoreMail(t *testing.T) {
+
+	const TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+
+	var (
+		testAccount *account.Account
+		err         error
+		testMailID  uuid.UUID
+
+		mockMailStore mailmock.MailStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+		testMailID = uuid.New()
+
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+
+	}
+
+	t.Run("should restore mail of the user from trash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockMailStore.On("RestoreMail", mock.Anything, mock.Anything).Return(nil)
+		message, newMsgErr := request.NewRestoreEmail(TestOrigin, testMailID)
+		signature, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{Data: string(message), Signature: signature}
+
+		// Act
+		result, restoreErr := mailService.RestoreMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, restoreErr)
+		assert.Equal(t, testMailID, result.ID)
+		mockMailStore.AssertCalled(t, "RestoreMail", testMailID, testAccount.GetAddress())
+	})
+
+	t.Run("should return mail not found when mail is not in trash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockMailStore.On("RestoreMail", mock.Anything, mock.Anything).Return(sql.ErrNoRows)
+		message, newMsgErr := request.NewRestoreEmail(TestOrigin, testMailID)
+		signature, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{Data: string(message), Signature: signature}
+
+		// Act
+		_, restoreErr := mailService.RestoreMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, restoreErr, "mail not found")
+	})
+}
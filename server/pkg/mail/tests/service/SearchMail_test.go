@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"encoding/json"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSearchMail(t *testing.T) {
+
+	const (
+		TestPrivateKey       = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+		TestSenderPrivateKey = "489bf3f950d71050677c53675d3d214d2a08af8453de702b972fa1b996c9ef79"
+	)
+
+	var (
+		testAccount   *account.Account
+		senderAccount *account.Account
+		err           error
+
+		mockMailStore mailmock.MailStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+		senderAccount, err = account.ConnectAccount(TestSenderPrivateKey)
+		assert.NoError(t, err)
+
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+
+		result := mail.StoreSearchMailResult{
+			Mails:  []model.MailEntity{{ID: uuid.New(), Sender: senderAccount.GetAddress()}},
+			Total:  4,
+			Unread: 1,
+		}
+		mockMailStore.On("SearchMail", mock.Anything).Return(result, nil)
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	t.Run("should search inbox of the user with signed filters", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewSearchEmail(TestOrigin, senderAccount.GetAddress(), "2026-01-01T00:00:00Z", "2026-02-01T00:00:00Z")
+		requestBody, signErr := signedRequest(message)
+		query := mail.ServiceSearchMailQuery{Recipient: testAccount.GetAddress(), Page: 2, Limit: 10}
+
+		// Act
+		result, searchErr := mailService.SearchMail(requestBody, query)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, searchErr)
+		since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		until := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+		expectedStoreQuery := mail.StoreSearchMailQuery{
+			Recipient: testAccount.GetAddress(),
+			Sender:    senderAccount.GetAddress(),
+			Since:     &since,
+			Until:     &until,
+			Offset:    10,
+			Limit:     10,
+		}
+		mockMailStore.AssertCalled(t, "SearchMail", expectedStoreQuery)
+		assert.Equal(t, 4, result.Total)
+		assert.Equal(t, 1, result.Unread)
+		assert.Equal(t, 1, len(result.Inbox))
+	})
+
+	t.Run("should return bad request when sender filter is not canonical", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, marshalErr := json.Marshal(request.SearchEmailRequest{
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.SearchEmail,
+				Origin:    TestOrigin,
+				ID:        uuid.New(),
+				Timestamp: time.Now().Format(time.RFC3339),
+			},
+			From: strings.ToUpper(senderAccount.GetAddress()),
+		})
+		requestBody, signErr := signedRequest(message)
+		query := mail.ServiceSearchMailQuery{Recipient: testAccount.GetAddress(), Page: 1, Limit: 10}
+
+		// Act
+		_, searchErr := mailService.SearchMail(requestBody, query)
+
+		// Assert
+		util.AssertNoAnyError(t, marshalErr, signErr)
+		assert.EqualError(t, searchErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "SearchMail", mock.Anything)
+	})
+
+	t.Run("should return bad request when time filter is not RFC3339", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, marshalErr := json.Marshal(request.SearchEmailRequest{
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.SearchEmail,
+				Origin:    TestOrigin,
+				ID:        uuid.New(),
+				Timestamp: time.Now().Format(time.RFC3339),
+			},
+			Since: "last week",
+		})
+		requestBody, signErr := signedRequest(message)
+		query := mail.ServiceSearchMailQuery{Recipient: testAccount.GetAddress(), Page: 1, Limit: 10}
+
+		// Act
+		_, searchErr := mailService.SearchMail(requestBody, query)
+
+		// Assert
+		util.AssertNoAnyError(t, marshalErr, signErr)
+		assert.EqualError(t, searchErr, "bad request")
+	})
+}
@@ -1,19 +1,387 @@
 package service_test
 
 import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"passwordless-mail-client/pkg/account"
+	clientmodel "passwordless-mail-client/pkg/model"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/blob"
+	blobmocks "passwordless-mail-server/pkg/blob/mocks"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestSendMail(t *testing.T) {
 
-	// const TestPrivateKey = "489bf3f950d71050677c53675d3d214d2a08af8453de702b972fa1b996c9ef79"
+	const (
+		TestPrivateKey          = "489bf3f950d71050677c53675d3d214d2a08af8453de702b972fa1b996c9ef79"
+		TestRecipientPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+		TestOtherPrivateKey     = "923cebb3d8809d3caf09faa74ae2a39c23824a6fe75c44cab2a73dc6a0f3b606"
+	)
+
+	var (
+		testAccount      *account.Account
+		recipientAccount *account.Account
+		otherAccount     *account.Account
+		err              error
+
+		mockMailStore mailmock.MailStore
+		mockBlobStore blobmocks.BlobStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+		recipientAccount, err = account.ConnectAccount(TestRecipientPrivateKey)
+		assert.NoError(t, err)
+		otherAccount, err = account.ConnectAccount(TestOtherPrivateKey)
+		assert.NoError(t, err)
+
+		mockMailStore = mailmock.MailStore{}
+		mockBlobStore = blobmocks.BlobStore{}
+		mailService = mail.NewService(&mockMailStore, &mockBlobStore)
+
+		mockMailStore.On("InsertMail", mock.Anything).Return([]model.MailEntity{{ID: uuid.New()}}, nil)
+	}
+
+	t.Run("should store encrypted mail and the sender signed request as they are", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewSendEmail(TestOrigin, recipientAccount.GetAddress(), "test subject", "test body")
+		signedMessage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMessage,
+		}
+		var sendEmail request.SendEmailRequest
+		unmarshalErr := json.Unmarshal(message, &sendEmail)
+
+		// Act
+		_, sendErr := mailService.SendMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		assert.NoError(t, newMsgErr)
+		assert.NoError(t, signErr)
+		assert.NoError(t, unmarshalErr)
+		assert.NoError(t, sendErr)
+		expectedMail := model.OutgoingMail{
+			MessageID: sendEmail.MessageID,
+			From:      testAccount.GetAddress(),
+			To:        []string{recipientAccount.GetAddress()},
+			Cc:        []string{},
+			Deliveries: []model.Delivery{{
+				Recipient:    recipientAccount.GetAddress(),
+				Type:         model.RecipientTo,
+				EphemeralKey: sendEmail.Deliveries[0].EphemeralKey,
+				Subject:      sendEmail.Deliveries[0].Subject,
+				Body:         sendEmail.Deliveries[0].Body,
+			}},
+			SignedData: requestBody.Data,
+			Signature:  requestBody.Signature,
+		}
+		mockMailStore.AssertCalled(t, "InsertMail", expectedMail)
+	})
+
+	t.Run("should return bad request when mail has no ephemeral key", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		sendEmail := request.SendEmailRequest{
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.SendEmail,
+				Origin:    TestOrigin,
+				ID:        uuid.New(),
+				Timestamp: time.Now().Format(time.RFC3339),
+			},
+			MessageID: uuid.New(),
+			To:        []string{recipientAccount.GetAddress()},
+			Deliveries: []request.Delivery{{
+				Recipient: recipientAccount.GetAddress(),
+				Subject:   "plaintext subject",
+				Body:      "plaintext body",
+			}},
+		}
+		message, marshalErr := json.Marshal(sendEmail)
+		signedMessage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMessage,
+		}
+
+		// Act
+		_, sendErr := mailService.SendMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		assert.NoError(t, marshalErr)
+		assert.NoError(t, signErr)
+		assert.EqualError(t, sendErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "InsertMail", mock.Anything)
+	})
+	t.Run("should return bad request when recipient address is not canonical", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		sealed, encryptErr := account.EncryptMail(recipientAccount.PublicKey, "test subject", "test body")
+		sendEmail := request.SendEmailRequest{
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.SendEmail,
+				Origin:    TestOrigin,
+				ID:        uuid.New(),
+				Timestamp: time.Now().Format(time.RFC3339),
+			},
+			MessageID: uuid.New(),
+			To:        []string{strings.ToUpper(recipientAccount.GetAddress())},
+			Deliveries: []request.Delivery{{
+				Recipient:    strings.ToUpper(recipientAccount.GetAddress()),
+				EphemeralKey: sealed.EphemeralKey,
+				Subject:      sealed.Subject,
+				Body:         sealed.Body,
+			}},
+		}
+		message, marshalErr := json.Marshal(sendEmail)
+		signedMessage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMessage,
+		}
+
+		// Act
+		_, sendErr := mailService.SendMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, encryptErr, marshalErr, signErr)
+		assert.EqualError(t, sendErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "InsertMail", mock.Anything)
+	})
+	t.Run("should store reply in the thread of the original mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		originalID := uuid.New()
+		threadID := uuid.New()
+		mockMailStore.On("GetMail", originalID, testAccount.GetAddress()).Return(&model.MailEntity{ID: originalID, ThreadID: threadID}, nil)
+		message, newMsgErr := request.NewReplyEmail(TestOrigin, recipientAccount.GetAddress(), "re: test subject", "test body", originalID)
+		signedMessage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMessage,
+		}
+
+		// Act
+		_, sendErr := mailService.SendMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, sendErr)
+		mockMailStore.AssertCalled(t, "InsertMail", mock.MatchedBy(func(mail model.OutgoingMail) bool {
+			return mail.InReplyTo != nil && *mail.InReplyTo == originalID && mail.ThreadID == threadID
+		}))
+	})
+
+	t.Run("should return bad request when replying to a mail the sender cannot see", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		originalID := uuid.New()
+		mockMailStore.On("GetMail", originalID, testAccount.GetAddress()).Return(nil, sql.ErrNoRows)
+		message, newMsgErr := request.NewReplyEmail(TestOrigin, recipientAccount.GetAddress(), "re: test subject", "test body", originalID)
+		signedMessage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMessage,
+		}
+
+		// Act
+		_, sendErr := mailService.SendMail(requestBody, testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr)
+		assert.EqualError(t, sendErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "InsertMail", mock.Anything)
+	})
+	signedRequest := func(message []byte) model.RequestBody {
+		signedMessage, err := testAccount.Sign(message)
+		assert.NoError(t, err)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signedMessage,
+		}
+	}
+
+	t.Run("should store one delivery per to and cc recipient and bcc copies separately", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		recipients := request.Recipients{
+			To:  []string{recipientAccount.GetAddress()},
+			Cc:  []string{testAccount.GetAddress()},
+			Bcc: []string{otherAccount.GetAddress()},
+		}
+		messages, newMsgErr := request.NewSendEmails(TestOrigin, recipients, "test subject", "test body", nil)
+
+		// Act
+		_, sendErr := mailService.SendMail(signedRequest(messages[0]), testAccount.GetAddress())
+		_, sendBccErr := mailService.SendMail(signedRequest(messages[1]), testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, sendErr, sendBccErr)
+		deliveryTypes := func(mail model.OutgoingMail) map[string]model.RecipientType {
+			types := map[string]model.RecipientType{}
+			for _, delivery := range mail.Deliveries {
+				types[delivery.Recipient] = delivery.Type
+			}
+			return types
+		}
+		mockMailStore.AssertCalled(t, "InsertMail", mock.MatchedBy(func(mail model.OutgoingMail) bool {
+			types := deliveryTypes(mail)
+			return len(types) == 2 &&
+				types[recipientAccount.GetAddress()] == model.RecipientTo &&
+				types[testAccount.GetAddress()] == model.RecipientCc
+		}))
+		mockMailStore.AssertCalled(t, "InsertMail", mock.MatchedBy(func(mail model.OutgoingMail) bool {
+			types := deliveryTypes(mail)
+			return len(types) == 1 && types[otherAccount.GetAddress()] == model.RecipientBcc
+		}))
+	})
+
+	t.Run("should return bad request when a delivery is missing", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		recipients := request.Recipients{
+			To: []string{recipientAccount.GetAddress(), otherAccount.GetAddress()},
+		}
+		messages, newMsgErr := request.NewSendEmails(TestOrigin, recipients, "test subject", "test body", nil)
+		var sendEmail request.SendEmailRequest
+		unmarshalErr := json.Unmarshal(messages[0], &sendEmail)
+		sendEmail.Deliveries = sendEmail.Deliveries[:1]
+		message, marshalErr := json.Marshal(sendEmail)
+
+		// Act
+		_, sendErr := mailService.SendMail(signedRequest(message), testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, unmarshalErr, marshalErr)
+		assert.EqualError(t, sendErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "InsertMail", mock.Anything)
+	})
+
+	t.Run("should return bad request when one request reveals more than one bcc recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		recipients := request.Recipients{
+			Bcc: []string{recipientAccount.GetAddress(), otherAccount.GetAddress()},
+		}
+		messages, newMsgErr := request.NewSendEmails(TestOrigin, recipients, "test subject", "test body", nil)
+		var first, second request.SendEmailRequest
+		unmarshalErr1 := json.Unmarshal(messages[0], &first)
+		unmarshalErr2 := json.Unmarshal(messages[1], &second)
+		first.Bcc = append(first.Bcc, second.Bcc...)
+		first.Deliveries = append(first.Deliveries, second.Deliveries...)
+		message, marshalErr := json.Marshal(first)
+
+		// Act
+		_, sendErr := mailService.SendMail(signedRequest(message), testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, unmarshalErr1, unmarshalErr2, marshalErr)
+		assert.EqualError(t, sendErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "InsertMail", mock.Anything)
+	})
+
+	t.Run("should return bad request when message id conflicts with stored mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		mockMailStore = mailmock.MailStore{}
+		mailService = mail.NewService(&mockMailStore, nil)
+		mockMailStore.On("InsertMail", mock.Anything).Return(nil, mail.ErrMessageConflict)
+		message, newMsgErr := request.NewSendEmail(TestOrigin, recipientAccount.GetAddress(), "test subject", "test body")
+
+		// Act
+		_, sendErr := mailService.SendMail(signedRequest(message), testAccount.GetAddress())
+
+		// Assert
+		assert.NoError(t, newMsgErr)
+		assert.EqualError(t, sendErr, "bad request")
+	})
+
+	// mail to the recipient with one uploaded attachment of size bytes
+	newAttachmentMail := func(size int64) ([]byte, string, error) {
+		sum := sha256.Sum256([]byte("test attachment"))
+		hash := hex.EncodeToString(sum[:])
+		attachments := []request.OutgoingAttachment{{
+			Attachment: clientmodel.Attachment{Hash: hash, Size: size, MimeType: "text/plain"},
+			Name:       "test.txt",
+			Key:        []byte("test attachment key"),
+		}}
+		recipients := request.Recipients{To: []string{recipientAccount.GetAddress()}}
+		messages, err := request.NewSendEmailsWithAttachments(TestOrigin, recipients, "test subject", "test body", nil, attachments)
+		if err != nil {
+			return nil, "", err
+		}
+		return messages[0], hash, nil
+	}
+
+	t.Run("should store attachments uploaded with the signed size", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, hash, newMsgErr := newAttachmentMail(15)
+		mockBlobStore.On("Size", hash).Return(int64(15), nil)
+
+		// Act
+		_, sendErr := mailService.SendMail(signedRequest(message), testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, sendErr)
+		mockMailStore.AssertCalled(t, "InsertMail", mock.MatchedBy(func(mail model.OutgoingMail) bool {
+			return len(mail.Attachments) == 1 &&
+				mail.Attachments[0] == model.Attachment{Hash: hash, Size: 15, MimeType: "text/plain"} &&
+				mail.Deliveries[0].AttachmentKeys != ""
+		}))
+	})
+
+	t.Run("should return bad request when attachment is not uploaded or has another size", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		notUploaded, hash, newMsgErr1 := newAttachmentMail(15)
+		otherSize, _, newMsgErr2 := newAttachmentMail(16)
+		mockBlobStore.On("Size", hash).Return(int64(0), blob.ErrNotFound).Once()
+		mockBlobStore.On("Size", hash).Return(int64(15), nil).Once()
+
+		// Act
+		_, notUploadedErr := mailService.SendMail(signedRequest(notUploaded), testAccount.GetAddress())
+		_, otherSizeErr := mailService.SendMail(signedRequest(otherSize), testAccount.GetAddress())
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr1, newMsgErr2)
+		assert.EqualError(t, notUploadedErr, "bad request")
+		assert.EqualError(t, otherSizeErr, "bad request")
+		mockMailStore.AssertNotCalled(t, "InsertMail", mock.Anything)
+	})
 
-	// var (
-	// 	testAccount *account.Account
-	// 	err         error
-	// )
+	t.Run("should return bad request when a delivery has no attachment keys", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, _, newMsgErr := newAttachmentMail(15)
+		var sendEmail request.SendEmailRequest
+		unmarshalErr := json.Unmarshal(message, &sendEmail)
+		sendEmail.Deliveries[0].AttachmentKeys = ""
+		message, marshalErr := json.Marshal(sendEmail)
 
-	// beforeEach := func() {}
+		// Act
+		_, sendErr := mailService.SendMail(signedRequest(message), testAccount.GetAddress())
 
-	// t.Run("TestSendMail", func(t *testing.T) {})
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, unmarshalErr, marshalErr)
+		assert.EqualError(t, sendErr, "bad request")
+		mockBlobStore.AssertNotCalled(t, "Size", mock.Anything)
+	})
 }
@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"encoding/json"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	blobmocks "passwordless-mail-server/pkg/blob/mocks"
+	"passwordless-mail-server/pkg/mail"
+	mailmock "passwordless-mail-server/pkg/mail/mocks"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUploadAttachment(t *testing.T) {
+
+	const TestPrivateKey = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+
+	var (
+		testAccount *account.Account
+		err         error
+
+		mockMailStore mailmock.MailStore
+		mockBlobStore blobmocks.BlobStore
+		mailService   mail.MailService
+	)
+
+	beforeEach := func() {
+		testAccount, err = account.ConnectAccount(TestPrivateKey)
+		assert.NoError(t, err)
+
+		mockMailStore = mailmock.MailStore{}
+		mockBlobStore = blobmocks.BlobStore{}
+		mailService = mail.NewService(&mockMailStore, &mockBlobStore)
+
+	}
+
+	signedRequest := func(message []byte) (model.RequestBody, error) {
+		signature, err := testAccount.Sign(message)
+		return model.RequestBody{
+			Data:      string(message),
+			Signature: signature,
+		}, err
+	}
+
+	t.Run("should store uploaded content and return its hash and size", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		content := []byte("encrypted attachment")
+		mockBlobStore.On("Put", content).Return("test hash", nil)
+		message, newMsgErr := request.NewUploadAttachment(TestOrigin, content)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		result, uploadErr := mailService.UploadAttachment(requestBody)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, signErr, uploadErr)
+		assert.Equal(t, model.UploadAttachmentResponse{Hash: "test hash", Size: int64(len(content))}, result)
+	})
+
+	t.Run("should return bad request when content is over the size limit", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		message, newMsgErr := request.NewUploadAttachment(TestOrigin, []byte("encrypted attachment"))
+		var upload request.UploadAttachmentRequest
+		unmarshalErr := json.Unmarshal(message, &upload)
+		upload.Content = make([]byte, request.MaxAttachmentSize+1)
+		message, marshalErr := json.Marshal(upload)
+		requestBody, signErr := signedRequest(message)
+
+		// Act
+		_, uploadErr := mailService.UploadAttachment(requestBody)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, unmarshalErr, marshalErr, signErr)
+		assert.EqualError(t, uploadErr, "bad request")
+		mockBlobStore.AssertNotCalled(t, "Put", mock.Anything)
+	})
+
+}
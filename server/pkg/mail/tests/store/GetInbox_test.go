@@ -3,7 +3,10 @@ package store_test
 import (
 	"fmt"
 	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -12,7 +15,7 @@ func TestGetInbox(t *testing.T) {
 	var store mail.MailStore
 
 	beforeEach := func() {
-		store = mail.NewStore(testDatabase.DB)
+		store = newStore()
 	}
 
 	afterEach := func() {
@@ -93,4 +96,91 @@ func TestGetInbox(t *testing.T) {
 		assert.Equal(t, 3, len(inbox1))
 		assert.Equal(t, 0, len(inbox2))
 	})
+
+	// recipient-1 gets sender-1 .. sender-5, one minute apart, sender-5 is newest
+	insertTimedMails := func() error {
+		base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 5; i++ {
+			_, err := testDatabase.DB.Exec(
+				"INSERT INTO mail (recipient, sender, mail_subject, body, sent_at) VALUES ($1, $2, $3, $4, $5)",
+				"recipient-1", fmt.Sprintf("sender-%d", i+1), "subject", "body", base.Add(time.Duration(i)*time.Minute),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	senders := func(inbox []model.MailEntity) []string {
+		var result []string
+		for _, mail := range inbox {
+			result = append(result, mail.Sender)
+		}
+		return result
+	}
+
+	t.Run("should return newest mail first by default and oldest first when configured", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertTimedMails()
+
+		// Act
+		newest, newestErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient-1", Limit: 3})
+		oldest, oldestErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient-1", Limit: 3, Order: mail.OldestFirst})
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, newestErr, oldestErr)
+		assert.Equal(t, []string{"sender-5", "sender-4", "sender-3"}, senders(newest))
+		assert.Equal(t, []string{"sender-1", "sender-2", "sender-3"}, senders(oldest))
+		assert.NotEmpty(t, newest[0].SentAt)
+	})
+
+	t.Run("should return mails next to before and after cursor", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertTimedMails()
+		all, allErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient-1", Limit: 5})
+		middle, parseErr := time.Parse(time.RFC3339Nano, all[2].SentAt)
+		cursor := &mail.Cursor{SentAt: middle, ID: all[2].ID}
+
+		// Act
+		older, olderErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient-1", Limit: 1, Before: cursor})
+		newer, newerErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient-1", Limit: 2, After: cursor})
+		newerOldestFirst, ascErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient-1", Limit: 1, After: cursor, Order: mail.OldestFirst})
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, allErr, parseErr, olderErr, newerErr, ascErr)
+		assert.Equal(t, []string{"sender-2"}, senders(older))
+		assert.Equal(t, []string{"sender-5", "sender-4"}, senders(newer))
+		assert.Equal(t, []string{"sender-4"}, senders(newerOldestFirst))
+	})
+
+	t.Run("should keep cursor page stable when new mail arrives", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertTimedMails()
+		firstPage, firstErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient-1", Limit: 2})
+		last := firstPage[len(firstPage)-1]
+		lastSentAt, parseErr := time.Parse(time.RFC3339Nano, last.SentAt)
+		_, newMailErr := testDatabase.DB.Exec(
+			"INSERT INTO mail (recipient, sender, mail_subject, body) VALUES ($1, $2, $3, $4)",
+			"recipient-1", "sender-new", "subject", "body",
+		)
+
+		// Act
+		secondPage, secondErr := store.GetInbox(mail.StoreGetInboxQuery{
+			Recipient: "recipient-1",
+			Limit:     2,
+			Before:    &mail.Cursor{SentAt: lastSentAt, ID: last.ID},
+		})
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, firstErr, parseErr, newMailErr, secondErr)
+		assert.Equal(t, []string{"sender-5", "sender-4"}, senders(firstPage))
+		assert.Equal(t, []string{"sender-3", "sender-2"}, senders(secondPage))
+	})
 }
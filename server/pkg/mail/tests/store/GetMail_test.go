@@ -17,7 +17,7 @@ func TestGetMail(t *testing.T) {
 	)
 
 	beforeEach := func() {
-		store = mail.NewStore(testDatabase.DB)
+		store = newStore()
 	}
 
 	afterEach := func() {
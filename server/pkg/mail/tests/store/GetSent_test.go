@@ -0,0 +1,122 @@
+package store_test
+
+import (
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSent(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should return empty array when sender has not sent any mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(2), testDatabase.DB)
+
+		// Act
+		sent, err := store.GetSent(mail.StoreGetSentQuery{Sender: "random-sender", Offset: 0, Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(sent))
+	})
+
+	t.Run("should return mails sent by the sender including mails trashed by recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		mockMails := mockMail(3)
+		mockMails[0].Sender = "sender-1"
+		mockMails[1].Sender = "sender-1"
+		insertMails(mockMails, testDatabase.DB)
+		for _, mail := range retrieveMails(testDatabase.DB) {
+			if mail.Sender == "sender-1" {
+				assert.NoError(t, store.TrashMail(mail.ID, mail.Recipient))
+				break
+			}
+		}
+
+		// Act
+		sent, err := store.GetSent(mail.StoreGetSentQuery{Sender: "sender-1", Offset: 0, Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(sent))
+		for _, mail := range sent {
+			assert.Equal(t, "sender-1", mail.Sender)
+		}
+	})
+
+	t.Run("should return sent mails with correct limit and offset", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		mockMails := mockMail(7)
+		for i := range mockMails {
+			mockMails[i].Sender = "sender-1"
+		}
+		insertMails(mockMails, testDatabase.DB)
+
+		// Act
+		firstPage, firstErr := store.GetSent(mail.StoreGetSentQuery{Sender: "sender-1", Offset: 0, Limit: 5})
+		secondPage, secondErr := store.GetSent(mail.StoreGetSentQuery{Sender: "sender-1", Offset: 5, Limit: 5})
+
+		// Assert
+		assert.NoError(t, firstErr)
+		assert.NoError(t, secondErr)
+		assert.Equal(t, 5, len(firstPage))
+		assert.Equal(t, 2, len(secondPage))
+	})
+	t.Run("should return one mail per message sent to many recipients", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		messageID := uuid.New()
+		outgoing := model.OutgoingMail{
+			MessageID: messageID,
+			From:      "sender-1",
+			To:        []string{"recipient-1"},
+			Cc:        []string{"recipient-2"},
+			Deliveries: []model.Delivery{
+				{Recipient: "recipient-1", Type: model.RecipientTo},
+				{Recipient: "recipient-2", Type: model.RecipientCc},
+			},
+		}
+		bcc := model.OutgoingMail{
+			MessageID:  messageID,
+			From:       "sender-1",
+			To:         []string{"recipient-1"},
+			Cc:         []string{"recipient-2"},
+			Deliveries: []model.Delivery{{Recipient: "recipient-3", Type: model.RecipientBcc}},
+		}
+		_, insertErr := store.InsertMail(outgoing)
+		_, bccErr := store.InsertMail(bcc)
+
+		// Act
+		sent, err := store.GetSent(mail.StoreGetSentQuery{Sender: "sender-1", Offset: 0, Limit: 10})
+		total, totalErr := store.GetTotalMailsSent("sender-1")
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, bccErr, err, totalErr)
+		assert.Equal(t, 1, len(sent))
+		assert.Equal(t, 1, total)
+		assert.Equal(t, messageID, sent[0].MessageID)
+		assert.NotEqual(t, model.RecipientBcc, sent[0].RecipientType)
+	})
+}
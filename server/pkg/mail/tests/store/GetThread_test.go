@@ -0,0 +1,133 @@
+package store_test
+
+import (
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetThread(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	threadID := uuid.New()
+
+	// alice and bob talk in one thread, one minute apart, carol gets a mail of another thread
+	insertThread := func() error {
+		base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		conversation := []struct {
+			sender    string
+			recipient string
+			thread    uuid.UUID
+		}{
+			{"alice", "bob", threadID},
+			{"bob", "alice", threadID},
+			{"alice", "bob", threadID},
+			{"alice", "carol", uuid.New()},
+		}
+		for i, mail := range conversation {
+			_, err := testDatabase.DB.Exec(
+				"INSERT INTO mail (recipient, sender, mail_subject, body, sent_at, thread_id) VALUES ($1, $2, $3, $4, $5, $6)",
+				mail.recipient, mail.sender, "subject", fmt.Sprintf("body-%d", i+1), base.Add(time.Duration(i)*time.Minute), mail.thread,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	bodies := func(thread []model.MailEntity) []string {
+		var result []string
+		for _, mail := range thread {
+			result = append(result, mail.Body)
+		}
+		return result
+	}
+
+	t.Run("should return mails of the thread oldest first", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertThread()
+
+		// Act
+		aliceThread, aliceErr := store.GetThread(threadID, "alice")
+		bobThread, bobErr := store.GetThread(threadID, "bob")
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, aliceErr, bobErr)
+		assert.Equal(t, []string{"body-1", "body-2", "body-3"}, bodies(aliceThread))
+		assert.Equal(t, []string{"body-1", "body-2", "body-3"}, bodies(bobThread))
+	})
+
+	t.Run("should return empty when user is not part of the thread", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertThread()
+
+		// Act
+		thread, err := store.GetThread(threadID, "carol")
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, err)
+		assert.Empty(t, thread)
+	})
+
+	t.Run("should leave out mails the user moved to trash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertThread()
+		_, trashErr := testDatabase.DB.Exec("UPDATE mail SET deleted_at = CURRENT_TIMESTAMP WHERE body = 'body-1'")
+
+		// Act
+		bobThread, bobErr := store.GetThread(threadID, "bob")
+		aliceThread, aliceErr := store.GetThread(threadID, "alice")
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, trashErr, bobErr, aliceErr)
+		assert.Equal(t, []string{"body-2", "body-3"}, bodies(bobThread))
+		assert.Equal(t, []string{"body-1", "body-2", "body-3"}, bodies(aliceThread))
+	})
+
+	t.Run("should return one delivery per message, the one to the user when there is one", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		outgoing := model.OutgoingMail{
+			MessageID: threadID,
+			From:      "alice",
+			To:        []string{"bob", "carol"},
+			Deliveries: []model.Delivery{
+				{Recipient: "bob", Type: model.RecipientTo, Body: "to bob"},
+				{Recipient: "carol", Type: model.RecipientTo, Body: "to carol"},
+			},
+		}
+		_, insertErr := store.InsertMail(outgoing)
+
+		// Act
+		aliceThread, aliceErr := store.GetThread(threadID, "alice")
+		carolThread, carolErr := store.GetThread(threadID, "carol")
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, aliceErr, carolErr)
+		assert.Equal(t, 1, len(aliceThread))
+		assert.Equal(t, []string{"to carol"}, bodies(carolThread))
+	})
+}
@@ -13,7 +13,7 @@ func TestGetTotalMailsReceived(t *testing.T) {
 	var store mail.MailStore
 
 	beforeEach := func() {
-		store = mail.NewStore(testDatabase.DB)
+		store = newStore()
 	}
 
 	afterEach := func() {
@@ -0,0 +1,54 @@
+package store_test
+
+import (
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTotalMailsSent(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should return 0 when sender has not sent any mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(2), testDatabase.DB)
+
+		// Act
+		total, err := store.GetTotalMailsSent("random-sender")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0, total)
+	})
+
+	t.Run("should return total mails sent by the sender", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		mockMails := mockMail(6)
+		for i := 0; i < 4; i++ {
+			mockMails[i].Sender = "sender-1"
+		}
+		insertMails(mockMails, testDatabase.DB)
+
+		// Act
+		total, err := store.GetTotalMailsSent("sender-1")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 4, total)
+	})
+}
@@ -0,0 +1,67 @@
+package store_test
+
+import (
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTotalUnread(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should count only unread mails in the inbox of the recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		mockMails := mockMail(5)
+		for i := 0; i < 4; i++ {
+			mockMails[i].Recipient = "recipient-1"
+		}
+		insertMails(mockMails, testDatabase.DB)
+		var readID, trashID uuid.UUID
+		for _, mail := range retrieveMails(testDatabase.DB) {
+			switch mail.Sender {
+			case "sender-1":
+				readID = mail.ID
+			case "sender-2":
+				trashID = mail.ID
+			}
+		}
+		_, readErr := store.MarkRead([]uuid.UUID{readID}, "recipient-1")
+		trashErr := store.TrashMail(trashID, "recipient-1")
+
+		// Act
+		unread, unreadErr := store.GetTotalUnread("recipient-1")
+
+		// Assert
+		util.AssertNoAnyError(t, readErr, trashErr, unreadErr)
+		assert.Equal(t, 2, unread)
+	})
+
+	t.Run("should return 0 when recipient has no mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(2), testDatabase.DB)
+
+		// Act
+		unread, unreadErr := store.GetTotalUnread("random-recipient")
+
+		// Assert
+		assert.NoError(t, unreadErr)
+		assert.Equal(t, 0, unread)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"passwordless-mail-server/pkg/model"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,7 +14,7 @@ func TestInsertMail(t *testing.T) {
 	var store mail.MailStore
 
 	beforeEach := func() {
-		store = mail.NewStore(testDatabase.DB)
+		store = newStore()
 	}
 
 	afterEach := func() {
@@ -21,53 +22,193 @@ func TestInsertMail(t *testing.T) {
 		fmt.Println("delete table items error", err)
 	}
 
+	newOutgoingMail := func(sender string, recipients ...string) model.OutgoingMail {
+		mail := model.OutgoingMail{
+			MessageID:  uuid.New(),
+			From:       sender,
+			To:         recipients,
+			SignedData: "signed send mail request",
+			Signature:  []byte("sender signature"),
+		}
+		for _, recipient := range recipients {
+			mail.Deliveries = append(mail.Deliveries, model.Delivery{
+				Recipient:    recipient,
+				Type:         model.RecipientTo,
+				EphemeralKey: "ephemeral key of " + recipient,
+				Subject:      "subject to " + recipient,
+				Body:         "body to " + recipient,
+			})
+		}
+		return mail
+	}
+
 	t.Run("should insert mail into the database", func(t *testing.T) {
 		// Arrange
 		beforeEach()
 		defer afterEach()
-		testMail := model.Mail{
-			From:    "sender public key",
-			To:      "recipient public key",
-			Subject: "test subject",
-			Body:    "test body",
-		}
+		testMail := newOutgoingMail("sender public key", "recipient public key")
 		preStoredMails := retrieveMails(testDatabase.DB)
 
 		// Act
-		insertedMail, err := store.InsertMail(testMail)
+		insertedMails, err := store.InsertMail(testMail)
 		fmt.Println("insert mail error", err)
 
 		// Assert
 		assert.NoError(t, err)
-		assert.NotEmpty(t, insertedMail.ID)
+		assert.Equal(t, 1, len(insertedMails))
+		assert.NotEmpty(t, insertedMails[0].ID)
 		postStoredMails := retrieveMails(testDatabase.DB)
 		assert.Equal(t, len(preStoredMails)+1, len(postStoredMails))
+		assert.Equal(t, testMail.MessageID, postStoredMails[0].MessageID)
 		assert.Equal(t, testMail.From, postStoredMails[0].Sender)
-		assert.Equal(t, testMail.To, postStoredMails[0].Recipient)
-		assert.Equal(t, testMail.Subject, postStoredMails[0].MailSubject)
-		assert.Equal(t, testMail.Body, postStoredMails[0].Body)
+		assert.Equal(t, "recipient public key", postStoredMails[0].Recipient)
+		assert.Equal(t, model.RecipientTo, postStoredMails[0].RecipientType)
+		assert.Equal(t, []string{"recipient public key"}, postStoredMails[0].ToRecipients)
+		assert.Equal(t, []string{}, postStoredMails[0].CcRecipients)
+		assert.Equal(t, testMail.Deliveries[0].Subject, postStoredMails[0].MailSubject)
+		assert.Equal(t, testMail.Deliveries[0].Body, postStoredMails[0].Body)
+		assert.Equal(t, testMail.Deliveries[0].EphemeralKey, postStoredMails[0].EphemeralKey)
+		assert.Equal(t, testMail.SignedData, postStoredMails[0].SignedData)
+		assert.Equal(t, testMail.Signature, postStoredMails[0].Signature)
+		assert.Nil(t, postStoredMails[0].InReplyTo)
+		assert.Equal(t, testMail.MessageID, postStoredMails[0].ThreadID)
 	})
 
-	t.Run("should return error when error is occurred", func(t *testing.T) {
+	t.Run("should insert one row per delivery sharing the message id", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		testMail := newOutgoingMail("sender", "recipient-1", "recipient-2")
+		testMail.Cc = []string{"recipient-2"}
+		testMail.To = []string{"recipient-1"}
+		testMail.Deliveries[1].Type = model.RecipientCc
+
+		// Act
+		insertedMails, err := store.InsertMail(testMail)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(insertedMails))
+		for i, recipient := range []string{"recipient-1", "recipient-2"} {
+			storedMail, getErr := store.GetMail(insertedMails[i].ID, recipient)
+			assert.NoError(t, getErr)
+			assert.Equal(t, testMail.MessageID, storedMail.MessageID)
+			assert.Equal(t, testMail.Deliveries[i].Type, storedMail.RecipientType)
+			assert.Equal(t, []string{"recipient-1"}, storedMail.ToRecipients)
+			assert.Equal(t, []string{"recipient-2"}, storedMail.CcRecipients)
+			assert.Equal(t, testMail.Deliveries[i].Body, storedMail.Body)
+		}
+	})
+
+	t.Run("should add bcc delivery of the same sender to the message", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		testMail := newOutgoingMail("sender", "recipient-1")
+		bccMail := newOutgoingMail("sender", "recipient-2")
+		bccMail.MessageID = testMail.MessageID
+		bccMail.Deliveries[0].Type = model.RecipientBcc
+
+		// Act
+		_, insertErr := store.InsertMail(testMail)
+		insertedBcc, bccErr := store.InsertMail(bccMail)
+
+		// Assert
+		assert.NoError(t, insertErr)
+		assert.NoError(t, bccErr)
+		assert.Equal(t, testMail.MessageID, insertedBcc[0].MessageID)
+		assert.Equal(t, 2, len(retrieveMails(testDatabase.DB)))
+	})
+
+	t.Run("should return message conflict when message id belongs to another sender or recipient is delivered twice", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		testMail := newOutgoingMail("sender", "recipient-1")
+		otherSenderMail := newOutgoingMail("other sender", "recipient-2")
+		otherSenderMail.MessageID = testMail.MessageID
+		duplicateMail := newOutgoingMail("sender", "recipient-1")
+		duplicateMail.MessageID = testMail.MessageID
+
+		// Act
+		_, insertErr := store.InsertMail(testMail)
+		_, otherSenderErr := store.InsertMail(otherSenderMail)
+		_, duplicateErr := store.InsertMail(duplicateMail)
+
+		// Assert
+		assert.NoError(t, insertErr)
+		assert.Equal(t, mail.ErrMessageConflict, otherSenderErr)
+		assert.Equal(t, mail.ErrMessageConflict, duplicateErr)
+		assert.Equal(t, 1, len(retrieveMails(testDatabase.DB)))
+	})
+
+	t.Run("should store attachments with every delivery and attachment keys per delivery", func(t *testing.T) {
 		// Arrange
 		beforeEach()
 		defer afterEach()
-		testMail := model.Mail{
-			From:    "sender public key",
-			To:      "recipient public key",
-			Subject: "test subject",
-			Body:    "test body",
+		testMail := newOutgoingMail("sender", "recipient-1", "recipient-2")
+		testMail.Attachments = []model.Attachment{{Hash: "attachment hash", Size: 20, MimeType: "text/plain"}}
+		for i := range testMail.Deliveries {
+			testMail.Deliveries[i].AttachmentKeys = "attachment keys of " + testMail.Deliveries[i].Recipient
 		}
+
+		// Act
+		insertedMails, err := store.InsertMail(testMail)
+
+		// Assert
+		assert.NoError(t, err)
+		for i, recipient := range []string{"recipient-1", "recipient-2"} {
+			storedMail, getErr := store.GetMail(insertedMails[i].ID, recipient)
+			assert.NoError(t, getErr)
+			assert.Equal(t, model.Attachments(testMail.Attachments), storedMail.Attachments)
+			assert.Equal(t, "attachment keys of "+recipient, storedMail.AttachmentKeys)
+		}
+		withoutAttachment, err := store.InsertMail(newOutgoingMail("sender", "recipient-3"))
+		assert.NoError(t, err)
+		storedMail, getErr := store.GetMail(withoutAttachment[0].ID, "recipient-3")
+		assert.NoError(t, getErr)
+		assert.Equal(t, model.Attachments{}, storedMail.Attachments)
+		assert.Empty(t, storedMail.AttachmentKeys)
+	})
+
+	t.Run("should insert reply into the thread of the original mail", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		original, err := store.InsertMail(newOutgoingMail("sender public key", "recipient public key"))
+		assert.NoError(t, err)
+		reply := newOutgoingMail("recipient public key", "sender public key")
+		reply.InReplyTo = &original[0].ID
+		reply.ThreadID = original[0].ThreadID
+
+		// Act
+		insertedReply, err := store.InsertMail(reply)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, original[0].MessageID, original[0].ThreadID)
+		assert.Equal(t, original[0].ThreadID, insertedReply[0].ThreadID)
+		storedReply, err := store.GetMail(insertedReply[0].ID, "sender public key")
+		assert.NoError(t, err)
+		assert.Equal(t, original[0].ID, *storedReply.InReplyTo)
+		assert.Equal(t, original[0].ThreadID, storedReply.ThreadID)
+	})
+
+	t.Run("should return error when error is occurred", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		testMail := newOutgoingMail("sender public key", "recipient public key")
 		preStoredMails := retrieveMails(testDatabase.DB)
 
 		// Act
 		testDatabase.DropTestTable()
 		defer testDatabase.CreateTestTable()
-		insertedMail, err := store.InsertMail(testMail)
+		insertedMails, err := store.InsertMail(testMail)
 
 		// Assert
 		assert.Error(t, err)
-		assert.Nil(t, insertedMail)
+		assert.Nil(t, insertedMails)
 		postStoredMails := retrieveMails(testDatabase.DB)
 		assert.Equal(t, len(preStoredMails), len(postStoredMails))
 	})
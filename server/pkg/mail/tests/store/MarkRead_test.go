@@ -0,0 +1,87 @@
+package store_test
+
+import (
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkRead(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should mark only mails of the recipient as read", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		mockMails := mockMail(3)
+		mockMails[0].Recipient = "recipient-1"
+		mockMails[1].Recipient = "recipient-1"
+		insertMails(mockMails, testDatabase.DB)
+		var ids []uuid.UUID
+		for _, mail := range retrieveMails(testDatabase.DB) {
+			ids = append(ids, mail.ID)
+		}
+
+		// Act
+		updated, markErr := store.MarkRead(ids, "recipient-1")
+
+		// Assert
+		assert.NoError(t, markErr)
+		assert.Equal(t, 2, updated)
+		for _, mail := range retrieveMails(testDatabase.DB) {
+			if mail.Recipient == "recipient-1" {
+				assert.NotNil(t, mail.ReadAt)
+			} else {
+				assert.Nil(t, mail.ReadAt)
+			}
+		}
+	})
+
+	t.Run("should not count mails that are already read", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(1), testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+		ids := []uuid.UUID{mails[0].ID}
+		_, firstErr := store.MarkRead(ids, mails[0].Recipient)
+
+		// Act
+		updated, markErr := store.MarkRead(ids, mails[0].Recipient)
+
+		// Assert
+		util.AssertNoAnyError(t, firstErr, markErr)
+		assert.Equal(t, 0, updated)
+	})
+
+	t.Run("should mark read mails as unread", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(1), testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+		ids := []uuid.UUID{mails[0].ID}
+		_, readErr := store.MarkRead(ids, mails[0].Recipient)
+
+		// Act
+		updated, markErr := store.MarkUnread(ids, mails[0].Recipient)
+
+		// Assert
+		util.AssertNoAnyError(t, readErr, markErr)
+		assert.Equal(t, 1, updated)
+		assert.Nil(t, retrieveMails(testDatabase.DB)[0].ReadAt)
+	})
+}
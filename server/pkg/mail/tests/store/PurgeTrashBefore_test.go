@@ -0,0 +1,66 @@
+package store_test
+
+import (
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeTrashBefore(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should delete mails trashed before the given time of every recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(3), testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+		now := time.Now().UTC()
+		_, oldErr := testDatabase.DB.Exec(
+			"UPDATE mail SET deleted_at = $1 WHERE id = $2 OR id = $3",
+			now.Add(-48*time.Hour), mails[0].ID, mails[1].ID,
+		)
+		_, recentErr := testDatabase.DB.Exec(
+			"UPDATE mail SET deleted_at = $1 WHERE id = $2",
+			now.Add(-time.Hour), mails[2].ID,
+		)
+
+		// Act
+		purged, purgeErr := store.PurgeTrashBefore(now.Add(-24 * time.Hour))
+
+		// Assert
+		util.AssertNoAnyError(t, oldErr, recentErr, purgeErr)
+		assert.Equal(t, 2, purged)
+		remaining := retrieveMails(testDatabase.DB)
+		assert.Equal(t, 1, len(remaining))
+		assert.Equal(t, mails[2].ID, remaining[0].ID)
+	})
+
+	t.Run("should keep mails that are not in trash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(2), testDatabase.DB)
+
+		// Act
+		purged, purgeErr := store.PurgeTrashBefore(time.Now())
+
+		// Assert
+		assert.NoError(t, purgeErr)
+		assert.Equal(t, 0, purged)
+		assert.Equal(t, 2, len(retrieveMails(testDatabase.DB)))
+	})
+}
@@ -0,0 +1,69 @@
+package store_test
+
+import (
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeTrash(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should delete only trashed mails of the recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		mockMails := mockMail(4)
+		mockMails[0].Recipient = "recipient-1"
+		mockMails[1].Recipient = "recipient-1"
+		mockMails[2].Recipient = "recipient-1"
+		mockMails[3].Recipient = "recipient-2"
+		insertMails(mockMails, testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+		var trashErrs []error
+		for _, mail := range mails {
+			if mail.Sender != "sender-3" {
+				trashErrs = append(trashErrs, store.TrashMail(mail.ID, mail.Recipient))
+			}
+		}
+
+		// Act
+		purged, purgeErr := store.PurgeTrash("recipient-1")
+
+		// Assert
+		util.AssertNoAnyError(t, append(trashErrs, purgeErr)...)
+		assert.Equal(t, 2, purged)
+		remaining := retrieveMails(testDatabase.DB)
+		assert.Equal(t, 2, len(remaining))
+		for _, mail := range remaining {
+			assert.Contains(t, []string{"sender-3", "sender-4"}, mail.Sender)
+		}
+	})
+
+	t.Run("should return 0 when trash is empty", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(2), testDatabase.DB)
+
+		// Act
+		purged, purgeErr := store.PurgeTrash("recipient-1")
+
+		// Assert
+		assert.NoError(t, purgeErr)
+		assert.Equal(t, 0, purged)
+		assert.Equal(t, 2, len(retrieveMails(testDatabase.DB)))
+	})
+}
@@ -0,0 +1,74 @@
+package store_test
+
+import (
+	"database/sql"
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreMail(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should move trashed mail back to inbox", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(1), testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+		trashErr := store.TrashMail(mails[0].ID, mails[0].Recipient)
+
+		// Act
+		restoreErr := store.RestoreMail(mails[0].ID, mails[0].Recipient)
+		total, totalErr := store.GetTotalMailsReceived(mails[0].Recipient)
+
+		// Assert
+		assert.NoError(t, trashErr)
+		assert.NoError(t, restoreErr)
+		assert.NoError(t, totalErr)
+		assert.Equal(t, 1, total)
+		assert.Nil(t, retrieveMails(testDatabase.DB)[0].DeletedAt)
+	})
+
+	t.Run("should return no rows when mail is not in trash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(1), testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+
+		// Act
+		restoreErr := store.RestoreMail(mails[0].ID, mails[0].Recipient)
+
+		// Assert
+		assert.ErrorIs(t, restoreErr, sql.ErrNoRows)
+	})
+
+	t.Run("should return no rows when mail belongs to another recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(1), testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+		trashErr := store.TrashMail(mails[0].ID, mails[0].Recipient)
+
+		// Act
+		restoreErr := store.RestoreMail(mails[0].ID, "random-recipient")
+
+		// Assert
+		assert.NoError(t, trashErr)
+		assert.ErrorIs(t, restoreErr, sql.ErrNoRows)
+		assert.NotNil(t, retrieveMails(testDatabase.DB)[0].DeletedAt)
+	})
+}
@@ -0,0 +1,101 @@
+package store_test
+
+import (
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchMail(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// recipient-1 gets one mail per day from sender-a, sender-b, sender-a, sender-b
+	insertSearchMails := func() error {
+		for i := 0; i < 4; i++ {
+			sender := "sender-a"
+			if i%2 == 1 {
+				sender = "sender-b"
+			}
+			_, err := testDatabase.DB.Exec(
+				"INSERT INTO mail (recipient, sender, mail_subject, body, sent_at) VALUES ($1, $2, $3, $4, $5)",
+				"recipient-1", sender, "subject", "body", base.AddDate(0, 0, i),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := testDatabase.DB.Exec(
+			"INSERT INTO mail (recipient, sender, mail_subject, body, sent_at) VALUES ($1, $2, $3, $4, $5)",
+			"recipient-2", "sender-a", "subject", "body", base,
+		)
+		return err
+	}
+
+	t.Run("should return every mail of the recipient without filters", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertSearchMails()
+
+		// Act
+		result, searchErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient-1", Limit: 10})
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, searchErr)
+		assert.Equal(t, 4, result.Total)
+		assert.Equal(t, 4, result.Unread)
+		assert.Equal(t, 4, len(result.Mails))
+	})
+
+	t.Run("should filter by sender and sent time", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertSearchMails()
+		since := base.AddDate(0, 0, 1)
+		until := base.AddDate(0, 0, 3)
+
+		// Act
+		bySender, senderErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient-1", Sender: "sender-a", Limit: 10})
+		byTime, timeErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient-1", Since: &since, Until: &until, Limit: 10})
+		byBoth, bothErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient-1", Sender: "sender-a", Since: &since, Limit: 10})
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, senderErr, timeErr, bothErr)
+		assert.Equal(t, 2, bySender.Total)
+		assert.Equal(t, 2, byTime.Total)
+		assert.Equal(t, 1, byBoth.Total)
+		assert.Equal(t, "sender-a", byBoth.Mails[0].Sender)
+	})
+
+	t.Run("should page newest first and count total of every page", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertErr := insertSearchMails()
+
+		// Act
+		result, searchErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient-1", Limit: 3, Offset: 3})
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, searchErr)
+		assert.Equal(t, 4, result.Total)
+		assert.Equal(t, 1, len(result.Mails))
+		assert.Equal(t, "sender-a", result.Mails[0].Sender)
+	})
+}
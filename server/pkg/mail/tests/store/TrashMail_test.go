@@ -0,0 +1,91 @@
+package store_test
+
+import (
+	"database/sql"
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashMail(t *testing.T) {
+	var store mail.MailStore
+
+	beforeEach := func() {
+		store = newStore()
+	}
+
+	afterEach := func() {
+		err = testDatabase.DeleteItemsFromTable("mail")
+		fmt.Println("delete table items error", err)
+	}
+
+	t.Run("should hide trashed mail from inbox and total", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		mockMails := mockMail(2)
+		mockMails[0].Recipient = "recipient-1"
+		mockMails[1].Recipient = "recipient-1"
+		insertMails(mockMails, testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+
+		// Act
+		trashErr := store.TrashMail(mails[0].ID, "recipient-1")
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient-1", Limit: 10})
+		total, totalErr := store.GetTotalMailsReceived("recipient-1")
+
+		// Assert
+		assert.NoError(t, trashErr)
+		assert.NoError(t, inboxErr)
+		assert.NoError(t, totalErr)
+		assert.Equal(t, 1, len(inbox))
+		assert.Equal(t, mails[1].ID, inbox[0].ID)
+		assert.Equal(t, 1, total)
+	})
+
+	t.Run("should return no rows when mail belongs to another recipient", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(1), testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+
+		// Act
+		trashErr := store.TrashMail(mails[0].ID, "random-recipient")
+
+		// Assert
+		assert.ErrorIs(t, trashErr, sql.ErrNoRows)
+		assert.Nil(t, retrieveMails(testDatabase.DB)[0].DeletedAt)
+	})
+
+	t.Run("should return no rows when mail is already in trash", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+		insertMails(mockMail(1), testDatabase.DB)
+		mails := retrieveMails(testDatabase.DB)
+		firstErr := store.TrashMail(mails[0].ID, mails[0].Recipient)
+
+		// Act
+		trashErr := store.TrashMail(mails[0].ID, mails[0].Recipient)
+
+		// Assert
+		assert.NoError(t, firstErr)
+		assert.ErrorIs(t, trashErr, sql.ErrNoRows)
+	})
+
+	t.Run("should return no rows when mail does not exist", func(t *testing.T) {
+		// Arrange
+		beforeEach()
+		defer afterEach()
+
+		// Act
+		trashErr := store.TrashMail(uuid.New(), "recipient-1")
+
+		// Assert
+		assert.ErrorIs(t, trashErr, sql.ErrNoRows)
+	})
+}
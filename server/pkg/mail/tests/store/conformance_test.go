@@ -0,0 +1,18 @@
+package store_test
+
+import (
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/storage/storagetest"
+	"testing"
+)
+
+func TestStore_Conformance(t *testing.T) {
+	storagetest.TestMailStore(t, func(t *testing.T) mail.MailStore {
+		err := testDatabase.DeleteItemsFromTable("mail")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return newStore()
+	})
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"passwordless-mail-server/pkg/mail"
 	"passwordless-mail-server/pkg/model"
 	"passwordless-mail-server/pkg/util"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 var testDatabase util.TestDatabase
@@ -31,6 +34,16 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// store of the test database backend, postgres or sqlite
+// by the scheme of DATABASE_CONNECTION_STRING
+func newStore() mail.MailStore {
+	if testDatabase.Driver == util.SQLiteDriver {
+		return mail.NewSQLiteStore(testDatabase.DB)
+	}
+
+	return mail.NewStore(testDatabase.DB)
+}
+
 func mockMail(amount int) []model.MailEntity {
 	var mails []model.MailEntity
 
@@ -68,7 +81,7 @@ func insertMails(mails []model.MailEntity, db *sql.DB) error {
 
 func retrieveMails(db *sql.DB) []model.MailEntity {
 	var mails []model.MailEntity
-	rows, err := db.Query("SELECT * FROM mail")
+	rows, err := db.Query("SELECT id, recipient, sender, mail_subject, body, sent_at, ephemeral_key, signed_data, signature, deleted_at, read_at, in_reply_to, thread_id, message_id, recipient_type, to_recipients, cc_recipients, attachments, attachment_keys FROM mail")
 	if err != nil {
 		return []model.MailEntity{}
 	}
@@ -76,7 +89,7 @@ func retrieveMails(db *sql.DB) []model.MailEntity {
 
 	for rows.Next() {
 		var mail model.MailEntity
-		err := rows.Scan(&mail.ID, &mail.Recipient, &mail.Sender, &mail.MailSubject, &mail.Body, &mail.SentAt)
+		err := rows.Scan(&mail.ID, &mail.Recipient, &mail.Sender, &mail.MailSubject, &mail.Body, &mail.SentAt, &mail.EphemeralKey, &mail.SignedData, &mail.Signature, &mail.DeletedAt, &mail.ReadAt, &mail.InReplyTo, &mail.ThreadID, &mail.MessageID, &mail.RecipientType, pq.Array(&mail.ToRecipients), pq.Array(&mail.CcRecipients), &mail.Attachments, &mail.AttachmentKeys)
 		if err != nil {
 			return []model.MailEntity{}
 		}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+type ChallengeResponse struct {
+	Nonce     string `json:"nonce"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type LoginResponse struct {
+	Token     string   `json:"token"`
+	Address   string   `json:"address"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at"`
+}
+
+// revoked sessions of logout or revoke sessions
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// SQL table schema
+
+type ChallengeEntity struct {
+	Nonce     string    `db:"nonce"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// only the SHA-256 of a token is stored, so a database leak does not leak sessions
+type SessionEntity struct {
+	TokenHash string     `db:"token_hash"`
+	Address   string     `db:"address"`
+	Scopes    []string   `db:"scopes"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"` // nil until logout or revoke
+}
@@ -0,0 +1,9 @@
+package model
+
+// body of every error response, request id is also sent in the
+// X-Request-Id header and written to the server log of a failed request
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+}
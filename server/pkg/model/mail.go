@@ -1,36 +1,185 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+)
+
+// one delivery of a mail to recipient, a mail sent to many recipients
+// has one delivery per recipient sharing the same message id.
+// subject and body are ciphertext encrypted by the sender client
+// to the recipient public key, the server never reads them.
+// signed data and signature are the sender's original send request,
+// so recipients can verify the sender without trusting the server
 type Mail struct {
-	ID      uuid.UUID `json:"id"`
-	From    string    `json:"from"`
-	To      string    `json:"to"`
-	Subject string    `json:"subject"`
-	Body    string    `json:"body"`
+	ID           uuid.UUID    `json:"id"`
+	MessageID    uuid.UUID    `json:"message_id"`
+	From         string       `json:"from"`
+	Recipient    string       `json:"recipient"`
+	To           []string     `json:"to"`
+	Cc           []string     `json:"cc"`
+	Bcc          []string     `json:"bcc,omitempty"` // only the bcc recipient of this delivery
+	EphemeralKey string       `json:"ephemeral_key"`
+	Subject      string       `json:"subject"`
+	Body         string       `json:"body"`
+	SignedData   string       `json:"signed_data"`
+	Signature    []byte       `json:"signature"`
+	SentAt       string       `json:"sent_at"`
+	ReadAt       *string      `json:"read_at"` // nil until the recipient opens it
+	InReplyTo    *uuid.UUID   `json:"in_reply_to"`
+	ThreadID     uuid.UUID    `json:"thread_id"`
+	Attachments  []Attachment `json:"attachments"`
+	// names and decryption keys of the attachments, encrypted to the recipient
+	AttachmentKeys string `json:"attachment_keys,omitempty"`
+}
+
+// encrypted attachment blob referenced by a mail,
+// hash is the hex SHA-256 of the ciphertext and size is its length in bytes
+type Attachment struct {
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+}
+
+// stored as a JSONB column
+type Attachments []Attachment
+
+func (a Attachments) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	value, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(value), nil
+}
+
+func (a *Attachments) Scan(src any) error {
+	var data []byte
+	switch value := src.(type) {
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	case nil:
+		*a = Attachments{}
+		return nil
+	default:
+		return fmt.Errorf("can not scan %T into attachments", src)
+	}
+
+	return json.Unmarshal(data, a)
+}
+
+// content of a mail encrypted to one recipient
+type Delivery struct {
+	Recipient      string
+	Type           RecipientType
+	EphemeralKey   string
+	Subject        string
+	Body           string
+	AttachmentKeys string
+}
+
+type RecipientType string
+
+const (
+	RecipientTo  RecipientType = "to"
+	RecipientCc  RecipientType = "cc"
+	RecipientBcc RecipientType = "bcc"
+)
+
+// one signed send request, stored as one row per delivery
+type OutgoingMail struct {
+	MessageID  uuid.UUID
+	From       string
+	To         []string
+	Cc         []string
+	Deliveries []Delivery
+	SignedData string
+	Signature  []byte
+	InReplyTo  *uuid.UUID
+	ThreadID   uuid.UUID
+	// shared by every delivery, blobs must already be uploaded
+	Attachments []Attachment
 }
 
 type InboxResponse struct {
-	Inbox []Mail `json:"inbox"`
+	Inbox  []Mail `json:"inbox"`
+	Total  int    `json:"total"`
+	Unread int    `json:"unread"`
+	Before string `json:"before,omitempty"` // cursor for older mails
+	After  string `json:"after,omitempty"`  // cursor for newer mails
+}
+
+type SentResponse struct {
+	Sent  []Mail `json:"sent"`
 	Total int    `json:"total"`
 }
 
+// mails of one conversation, oldest first
+type ThreadResponse struct {
+	ThreadID uuid.UUID `json:"thread_id"`
+	Mails    []Mail    `json:"mails"`
+}
+
+// id is the delivery to the first recipient,
+// ids has the delivery of every recipient by address
 type SendMailResponse struct {
+	ID        uuid.UUID            `json:"id"`
+	MessageID uuid.UUID            `json:"message_id"`
+	IDs       map[string]uuid.UUID `json:"ids"`
+}
+
+type UploadAttachmentResponse struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+type TrashMailResponse struct {
 	ID uuid.UUID `json:"id"`
 }
 
+type MarkMailsResponse struct {
+	Updated int `json:"updated"`
+}
+
+type PurgeTrashResponse struct {
+	Purged int `json:"purged"`
+}
+
 // SQL table schema
 
 type MailEntity struct {
-	ID          uuid.UUID `db:"id"`
-	Recipient   string    `db:"recipient"`
-	Sender      string    `db:"sender"`
-	MailSubject string    `db:"mail_subject"`
-	Body        string    `db:"body"`
-	SentAt      string    `db:"sent_at"`
+	ID             uuid.UUID     `db:"id"`
+	Recipient      string        `db:"recipient"`
+	Sender         string        `db:"sender"`
+	MailSubject    string        `db:"mail_subject"`
+	Body           string        `db:"body"`
+	SentAt         string        `db:"sent_at"`
+	EphemeralKey   string        `db:"ephemeral_key"`
+	SignedData     string        `db:"signed_data"`
+	Signature      []byte        `db:"signature"`
+	DeletedAt      *string       `db:"deleted_at"` // nil unless in trash
+	ReadAt         *string       `db:"read_at"`
+	InReplyTo      *uuid.UUID    `db:"in_reply_to"`
+	ThreadID       uuid.UUID     `db:"thread_id"`
+	MessageID      uuid.UUID     `db:"message_id"`
+	RecipientType  RecipientType `db:"recipient_type"`
+	ToRecipients   []string      `db:"to_recipients"`
+	CcRecipients   []string      `db:"cc_recipients"`
+	Attachments    Attachments   `db:"attachments"`
+	AttachmentKeys string        `db:"attachment_keys"`
 }
 
 type UsedUUIDEntity struct {
 	UUID      uuid.UUID `db:"uuid"`
 	CreatedAt string    `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
 }
@@ -3,4 +3,7 @@ package model
 type RequestBody struct {
 	Data      string `json:"data"`
 	Signature []byte `json:"signature"`
+	// set by the api middleware when the request came with a valid session
+	// token of the user, signature, id and timestamp are not checked then
+	Authenticated bool `json:"-"`
 }
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"database/sql"
+	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/util"
+	"strings"
+)
+
+// connection string of the in-memory backend, nothing is kept after the server stops
+const MemoryScheme = "memory://"
+
+// stores of one backend
+type Stores struct {
+	Mail    mail.MailStore
+	UUID    auth.UuidStore
+	Session auth.SessionStore
+	// release the backend, e.g. close the database connection
+	Close func() error
+}
+
+// open stores of the backend selected by the scheme of connection string,
+// memory:// keeps everything in the process, sqlite://path is a single file
+// database and anything else is postgres
+func Open(connectionString string) (*Stores, error) {
+	if strings.HasPrefix(connectionString, MemoryScheme) {
+		return OpenMemory(), nil
+	}
+	if strings.HasPrefix(connectionString, util.SQLiteScheme) {
+		return OpenSQLite(connectionString)
+	}
+
+	database, err := sql.Open(util.PostgresDriver, connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stores{
+		Mail:    mail.NewStore(database),
+		UUID:    auth.NewUUIDStore(database),
+		Session: auth.NewSessionStore(database),
+		Close:   database.Close,
+	}, nil
+}
+
+// stores of the sqlite database file of connection string,
+// its tables have to be created by infra/migration/sqlite first
+func OpenSQLite(connectionString string) (*Stores, error) {
+	database, err := util.OpenSQLite(connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stores{
+		Mail:    mail.NewSQLiteStore(database),
+		UUID:    auth.NewSQLiteUUIDStore(database),
+		Session: auth.NewSQLiteSessionStore(database),
+		Close:   database.Close,
+	}, nil
+}
+
+// thread-safe stores in the process, for development and tests without a database
+func OpenMemory() *Stores {
+	authStore := auth.NewMemoryStore()
+
+	return &Stores{
+		Mail:    mail.NewMemoryStore(),
+		UUID:    authStore,
+		Session: authStore,
+		Close:   func() error { return nil },
+	}
+}
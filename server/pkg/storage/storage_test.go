@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/storage/storagetest"
+	"passwordless-mail-server/pkg/util"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen(t *testing.T) {
+
+	t.Run("should open memory stores when connection string is memory scheme", func(t *testing.T) {
+		// Arrange
+		id := uuid.New()
+
+		// Act
+		stores, openErr := Open(MemoryScheme)
+		insertErr := stores.UUID.InsertUsedUUID(id, time.Now().Add(time.Minute))
+		used, getErr := stores.UUID.GetUsedUUID(id)
+		closeErr := stores.Close()
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, insertErr)
+		assert.NoError(t, getErr)
+		assert.NoError(t, closeErr)
+		assert.Equal(t, id, used.UUID)
+	})
+
+	t.Run("should open sqlite stores when connection string is sqlite scheme", func(t *testing.T) {
+		// Arrange
+		connectionString := util.SQLiteScheme + filepath.Join(t.TempDir(), "kmail.db")
+
+		// Act
+		stores, openErr := Open(connectionString)
+		closeErr := stores.Close()
+
+		// Assert
+		assert.NoError(t, openErr)
+		assert.NoError(t, closeErr)
+		assert.IsType(t, &mail.SQLiteStore{}, stores.Mail)
+		assert.IsType(t, &auth.SQLiteStore{}, stores.UUID)
+		assert.IsType(t, &auth.SQLiteStore{}, stores.Session)
+	})
+
+	t.Run("should return error when sqlite path is empty", func(t *testing.T) {
+		// Act
+		stores, openErr := Open(util.SQLiteScheme)
+
+		// Assert
+		assert.Error(t, openErr)
+		assert.Nil(t, stores)
+	})
+
+	t.Run("should not share data between memory stores", func(t *testing.T) {
+		// Arrange
+		first := OpenMemory()
+		second := OpenMemory()
+		_, insertErr := first.Mail.InsertMail(model.OutgoingMail{
+			MessageID:  uuid.New(),
+			From:       "sender",
+			To:         []string{"recipient"},
+			Deliveries: []model.Delivery{{Recipient: "recipient", Type: model.RecipientTo}},
+		})
+
+		// Act
+		firstTotal, firstErr := first.Mail.GetTotalMailsReceived("recipient")
+		secondTotal, secondErr := second.Mail.GetTotalMailsReceived("recipient")
+
+		// Assert
+		assert.NoError(t, insertErr)
+		assert.NoError(t, firstErr)
+		assert.NoError(t, secondErr)
+		assert.Equal(t, 1, firstTotal)
+		assert.Equal(t, 0, secondTotal)
+	})
+}
+
+func TestMemoryStores(t *testing.T) {
+
+	t.Run("should behave like a mail store", func(t *testing.T) {
+		storagetest.TestMailStore(t, func(t *testing.T) mail.MailStore {
+			return OpenMemory().Mail
+		})
+	})
+
+	t.Run("should behave like a uuid store", func(t *testing.T) {
+		storagetest.TestUuidStore(t, func(t *testing.T) auth.UuidStore {
+			return OpenMemory().UUID
+		})
+	})
+}
+
+func TestSQLiteStores(t *testing.T) {
+
+	// stores of a new database file migrated by infra/migration/sqlite
+	openSQLite := func(t *testing.T) *Stores {
+		connectionString := util.SQLiteScheme + filepath.Join(t.TempDir(), "kmail.db")
+		database, err := util.OpenSQLite(connectionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = util.TestDatabase{DB: database, Driver: util.SQLiteDriver}.CreateTestTable()
+		database.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores, err := OpenSQLite(connectionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { stores.Close() })
+
+		return stores
+	}
+
+	t.Run("should behave like a mail store", func(t *testing.T) {
+		storagetest.TestMailStore(t, func(t *testing.T) mail.MailStore {
+			return openSQLite(t).Mail
+		})
+	})
+
+	t.Run("should behave like a uuid store", func(t *testing.T) {
+		storagetest.TestUuidStore(t, func(t *testing.T) auth.UuidStore {
+			return openSQLite(t).UUID
+		})
+	})
+}
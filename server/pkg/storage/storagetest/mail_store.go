@@ -0,0 +1,607 @@
+// Package storagetest has the behavior every store backend is held to.
+// a backend runs it from its own tests by passing a constructor, e.g.
+//
+//	storagetest.TestMailStore(t, func(t *testing.T) mail.MailStore {
+//		return mail.NewMemoryStore()
+//	})
+package storagetest
+
+import (
+	"database/sql"
+	"fmt"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// run the MailStore cases, newStore is called by every case
+// and returns a store without any mail
+func TestMailStore(t *testing.T, newStore func(t *testing.T) mail.MailStore) {
+
+	t.Run("should return inbox newest first", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 3, "sender", "recipient")
+
+		// Act
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 10, Order: mail.NewestFirst})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Equal(t, []uuid.UUID{sent[2].ID, sent[1].ID, sent[0].ID}, mailIDs(inbox))
+	})
+
+	t.Run("should return inbox oldest first", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 3, "sender", "recipient")
+
+		// Act
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 10, Order: mail.OldestFirst})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Equal(t, []uuid.UUID{sent[0].ID, sent[1].ID, sent[2].ID}, mailIDs(inbox))
+	})
+
+	t.Run("should return every inbox mail once across offset pages", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 5, "sender", "recipient")
+		query := mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 2, Order: mail.OldestFirst}
+
+		// Act
+		var pages [][]model.MailEntity
+		var pageErrs []error
+		for offset := 0; offset < 6; offset += 2 {
+			query.Offset = offset
+			page, pageErr := store.GetInbox(query)
+			pages = append(pages, page)
+			pageErrs = append(pageErrs, pageErr)
+		}
+
+		// Assert
+		util.AssertNoAnyError(t, pageErrs...)
+		assert.Equal(t, []uuid.UUID{sent[0].ID, sent[1].ID}, mailIDs(pages[0]))
+		assert.Equal(t, []uuid.UUID{sent[2].ID, sent[3].ID}, mailIDs(pages[1]))
+		assert.Equal(t, []uuid.UUID{sent[4].ID}, mailIDs(pages[2]))
+	})
+
+	t.Run("should return no inbox mails when offset is past the last mail", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sendMails(t, store, 2, "sender", "recipient")
+
+		// Act
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 10, Offset: 2})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Empty(t, inbox)
+	})
+
+	t.Run("should return no inbox mails when limit is zero", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sendMails(t, store, 2, "sender", "recipient")
+
+		// Act
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 0})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Empty(t, inbox)
+	})
+
+	t.Run("should return no inbox mails when limit is negative", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sendMails(t, store, 2, "sender", "recipient")
+
+		// Act
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: -1})
+		empty, emptyErr := newStore(t).GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: -10, Offset: -10})
+
+		// Assert
+		util.AssertNoAnyError(t, inboxErr, emptyErr)
+		assert.Empty(t, inbox)
+		assert.Empty(t, empty)
+	})
+
+	t.Run("should return inbox from the first mail when offset is negative", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 3, "sender", "recipient")
+
+		// Act
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 2, Offset: -10, Order: mail.OldestFirst})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Equal(t, []uuid.UUID{sent[0].ID, sent[1].ID}, mailIDs(inbox))
+	})
+
+	t.Run("should page inbox from before and after cursors in the requested order", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 5, "sender", "recipient")
+		middle := cursorOf(t, store, sent[2])
+
+		// Act
+		newestBefore, newestBeforeErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 10, Order: mail.NewestFirst, Before: &middle})
+		oldestBefore, oldestBeforeErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 1, Order: mail.OldestFirst, Before: &middle})
+		newestAfter, newestAfterErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 1, Order: mail.NewestFirst, After: &middle})
+		oldestAfter, oldestAfterErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 10, Order: mail.OldestFirst, After: &middle})
+
+		// Assert
+		util.AssertNoAnyError(t, newestBeforeErr, oldestBeforeErr, newestAfterErr, oldestAfterErr)
+		assert.Equal(t, []uuid.UUID{sent[1].ID, sent[0].ID}, mailIDs(newestBefore))
+		assert.Equal(t, []uuid.UUID{sent[1].ID}, mailIDs(oldestBefore))
+		assert.Equal(t, []uuid.UUID{sent[3].ID}, mailIDs(newestAfter))
+		assert.Equal(t, []uuid.UUID{sent[3].ID, sent[4].ID}, mailIDs(oldestAfter))
+	})
+
+	t.Run("should return no inbox mails after the newest mail", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 2, "sender", "recipient")
+		newest := cursorOf(t, store, sent[1])
+
+		// Act
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 10, After: &newest})
+
+		// Assert
+		assert.NoError(t, inboxErr)
+		assert.Empty(t, inbox)
+	})
+
+	t.Run("should leave trashed mails out of inbox and totals", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 3, "sender", "recipient")
+		trashErr := store.TrashMail(sent[1].ID, "recipient")
+
+		// Act
+		inbox, inboxErr := store.GetInbox(mail.StoreGetInboxQuery{Recipient: "recipient", Limit: 10})
+		total, totalErr := store.GetTotalMailsReceived("recipient")
+		unread, unreadErr := store.GetTotalUnread("recipient")
+
+		// Assert
+		util.AssertNoAnyError(t, trashErr, inboxErr, totalErr, unreadErr)
+		assert.Equal(t, []uuid.UUID{sent[2].ID, sent[0].ID}, mailIDs(inbox))
+		assert.Equal(t, 2, total)
+		assert.Equal(t, 2, unread)
+	})
+
+	t.Run("should return sent mails newest first with one delivery per message", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		first := sendMails(t, store, 1, "sender", "recipient-1")
+		second := insertMail(t, store, "sender", []string{"recipient-1"}, model.Delivery{Recipient: "recipient-2", Type: model.RecipientBcc}, model.Delivery{Recipient: "recipient-1", Type: model.RecipientTo})
+
+		// Act
+		sent, sentErr := store.GetSent(mail.StoreGetSentQuery{Sender: "sender", Limit: 10})
+		total, totalErr := store.GetTotalMailsSent("sender")
+
+		// Assert
+		util.AssertNoAnyError(t, sentErr, totalErr)
+		assert.Equal(t, []uuid.UUID{deliveryTo(second, "recipient-1").ID, first[0].ID}, mailIDs(sent))
+		assert.Equal(t, 2, total)
+	})
+
+	t.Run("should return no sent mails when offset is past the last message", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sendMails(t, store, 2, "sender", "recipient")
+
+		// Act
+		sent, sentErr := store.GetSent(mail.StoreGetSentQuery{Sender: "sender", Limit: 10, Offset: 2})
+		limited, limitedErr := store.GetSent(mail.StoreGetSentQuery{Sender: "sender", Limit: 1, Offset: 1})
+
+		// Assert
+		util.AssertNoAnyError(t, sentErr, limitedErr)
+		assert.Empty(t, sent)
+		assert.Equal(t, 1, len(limited))
+	})
+
+	t.Run("should return sent mails from the first message when offset is negative and none when limit is not positive", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 2, "sender", "recipient")
+
+		// Act
+		first, firstErr := store.GetSent(mail.StoreGetSentQuery{Sender: "sender", Limit: 1, Offset: -10})
+		zero, zeroErr := store.GetSent(mail.StoreGetSentQuery{Sender: "sender", Limit: 0})
+		negative, negativeErr := store.GetSent(mail.StoreGetSentQuery{Sender: "sender", Limit: -1})
+
+		// Assert
+		util.AssertNoAnyError(t, firstErr, zeroErr, negativeErr)
+		assert.Equal(t, []uuid.UUID{sent[1].ID}, mailIDs(first))
+		assert.Empty(t, zero)
+		assert.Empty(t, negative)
+	})
+
+	t.Run("should search mails from since until before until, newest first", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 4, "sender", "recipient")
+		since := cursorOf(t, store, sent[1]).SentAt
+		until := cursorOf(t, store, sent[3]).SentAt
+
+		// Act
+		result, searchErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient", Since: &since, Until: &until, Limit: 10})
+		paged, pagedErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient", Since: &since, Until: &until, Limit: 10, Offset: 2})
+
+		// Assert
+		util.AssertNoAnyError(t, searchErr, pagedErr)
+		assert.Equal(t, []uuid.UUID{sent[2].ID, sent[1].ID}, mailIDs(result.Mails))
+		assert.Equal(t, 2, result.Total)
+		assert.Equal(t, 2, result.Unread)
+		assert.Empty(t, paged.Mails)
+		assert.Equal(t, 2, paged.Total)
+	})
+
+	t.Run("should search by the instant of since and until in another time zone", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 4, "sender", "recipient")
+		since := cursorOf(t, store, sent[1]).SentAt.In(time.FixedZone("UTC+7", 7*60*60))
+		until := cursorOf(t, store, sent[3]).SentAt.In(time.FixedZone("UTC-7", -7*60*60))
+
+		// Act
+		result, searchErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient", Since: &since, Until: &until, Limit: 10})
+
+		// Assert
+		assert.NoError(t, searchErr)
+		assert.Equal(t, []uuid.UUID{sent[2].ID, sent[1].ID}, mailIDs(result.Mails))
+		assert.Equal(t, 2, result.Total)
+	})
+
+	t.Run("should search from the first mail when offset is negative and return only totals when limit is not positive", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 2, "sender", "recipient")
+
+		// Act
+		first, firstErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient", Limit: 1, Offset: -10})
+		zero, zeroErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient", Limit: 0})
+		negative, negativeErr := store.SearchMail(mail.StoreSearchMailQuery{Recipient: "recipient", Limit: -1, Offset: -1})
+
+		// Assert
+		util.AssertNoAnyError(t, firstErr, zeroErr, negativeErr)
+		assert.Equal(t, []uuid.UUID{sent[1].ID}, mailIDs(first.Mails))
+		assert.Empty(t, zero.Mails)
+		assert.Empty(t, negative.Mails)
+		assert.Equal(t, 2, negative.Total)
+	})
+
+	t.Run("should return thread oldest first to sender and recipient", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		original := sendMails(t, store, 1, "alice", "bob")[0]
+		reply := insertReply(t, store, "bob", "alice", original)
+
+		// Act
+		aliceThread, aliceErr := store.GetThread(original.MessageID, "alice")
+		bobThread, bobErr := store.GetThread(original.MessageID, "bob")
+		otherThread, otherErr := store.GetThread(original.MessageID, "carol")
+
+		// Assert
+		util.AssertNoAnyError(t, aliceErr, bobErr, otherErr)
+		assert.Equal(t, original.MessageID, original.ThreadID)
+		assert.Equal(t, original.ThreadID, reply.ThreadID)
+		assert.Equal(t, []uuid.UUID{original.ID, reply.ID}, mailIDs(aliceThread))
+		assert.Equal(t, []uuid.UUID{original.ID, reply.ID}, mailIDs(bobThread))
+		assert.Empty(t, otherThread)
+	})
+
+	t.Run("should return the delivery to the user in a thread", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		deliveries := insertMail(t, store, "alice", []string{"bob", "carol"}, model.Delivery{Recipient: "bob", Type: model.RecipientTo}, model.Delivery{Recipient: "carol", Type: model.RecipientTo})
+
+		// Act
+		carolThread, carolErr := store.GetThread(deliveries[0].ThreadID, "carol")
+
+		// Assert
+		assert.NoError(t, carolErr)
+		assert.Equal(t, []uuid.UUID{deliveryTo(deliveries, "carol").ID}, mailIDs(carolThread))
+	})
+
+	t.Run("should return mail to its recipient", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 1, "sender", "recipient")[0]
+
+		// Act
+		stored, getErr := store.GetMail(sent.ID, "recipient")
+
+		// Assert
+		assert.NoError(t, getErr)
+		assertSameMail(t, sent, stored)
+	})
+
+	t.Run("should return mail to its sender", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 1, "sender", "recipient")[0]
+
+		// Act
+		stored, getErr := store.GetMail(sent.ID, "sender")
+
+		// Assert
+		assert.NoError(t, getErr)
+		assertSameMail(t, sent, stored)
+	})
+
+	t.Run("should return sql.ErrNoRows when user is not sender or recipient of mail", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 1, "sender", "recipient")[0]
+
+		// Act
+		stored, getErr := store.GetMail(sent.ID, "other")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, getErr)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("should return sql.ErrNoRows when user reads the delivery of another recipient", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		deliveries := insertMail(t, store, "sender", []string{"recipient-1"}, model.Delivery{Recipient: "recipient-1", Type: model.RecipientTo}, model.Delivery{Recipient: "recipient-2", Type: model.RecipientBcc})
+
+		// Act
+		stored, getErr := store.GetMail(deliveryTo(deliveries, "recipient-2").ID, "recipient-1")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, getErr)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("should return sql.ErrNoRows when mail does not exist", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+
+		// Act
+		stored, getErr := store.GetMail(uuid.New(), "recipient")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, getErr)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("should return trashed mail to its recipient", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 1, "sender", "recipient")[0]
+		trashErr := store.TrashMail(sent.ID, "recipient")
+
+		// Act
+		stored, getErr := store.GetMail(sent.ID, "recipient")
+
+		// Assert
+		util.AssertNoAnyError(t, trashErr, getErr)
+		assert.NotNil(t, stored.DeletedAt)
+	})
+
+	t.Run("should return ErrMessageConflict and insert nothing when message already has a delivery to recipient", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 1, "sender", "recipient-1")[0]
+
+		// Act
+		_, insertErr := store.InsertMail(model.OutgoingMail{
+			MessageID: sent.MessageID,
+			From:      "sender",
+			Deliveries: []model.Delivery{
+				{Recipient: "recipient-2", Type: model.RecipientBcc},
+				{Recipient: "recipient-1", Type: model.RecipientBcc},
+			},
+		})
+		total, totalErr := store.GetTotalMailsReceived("recipient-2")
+
+		// Assert
+		assert.Equal(t, mail.ErrMessageConflict, insertErr)
+		assert.NoError(t, totalErr)
+		assert.Equal(t, 0, total)
+	})
+
+	t.Run("should return ErrMessageConflict when message id is used by another sender", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 1, "sender", "recipient-1")[0]
+
+		// Act
+		_, insertErr := store.InsertMail(model.OutgoingMail{
+			MessageID:  sent.MessageID,
+			From:       "other",
+			Deliveries: []model.Delivery{{Recipient: "recipient-2", Type: model.RecipientTo}},
+		})
+
+		// Assert
+		assert.Equal(t, mail.ErrMessageConflict, insertErr)
+	})
+
+	t.Run("should count only mails whose read state changed", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 2, "sender", "recipient")
+		other := sendMails(t, store, 1, "sender", "other")
+
+		// Act
+		read, readErr := store.MarkRead([]uuid.UUID{sent[0].ID, other[0].ID}, "recipient")
+		readAgain, readAgainErr := store.MarkRead([]uuid.UUID{sent[0].ID, sent[1].ID}, "recipient")
+		unread, unreadErr := store.MarkUnread([]uuid.UUID{sent[0].ID, sent[1].ID, uuid.New()}, "recipient")
+		totalUnread, totalUnreadErr := store.GetTotalUnread("recipient")
+
+		// Assert
+		util.AssertNoAnyError(t, readErr, readAgainErr, unreadErr, totalUnreadErr)
+		assert.Equal(t, 1, read)
+		assert.Equal(t, 1, readAgain)
+		assert.Equal(t, 2, unread)
+		assert.Equal(t, 2, totalUnread)
+	})
+
+	t.Run("should return sql.ErrNoRows when trash or restore does not change mail", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 1, "sender", "recipient")[0]
+
+		// Act
+		restoreErr := store.RestoreMail(sent.ID, "recipient")
+		otherTrashErr := store.TrashMail(sent.ID, "sender")
+		trashErr := store.TrashMail(sent.ID, "recipient")
+		trashAgainErr := store.TrashMail(sent.ID, "recipient")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, restoreErr)
+		assert.Equal(t, sql.ErrNoRows, otherTrashErr)
+		assert.NoError(t, trashErr)
+		assert.Equal(t, sql.ErrNoRows, trashAgainErr)
+	})
+
+	t.Run("should purge only trashed mails", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 2, "sender", "recipient")
+		trashErr := store.TrashMail(sent[0].ID, "recipient")
+
+		// Act
+		kept, keptErr := store.PurgeTrashBefore(time.Now().Add(-time.Hour))
+		purged, purgeErr := store.PurgeTrash("recipient")
+		_, getErr := store.GetMail(sent[0].ID, "recipient")
+		total, totalErr := store.GetTotalMailsReceived("recipient")
+
+		// Assert
+		util.AssertNoAnyError(t, trashErr, keptErr, purgeErr, totalErr)
+		assert.Equal(t, 0, kept)
+		assert.Equal(t, 1, purged)
+		assert.Equal(t, sql.ErrNoRows, getErr)
+		assert.Equal(t, 1, total)
+	})
+
+	t.Run("should purge trash by the instant of a cutoff in another time zone", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		sent := sendMails(t, store, 1, "sender", "recipient")
+		trashErr := store.TrashMail(sent[0].ID, "recipient")
+		east := time.FixedZone("UTC+7", 7*60*60)
+		west := time.FixedZone("UTC-7", -7*60*60)
+
+		// Act
+		kept, keptErr := store.PurgeTrashBefore(time.Now().Add(-time.Minute).In(east))
+		purged, purgeErr := store.PurgeTrashBefore(time.Now().Add(time.Minute).In(west))
+
+		// Assert
+		util.AssertNoAnyError(t, trashErr, keptErr, purgeErr)
+		assert.Equal(t, 0, kept)
+		assert.Equal(t, 1, purged)
+	})
+}
+
+// amount messages from sender with one to delivery each, a millisecond
+// apart so their sent_at order is the order they are returned in
+func sendMails(t *testing.T, store mail.MailStore, amount int, sender string, recipient string) []model.MailEntity {
+	var sent []model.MailEntity
+	for i := 0; i < amount; i++ {
+		deliveries := insertMail(t, store, sender, []string{recipient}, model.Delivery{
+			Recipient: recipient,
+			Type:      model.RecipientTo,
+			Subject:   fmt.Sprintf("subject-%d", i+1),
+			Body:      fmt.Sprintf("body-%d", i+1),
+		})
+		sent = append(sent, deliveries[0])
+	}
+
+	return sent
+}
+
+func insertMail(t *testing.T, store mail.MailStore, sender string, to []string, deliveries ...model.Delivery) []model.MailEntity {
+	return insert(t, store, model.OutgoingMail{
+		MessageID:  uuid.New(),
+		From:       sender,
+		To:         to,
+		Deliveries: deliveries,
+		SignedData: "signed data",
+		Signature:  []byte("signature"),
+	})
+}
+
+func insertReply(t *testing.T, store mail.MailStore, sender string, recipient string, original model.MailEntity) model.MailEntity {
+	return insert(t, store, model.OutgoingMail{
+		MessageID:  uuid.New(),
+		From:       sender,
+		To:         []string{recipient},
+		Deliveries: []model.Delivery{{Recipient: recipient, Type: model.RecipientTo}},
+		SignedData: "signed data",
+		Signature:  []byte("signature"),
+		InReplyTo:  &original.ID,
+		ThreadID:   original.ThreadID,
+	})[0]
+}
+
+func insert(t *testing.T, store mail.MailStore, outgoing model.OutgoingMail) []model.MailEntity {
+	t.Helper()
+	time.Sleep(time.Millisecond)
+	deliveries, err := store.InsertMail(outgoing)
+	if err != nil {
+		t.Fatalf("insert mail: %v", err)
+	}
+
+	return deliveries
+}
+
+func deliveryTo(deliveries []model.MailEntity, recipient string) model.MailEntity {
+	for _, delivery := range deliveries {
+		if delivery.Recipient == recipient {
+			return delivery
+		}
+	}
+
+	return model.MailEntity{}
+}
+
+// cursor of a stored mail, InsertMail does not return sent_at
+func cursorOf(t *testing.T, store mail.MailStore, sent model.MailEntity) mail.Cursor {
+	t.Helper()
+	stored, err := store.GetMail(sent.ID, sent.Recipient)
+	if err != nil {
+		t.Fatalf("get mail: %v", err)
+	}
+	sentAt, err := time.Parse(time.RFC3339Nano, stored.SentAt)
+	if err != nil {
+		t.Fatalf("parse sent_at %q: %v", stored.SentAt, err)
+	}
+
+	return mail.Cursor{SentAt: sentAt.UTC(), ID: stored.ID}
+}
+
+func mailIDs(mails []model.MailEntity) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, entity := range mails {
+		ids = append(ids, entity.ID)
+	}
+
+	return ids
+}
+
+// stored is sent as InsertMail returned it, with sent_at set by the store
+func assertSameMail(t *testing.T, sent model.MailEntity, stored *model.MailEntity) {
+	t.Helper()
+	if !assert.NotNil(t, stored) {
+		return
+	}
+	_, parseErr := time.Parse(time.RFC3339Nano, stored.SentAt)
+	assert.NoError(t, parseErr)
+	assert.Nil(t, stored.ReadAt)
+	assert.Nil(t, stored.DeletedAt)
+	sent.SentAt = stored.SentAt
+	assert.Equal(t, sent, *stored)
+}
@@ -0,0 +1,125 @@
+package storagetest
+
+import (
+	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/util"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// run the UuidStore cases, newStore is called by every case
+// and returns a store without any used uuid
+func TestUuidStore(t *testing.T, newStore func(t *testing.T) auth.UuidStore) {
+
+	t.Run("should return no uuid and no error when uuid is not used", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+
+		// Act
+		entity, getErr := store.GetUsedUUID(uuid.New())
+
+		// Assert
+		assert.NoError(t, getErr)
+		assert.Nil(t, entity)
+	})
+
+	t.Run("should return used uuid with its expiry", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		id := uuid.New()
+		expiresAt := time.Now().Add(auth.RequestTimeout)
+		insertErr := store.InsertUsedUUID(id, expiresAt)
+
+		// Act
+		entity, getErr := store.GetUsedUUID(id)
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, getErr)
+		if assert.NotNil(t, entity) {
+			assert.Equal(t, id, entity.UUID)
+			assert.WithinDuration(t, expiresAt, entity.ExpiresAt, time.Millisecond)
+		}
+	})
+
+	t.Run("should return ErrUUIDUsed when uuid is inserted twice", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		id := uuid.New()
+		firstErr := store.InsertUsedUUID(id, time.Now().Add(auth.RequestTimeout))
+
+		// Act
+		secondErr := store.InsertUsedUUID(id, time.Now().Add(auth.RequestTimeout))
+
+		// Assert
+		assert.NoError(t, firstErr)
+		assert.ErrorIs(t, secondErr, auth.ErrUUIDUsed)
+	})
+
+	t.Run("should accept a uuid only once when it is inserted concurrently", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		id := uuid.New()
+		const attempts = 10
+		insertErrs := make(chan error, attempts)
+		var wg sync.WaitGroup
+
+		// Act
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				insertErrs <- store.InsertUsedUUID(id, time.Now().Add(auth.RequestTimeout))
+			}()
+		}
+		wg.Wait()
+		close(insertErrs)
+
+		// Assert
+		inserted := 0
+		for insertErr := range insertErrs {
+			if insertErr == nil {
+				inserted++
+				continue
+			}
+			assert.ErrorIs(t, insertErr, auth.ErrUUIDUsed)
+		}
+		assert.Equal(t, 1, inserted)
+	})
+
+	t.Run("should delete only uuids expired before the given time", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		expired := uuid.New()
+		fresh := uuid.New()
+		expiredErr := store.InsertUsedUUID(expired, time.Now().Add(-time.Minute))
+		freshErr := store.InsertUsedUUID(fresh, time.Now().Add(auth.RequestTimeout))
+
+		// Act
+		deleted, deleteErr := store.DeleteExpiredUUIDs(time.Now())
+		expiredEntity, expiredGetErr := store.GetUsedUUID(expired)
+		freshEntity, freshGetErr := store.GetUsedUUID(fresh)
+
+		// Assert
+		util.AssertNoAnyError(t, expiredErr, freshErr, deleteErr, expiredGetErr, freshGetErr)
+		assert.Equal(t, 1, deleted)
+		assert.Nil(t, expiredEntity)
+		assert.NotNil(t, freshEntity)
+	})
+
+	t.Run("should return zero when no uuid is expired", func(t *testing.T) {
+		// Arrange
+		store := newStore(t)
+		insertErr := store.InsertUsedUUID(uuid.New(), time.Now().Add(auth.RequestTimeout))
+
+		// Act
+		deleted, deleteErr := store.DeleteExpiredUUIDs(time.Now())
+
+		// Assert
+		util.AssertNoAnyError(t, insertErr, deleteErr)
+		assert.Equal(t, 0, deleted)
+	})
+}
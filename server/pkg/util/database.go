@@ -3,51 +3,94 @@ package util
 import (
 	"database/sql"
 	"errors"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
-func ConnectDatabase() *sql.DB {
+// connection string of a single file sqlite database, e.g. sqlite://./data/kmail.db
+const SQLiteScheme = "sqlite://"
+
+// database/sql driver names
+const (
+	PostgresDriver = "postgres"
+	SQLiteDriver   = "sqlite3"
+)
+
+// DATABASE_CONNECTION_STRING of the environment or ../.env, a postgres
+// connection string, sqlite://path or memory:// to keep everything in the process
+func ConnectionString() (string, error) {
 	err := godotenv.Load("../.env")
-	if err != nil {
-		log.Fatal(err)
-		return nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
 	}
 
 	connectionString := os.Getenv("DATABASE_CONNECTION_STRING")
 	if connectionString == "" {
-		log.Fatal("database connection string is not set correctly")
-		return nil
+		return "", errors.New("database connection string is not set correctly")
+	}
+
+	return connectionString, nil
+}
+
+// open the sqlite database file of connection string, its tables are created
+// by infra/migration/sqlite. sqlite has one writer at a time, so the stores
+// share one connection and wait for a lock instead of failing with busy
+func OpenSQLite(connectionString string) (*sql.DB, error) {
+	path := strings.TrimPrefix(connectionString, SQLiteScheme)
+	if path == "" {
+		return nil, errors.New("sqlite database path is not set")
 	}
 
-	db, err := sql.Open("postgres", connectionString)
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	database, err := sql.Open(SQLiteDriver, path+separator+"_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	database.SetMaxOpenConns(1)
+
+	err = database.Ping()
 	if err != nil {
-		log.Fatal(err)
-		return nil
+		database.Close()
+		return nil, err
 	}
 
-	return db
+	return database, nil
 }
 
 // util for testing //
 
+// Driver is PostgresDriver or SQLiteDriver
 type TestDatabase struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Driver string
 }
 
-func (td TestDatabase) CreateTestTable() error {
+// infra/migration/up or down of the test database driver
+func (td TestDatabase) migrationDirectory(direction string) string {
 	_, b, _, _ := runtime.Caller(0)
 	utilPath := filepath.Dir(b)
 	packagePath := filepath.Dir(utilPath)
 	serverPath := filepath.Dir(packagePath)
 	root := filepath.Dir(serverPath)
-	infraPath := filepath.Join(root, "infra")
-	migrationUpDirectory := filepath.Join(infraPath, "migration/up")
+	migrationPath := filepath.Join(root, "infra", "migration")
+	if td.Driver == SQLiteDriver {
+		migrationPath = filepath.Join(migrationPath, "sqlite")
+	}
+
+	return filepath.Join(migrationPath, direction)
+}
+
+func (td TestDatabase) CreateTestTable() error {
+	migrationUpDirectory := td.migrationDirectory("up")
 	files, err := os.ReadDir(migrationUpDirectory)
 	if err != nil {
 		return err
@@ -74,13 +117,7 @@ func (td TestDatabase) CreateTestTable() error {
 }
 
 func (td TestDatabase) DropTestTable() error {
-	_, b, _, _ := runtime.Caller(0)
-	utilPath := filepath.Dir(b)
-	packagePath := filepath.Dir(utilPath)
-	serverPath := filepath.Dir(packagePath)
-	root := filepath.Dir(serverPath)
-	infraPath := filepath.Join(root, "infra")
-	migrationDownDirectory := filepath.Join(infraPath, "migration/down")
+	migrationDownDirectory := td.migrationDirectory("down")
 	files, err := os.ReadDir(migrationDownDirectory)
 	if err != nil {
 		return err
@@ -122,7 +159,7 @@ func NewTestDatabase() (TestDatabase, error) {
 	serverPath := filepath.Dir(packagePath)
 	envPath := filepath.Join(serverPath, ".env")
 	err := godotenv.Load(envPath)
-	if err != nil {
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return TestDatabase{}, err
 	}
 
@@ -132,10 +169,19 @@ func NewTestDatabase() (TestDatabase, error) {
 		return TestDatabase{}, errors.New(errMessage)
 	}
 
-	db, err := sql.Open("postgres", connectionString)
+	if strings.HasPrefix(connectionString, SQLiteScheme) {
+		db, err := OpenSQLite(connectionString)
+		if err != nil {
+			return TestDatabase{}, err
+		}
+
+		return TestDatabase{DB: db, Driver: SQLiteDriver}, nil
+	}
+
+	db, err := sql.Open(PostgresDriver, connectionString)
 	if err != nil {
 		return TestDatabase{}, err
 	}
 
-	return TestDatabase{DB: db}, nil
+	return TestDatabase{DB: db, Driver: PostgresDriver}, nil
 }
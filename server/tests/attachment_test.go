@@ -0,0 +1,129 @@
+package server_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	clientmodel "passwordless-mail-client/pkg/model"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func AttachmentTestCases(t *testing.T) {
+
+	const (
+		SendMailPath    = "http://localhost:8080/mail/send"
+		UploadPath      = "http://localhost:8080/mail/attachment/upload"
+		AttachmentPath  = "http://localhost:8080/mail/attachment"
+		TestPrivateKey1 = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestPrivateKey2 = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+		TestPrivateKey3 = "923cebb3d8809d3caf09faa74ae2a39c23824a6fe75c44cab2a73dc6a0f3b606"
+	)
+
+	sender, senderErr := account.ConnectAccount(TestPrivateKey1)
+	recipient, recipientErr := account.ConnectAccount(TestPrivateKey2)
+	other, otherErr := account.ConnectAccount(TestPrivateKey3)
+	util.AssertNoAnyError(t, senderErr, recipientErr, otherErr)
+
+	upload := func(content []byte) (request.UploadAttachmentResponse, error) {
+		message, err := request.NewUploadAttachment(BaseApiPath, content)
+		if err != nil {
+			return request.UploadAttachmentResponse{}, err
+		}
+		response, err := postSigned(UploadPath, sender, message)
+		if err != nil {
+			return request.UploadAttachmentResponse{}, err
+		}
+		defer response.Body.Close()
+		var uploaded request.UploadAttachmentResponse
+		err = json.NewDecoder(response.Body).Decode(&uploaded)
+
+		return uploaded, err
+	}
+
+	download := func(reader *account.Account, message []byte) (int, []byte, error) {
+		response, err := postSigned(AttachmentPath, reader, message)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer response.Body.Close()
+		content, err := io.ReadAll(response.Body)
+
+		return response.StatusCode, content, err
+	}
+
+	t.Run("should upload attachment and let only sender and recipient download it", func(t *testing.T) {
+		// Arrange
+		ciphertext, key, encryptErr := account.EncryptAttachment([]byte("attachment content"))
+		uploaded, uploadErr := upload(ciphertext)
+		attachments := []request.OutgoingAttachment{{
+			Attachment: clientmodel.Attachment{Hash: uploaded.Hash, Size: uploaded.Size, MimeType: "text/plain"},
+			Name:       "test.txt",
+			Key:        key,
+		}}
+		messages, newMsgErr := request.NewSendEmailsWithAttachments(
+			BaseApiPath,
+			request.Recipients{To: []string{recipient.GetAddress()}},
+			"attachment subject",
+			"attachment body",
+			nil,
+			attachments,
+		)
+		sendResponse, sendErr := postSigned(SendMailPath, sender, messages[0])
+		var sent model.SendMailResponse
+		decodeErr := json.NewDecoder(sendResponse.Body).Decode(&sent)
+		sendResponse.Body.Close()
+		recipientMessage, newMsgErr1 := request.NewGetAttachment(BaseApiPath, sent.ID, uploaded.Hash)
+		senderMessage, newMsgErr2 := request.NewGetAttachment(BaseApiPath, sent.ID, uploaded.Hash)
+		otherMessage, newMsgErr3 := request.NewGetAttachment(BaseApiPath, sent.ID, uploaded.Hash)
+
+		// Act
+		recipientStatus, recipientContent, recipientErr := download(recipient, recipientMessage)
+		senderStatus, _, senderErr := download(sender, senderMessage)
+		otherStatus, _, otherErr := download(other, otherMessage)
+
+		// Assert
+		util.AssertNoAnyError(t, encryptErr, uploadErr, newMsgErr, sendErr, decodeErr, newMsgErr1, newMsgErr2, newMsgErr3, recipientErr, senderErr, otherErr)
+		assert.Equal(t, http.StatusCreated, sendResponse.StatusCode)
+		assert.Equal(t, int64(len(ciphertext)), uploaded.Size)
+		assert.Equal(t, http.StatusOK, recipientStatus)
+		assert.Equal(t, ciphertext, recipientContent)
+		assert.Equal(t, http.StatusOK, senderStatus)
+		assert.Equal(t, http.StatusNotFound, otherStatus)
+		content, decryptErr := account.DecryptAttachment(recipientContent, key)
+		assert.NoError(t, decryptErr)
+		assert.Equal(t, "attachment content", string(content))
+	})
+
+	t.Run("should return bad request when mail references an attachment that is not uploaded", func(t *testing.T) {
+		// Arrange
+		ciphertext, key, encryptErr := account.EncryptAttachment([]byte("never uploaded"))
+		attachments := []request.OutgoingAttachment{{
+			Attachment: clientmodel.Attachment{Hash: fmt.Sprintf("%x", sha256.Sum256(ciphertext)), Size: int64(len(ciphertext)), MimeType: "text/plain"},
+			Name:       "missing.txt",
+			Key:        key,
+		}}
+		messages, newMsgErr := request.NewSendEmailsWithAttachments(
+			BaseApiPath,
+			request.Recipients{To: []string{recipient.GetAddress()}},
+			"attachment subject",
+			"attachment body",
+			nil,
+			attachments,
+		)
+
+		// Act
+		response, sendErr := postSigned(SendMailPath, sender, messages[0])
+
+		// Assert
+		util.AssertNoAnyError(t, encryptErr, newMsgErr, sendErr)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+}
@@ -34,29 +34,21 @@ func InboxTestCases(t *testing.T) {
 			return fmt.Errorf("amount should be greater than 0")
 		}
 
-		database, err := util.NewTestDatabase()
-		if err != nil {
-			return err
-		}
-
-		// prepare mails
+		// insert mails to the store of the server
 		recipient := recipientAcc.GetAddress()
 		sender := senderAcc.GetAddress()
-		var mails []model.MailEntity
 		for i := 0; i < amount; i++ {
-			mail := model.MailEntity{
-				Recipient:   recipient,
-				Sender:      sender,
-				MailSubject: fmt.Sprintf("subject-%d", i+1),
-				Body:        fmt.Sprintf("body-%d", i+1),
-			}
-			mails = append(mails, mail)
-		}
-
-		// insert mails to database
-		queryScript := "INSERT INTO mail (recipient, sender, mail_subject, body) VALUES ($1, $2, $3, $4)"
-		for _, mail := range mails {
-			_, err := database.DB.Exec(queryScript, mail.Recipient, mail.Sender, mail.MailSubject, mail.Body)
+			_, err := testStores.Mail.InsertMail(model.OutgoingMail{
+				MessageID: uuid.New(),
+				From:      sender,
+				To:        []string{recipient},
+				Deliveries: []model.Delivery{{
+					Recipient: recipient,
+					Type:      model.RecipientTo,
+					Subject:   fmt.Sprintf("subject-%d", i+1),
+					Body:      fmt.Sprintf("body-%d", i+1),
+				}},
+			})
 			if err != nil {
 				return err
 			}
@@ -100,7 +92,7 @@ func InboxTestCases(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, response1.StatusCode)
 	})
 
-	t.Run("should return bad request when user send bad request body", func(t *testing.T) {
+	t.Run("should return unauthorized when x-public-key is not an address", func(t *testing.T) {
 		// Arrange
 		request, newReqErr := http.NewRequest(http.MethodPost, BaseInboxPath, nil)
 		request.Header.Add("x-public-key", "test")
@@ -112,7 +104,7 @@ func InboxTestCases(t *testing.T) {
 		// Assert
 		assert.NoError(t, newReqErr)
 		assert.NoError(t, sendReqErr)
-		assert.Equal(t, http.StatusBadRequest, response1.StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, response1.StatusCode)
 	})
 
 	t.Run("should return unauthorized when request contains invalid signature", func(t *testing.T) {
@@ -140,7 +132,7 @@ func InboxTestCases(t *testing.T) {
 	t.Run("should return bad request when request contains invalid query params", func(t *testing.T) {
 		// Arrange
 		testAccount, _ := account.ConnectAccount(TestPrivateKey1)
-		message, _ := request.NewGetInbox()
+		message, _ := request.NewGetInbox(BaseApiPath)
 		signedMassage, _ := testAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -161,10 +153,33 @@ func InboxTestCases(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
 	})
 
+	t.Run("should return bad request when page or limit is less than one", func(t *testing.T) {
+		// Arrange
+		testAccount, connectErr := account.ConnectAccount(TestPrivateKey1)
+		var statusCodes []int
+		var errs []error
+
+		// Act
+		for _, queryParams := range []string{"?page=0&limit=10", "?page=-1&limit=10", "?page=1&limit=0", "?page=1&limit=-10", "?before=x&limit=-1"} {
+			message, err := request.NewGetInbox(BaseApiPath)
+			errs = append(errs, err)
+			response, err := postSigned(BaseInboxPath+queryParams, testAccount, message)
+			errs = append(errs, err)
+			if err == nil {
+				statusCodes = append(statusCodes, response.StatusCode)
+				response.Body.Close()
+			}
+		}
+
+		// Assert
+		util.AssertNoAnyError(t, append(errs, connectErr)...)
+		assert.Equal(t, []int{400, 400, 400, 400, 400}, statusCodes)
+	})
+
 	t.Run("should return ok and mail inbox when user send request correctly", func(t *testing.T) {
 		// Arrange
 		testAccount, connectErr := account.ConnectAccount(TestPrivateKey1)
-		message, newMsgErr := request.NewGetInbox()
+		message, newMsgErr := request.NewGetInbox(BaseApiPath)
 		signedMassage, signErr := testAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -204,7 +219,7 @@ func InboxTestCases(t *testing.T) {
 		senderAcc, _ := account.ConnectAccount(TestPrivateKey2)
 		mockMailAmount := 14
 		insertMailErr := insertTestMails(*senderAcc, *recipientAcc, mockMailAmount)
-		message, newMsgErr := request.NewGetInbox()
+		message, newMsgErr := request.NewGetInbox(BaseApiPath)
 		signedMassage, signErr := recipientAcc.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -231,10 +246,50 @@ func InboxTestCases(t *testing.T) {
 		assert.Equal(t, mockMailAmount, inbox.Total)
 	})
 
+	t.Run("should page with before cursor without repeating mails newest first", func(t *testing.T) {
+		// Arrange
+		recipientAcc, connectErr := account.ConnectAccount(TestPrivateKey1)
+		getInbox := func(queryParams string) (model.InboxResponse, error) {
+			message, err := request.NewGetInbox(BaseApiPath)
+			if err != nil {
+				return model.InboxResponse{}, err
+			}
+			response, err := postSigned(BaseInboxPath+queryParams, recipientAcc, message)
+			if err != nil {
+				return model.InboxResponse{}, err
+			}
+			defer response.Body.Close()
+			inbox := model.InboxResponse{}
+			err = json.NewDecoder(response.Body).Decode(&inbox)
+			return inbox, err
+		}
+		firstPage, firstErr := getInbox("?page=1&limit=5")
+
+		// Act
+		secondPage, secondErr := getInbox("?limit=5&before=" + firstPage.Before)
+
+		// Assert
+		util.AssertNoAnyError(t, connectErr, firstErr, secondErr)
+		assert.Equal(t, 5, len(firstPage.Inbox))
+		assert.NotEmpty(t, secondPage.Inbox)
+		pages := append(firstPage.Inbox, secondPage.Inbox...)
+		seen := map[uuid.UUID]bool{}
+		for i, mail := range pages {
+			assert.False(t, seen[mail.ID])
+			seen[mail.ID] = true
+			if i > 0 {
+				previous, previousErr := time.Parse(time.RFC3339Nano, pages[i-1].SentAt)
+				current, currentErr := time.Parse(time.RFC3339Nano, mail.SentAt)
+				util.AssertNoAnyError(t, previousErr, currentErr)
+				assert.False(t, current.After(previous))
+			}
+		}
+	})
+
 	t.Run("should return unauthorize when request uuid is duplicate", func(t *testing.T) {
 		// Arrange
 		testAccount, connectErr := account.ConnectAccount(TestPrivateKey1)
-		message, newMsgErr := request.NewGetInbox()
+		message, newMsgErr := request.NewGetInbox(BaseApiPath)
 		signedMassage, signErr := testAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -268,13 +323,80 @@ func InboxTestCases(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, response2.StatusCode)
 	})
 
+	t.Run("should return unauthorized when request is signed for another action", func(t *testing.T) {
+		// Arrange
+		testAccount, connectErr := account.ConnectAccount(TestPrivateKey1)
+		message, newMsgErr := request.NewGetEmail(BaseApiPath, uuid.New())
+		signedMassage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMassage,
+		}
+		requestBodyByte, marshalErr := json.Marshal(requestBody)
+		apiPath := BaseInboxPath + "?page=1&limit=10"
+		payLoad := strings.NewReader(string(requestBodyByte))
+		request, newReqErr := http.NewRequest(http.MethodPost, apiPath, payLoad)
+		request.Header.Add("x-public-key", testAccount.GetAddress())
+
+		// Act
+		client := &http.Client{}
+		response, sendReqErr := client.Do(request)
+
+		// Assert
+		util.AssertNoAnyError(t, connectErr, newMsgErr, signErr, marshalErr, newReqErr, sendReqErr)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+
+	t.Run("should return bad request with upgrade message when request uses old protocol version", func(t *testing.T) {
+		// Arrange
+		testAccount, connectErr := account.ConnectAccount(TestPrivateKey1)
+		oldInbox := struct {
+			ID        uuid.UUID `json:"id"`
+			Timestamp string    `json:"timestamp"`
+		}{
+			ID:        uuid.New(),
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+		message, jsonEncodeErr := json.Marshal(oldInbox)
+		signedMassage, signErr := testAccount.Sign(message)
+		requestBody := model.RequestBody{
+			Data:      string(message),
+			Signature: signedMassage,
+		}
+		requestBodyByte, marshalErr := json.Marshal(requestBody)
+		apiPath := BaseInboxPath + "?page=1&limit=10"
+		payLoad := strings.NewReader(string(requestBodyByte))
+		request, newReqErr := http.NewRequest(http.MethodPost, apiPath, payLoad)
+		request.Header.Add("x-public-key", testAccount.GetAddress())
+
+		// Act
+		client := &http.Client{}
+		response, sendReqErr := client.Do(request)
+		responseBytes, readErr := io.ReadAll(response.Body)
+		var errorResponse model.ErrorResponse
+		unmarshalErr := json.Unmarshal(responseBytes, &errorResponse)
+
+		// Assert
+		util.AssertNoAnyError(t, connectErr, jsonEncodeErr, signErr, marshalErr, newReqErr, sendReqErr, readErr, unmarshalErr)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		assert.Equal(t, "unsupported_protocol_version", errorResponse.Code)
+		assert.Contains(t, errorResponse.Message, "unsupported protocol version")
+		assert.Equal(t, response.Header.Get("X-Request-Id"), errorResponse.RequestID)
+		assert.NotEmpty(t, errorResponse.RequestID)
+	})
+
 	t.Run("should return unauthorize when request contains timeout timestamp", func(t *testing.T) {
 		// Arrange
 		testAccount, connectErr := account.ConnectAccount(TestPrivateKey1)
 		last3minutes1second := time.Now().Add(-3 * time.Minute).Add(-1 * time.Second)
 		getInbox := request.GetInboxRequest{
-			ID:        uuid.New(),
-			Timestamp: last3minutes1second.Format(time.RFC3339),
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.GetInbox,
+				Origin:    BaseApiPath,
+				ID:        uuid.New(),
+				Timestamp: last3minutes1second.Format(time.RFC3339),
+			},
 		}
 		inbox, jsonEncodeErr := json.Marshal(getInbox)
 
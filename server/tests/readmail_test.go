@@ -2,7 +2,6 @@ package server_test
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"passwordless-mail-client/pkg/account"
@@ -30,14 +29,12 @@ func ReadMailTestCases(t *testing.T) {
 	var mailUUID uuid.UUID
 
 	beforeAll := func() {
-		allMails0 := retrieveMails()
-		fmt.Printf("allMails0: %v\n", allMails0)
 		sendAccount, _ := account.ConnectAccount(TestPrivateKey1)
 		receivedAccount, _ := account.ConnectAccount(TestPrivateKey2)
 		recipient := receivedAccount.GetAddress()
 		subject := recipient + "should receive this subject"
 		body := recipient + "should receive this body"
-		sendMailMessage, _ := request.NewSendEmail(recipient, subject, body)
+		sendMailMessage, _ := request.NewSendEmail(BaseApiPath, recipient, subject, body)
 		signedMessage, _ := sendAccount.Sign(sendMailMessage)
 		requestBody := model.RequestBody{
 			Data:      string(sendMailMessage),
@@ -162,9 +159,14 @@ func ReadMailTestCases(t *testing.T) {
 		// Arrange
 		account, newAccountErr := account.ConnectAccount(TestPrivateKey1)
 		badGetMailMessage := request.GetEmailRequest{
-			ID:        uuid.New(),
-			Timestamp: "bad timestamp",
-			EmailID:   mailUUID,
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.GetEmail,
+				Origin:    BaseApiPath,
+				ID:        uuid.New(),
+				Timestamp: "bad timestamp",
+			},
+			EmailID: mailUUID,
 		}
 		badMessageByte, marshalMsgErr := json.Marshal(badGetMailMessage)
 		signMessage, signMsgErr := account.Sign(badMessageByte)
@@ -222,7 +224,7 @@ func ReadMailTestCases(t *testing.T) {
 	t.Run("should return unauthorized when request contains invalid signature", func(t *testing.T) {
 		goodAccount, newGoodAccErr := account.ConnectAccount(TestPrivateKey1)
 		badAccount, newBadAccErr := account.ConnectAccount(TestPrivateKey2)
-		message, newMsgErr := request.NewGetEmail(mailUUID)
+		message, newMsgErr := request.NewGetEmail(BaseApiPath, mailUUID)
 		messageByte, marshalMsgErr := json.Marshal(message)
 		badSignMessage, signMsgErr := badAccount.Sign(messageByte)
 		requestBody := model.RequestBody{
@@ -247,7 +249,7 @@ func ReadMailTestCases(t *testing.T) {
 		// Arrange
 		account, newAccountErr := account.ConnectAccount(TestPrivateKey1)
 		randomMailID := uuid.New()
-		message, newMsgErr := request.NewGetEmail(randomMailID)
+		message, newMsgErr := request.NewGetEmail(BaseApiPath, randomMailID)
 		signMessage, signMsgErr := account.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -270,7 +272,7 @@ func ReadMailTestCases(t *testing.T) {
 	t.Run("should return OK and mail data when sender account read the mail", func(t *testing.T) {
 		// Arrange
 		account, newAccountErr := account.ConnectAccount(TestPrivateKey1)
-		message, newMsgErr := request.NewGetEmail(mailUUID)
+		message, newMsgErr := request.NewGetEmail(BaseApiPath, mailUUID)
 		signMessage, signMsgErr := account.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -297,7 +299,7 @@ func ReadMailTestCases(t *testing.T) {
 	t.Run("should return OK and mail data when recipient account read the mail", func(t *testing.T) {
 		// Arrange
 		recipientAccount, newAccountErr := account.ConnectAccount(TestPrivateKey2)
-		message, newMsgErr := request.NewGetEmail(mailUUID)
+		message, newMsgErr := request.NewGetEmail(BaseApiPath, mailUUID)
 		signMessage, signMsgErr := recipientAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -319,12 +321,15 @@ func ReadMailTestCases(t *testing.T) {
 		util.AssertNoAnyError(t, newAccountErr, newMsgErr, marshalByteErr, newReqErr, sendReqErr, signMsgErr, readErr, unmarshalErr)
 		assert.Equal(t, http.StatusOK, response.StatusCode)
 		assert.Equal(t, mailUUID, mail.ID)
+		senderAccount, _ := account.ConnectAccount(TestPrivateKey1)
+		assert.Equal(t, senderAccount.GetAddress(), mail.From)
+		assert.True(t, account.Verify(senderAccount.PublicKey, []byte(mail.SignedData), mail.Signature))
 	})
 
 	t.Run("should return not found when account is not the recipient or sender of the mail", func(t *testing.T) {
 		// Arrange
 		unauthorizeAccount, newUnauthorizeAccErr := account.ConnectAccount(TestPrivateKey3)
-		message, newMsgErr := request.NewGetEmail(mailUUID)
+		message, newMsgErr := request.NewGetEmail(BaseApiPath, mailUUID)
 		unauthorizeSignMessage, unauthorizeSignMsgErr := unauthorizeAccount.Sign(message)
 		unauthorizeRequestBody := model.RequestBody{
 			Data:      string(message),
@@ -350,7 +355,7 @@ func ReadMailTestCases(t *testing.T) {
 	t.Run("should return unauthorized when request uuid is duplicated", func(t *testing.T) {
 		// Arrange
 		account, newAccountErr := account.ConnectAccount(TestPrivateKey1)
-		message, newMsgErr := request.NewGetEmail(mailUUID)
+		message, newMsgErr := request.NewGetEmail(BaseApiPath, mailUUID)
 		signMessage, signMsgErr := account.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -381,9 +386,14 @@ func ReadMailTestCases(t *testing.T) {
 		account, newAccountErr := account.ConnectAccount(TestPrivateKey1)
 		last3minutes1second := time.Now().Add(-3 * time.Minute).Add(-1 * time.Second)
 		getEmail := request.GetEmailRequest{
-			ID:        uuid.New(),
-			Timestamp: last3minutes1second.Format(time.RFC3339),
-			EmailID:   mailUUID,
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.GetEmail,
+				Origin:    BaseApiPath,
+				ID:        uuid.New(),
+				Timestamp: last3minutes1second.Format(time.RFC3339),
+			},
+			EmailID: mailUUID,
 		}
 		message, jsonEncodeErr := json.Marshal(getEmail)
 		signMessage, signMsgErr := account.Sign(message)
@@ -406,27 +416,12 @@ func ReadMailTestCases(t *testing.T) {
 	})
 }
 
-func retrieveMails() []model.MailEntity {
-	testDatabase, err := util.NewTestDatabase()
+// mail id of user in the store of the server, nil when it does not exist
+func retrieveMail(id uuid.UUID, user string) *model.MailEntity {
+	mail, err := testStores.Mail.GetMail(id, user)
 	if err != nil {
-		return []model.MailEntity{}
-	}
-
-	var mails []model.MailEntity
-	rows, err := testDatabase.DB.Query("SELECT * FROM mail")
-	if err != nil {
-		return []model.MailEntity{}
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var mail model.MailEntity
-		err := rows.Scan(&mail.ID, &mail.Recipient, &mail.Sender, &mail.MailSubject, &mail.Body, &mail.SentAt)
-		if err != nil {
-			return []model.MailEntity{}
-		}
-		mails = append(mails, mail)
+		return nil
 	}
 
-	return mails
+	return mail
 }
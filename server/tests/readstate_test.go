@@ -0,0 +1,117 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func ReadStateTestCases(t *testing.T) {
+
+	const (
+		SendMailPath    = "http://localhost:8080/mail/send"
+		ReadMailPath    = "http://localhost:8080/mail"
+		MarkReadPath    = "http://localhost:8080/mail/read"
+		MarkUnreadPath  = "http://localhost:8080/mail/unread"
+		TestPrivateKey1 = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestPrivateKey2 = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+	)
+
+	sender, senderErr := account.ConnectAccount(TestPrivateKey1)
+	recipient, recipientErr := account.ConnectAccount(TestPrivateKey2)
+	util.AssertNoAnyError(t, senderErr, recipientErr)
+
+	sendTestMail := func() uuid.UUID {
+		message, err := request.NewSendEmail(BaseApiPath, recipient.GetAddress(), "read subject", "read body")
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := postSigned(SendMailPath, sender, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		var sent model.SendMailResponse
+		err = json.NewDecoder(response.Body).Decode(&sent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return sent.ID
+	}
+
+	readMail := func(reader *account.Account, id uuid.UUID) (model.Mail, error) {
+		message, err := request.NewGetEmail(BaseApiPath, id)
+		if err != nil {
+			return model.Mail{}, err
+		}
+		response, err := postSigned(ReadMailPath, reader, message)
+		if err != nil {
+			return model.Mail{}, err
+		}
+		defer response.Body.Close()
+		var mail model.Mail
+		err = json.NewDecoder(response.Body).Decode(&mail)
+
+		return mail, err
+	}
+
+	t.Run("should mark mail as read only when recipient opens it", func(t *testing.T) {
+		// Arrange
+		mailID := sendTestMail()
+
+		// Act
+		senderView, senderReadErr := readMail(sender, mailID)
+		recipientView, recipientReadErr := readMail(recipient, mailID)
+
+		// Assert
+		util.AssertNoAnyError(t, senderReadErr, recipientReadErr)
+		assert.Nil(t, senderView.ReadAt)
+		assert.NotNil(t, recipientView.ReadAt)
+	})
+
+	t.Run("should mark mails as unread and read in bulk", func(t *testing.T) {
+		// Arrange
+		ids := []uuid.UUID{sendTestMail(), sendTestMail()}
+		markReadMessage, newReadErr := request.NewMarkRead(BaseApiPath, ids)
+		markUnreadMessage, newUnreadErr := request.NewMarkUnread(BaseApiPath, ids)
+
+		// Act
+		readResponse, readErr := postSigned(MarkReadPath, recipient, markReadMessage)
+		var read model.MarkMailsResponse
+		readDecodeErr := json.NewDecoder(readResponse.Body).Decode(&read)
+		unreadResponse, unreadErr := postSigned(MarkUnreadPath, recipient, markUnreadMessage)
+		var unread model.MarkMailsResponse
+		unreadDecodeErr := json.NewDecoder(unreadResponse.Body).Decode(&unread)
+
+		// Assert
+		util.AssertNoAnyError(t, newReadErr, newUnreadErr, readErr, readDecodeErr, unreadErr, unreadDecodeErr)
+		assert.Equal(t, http.StatusOK, readResponse.StatusCode)
+		assert.Equal(t, 2, read.Updated)
+		assert.Equal(t, http.StatusOK, unreadResponse.StatusCode)
+		assert.Equal(t, 2, unread.Updated)
+	})
+
+	t.Run("should not mark mails of another recipient", func(t *testing.T) {
+		// Arrange
+		ids := []uuid.UUID{sendTestMail()}
+		message, newMsgErr := request.NewMarkRead(BaseApiPath, ids)
+
+		// Act
+		response, sendReqErr := postSigned(MarkReadPath, sender, message)
+		var result model.MarkMailsResponse
+		decodeErr := json.NewDecoder(response.Body).Decode(&result)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, sendReqErr, decodeErr)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, 0, result.Updated)
+	})
+}
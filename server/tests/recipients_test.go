@@ -0,0 +1,115 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func RecipientsTestCases(t *testing.T) {
+
+	const (
+		SendMailPath    = "http://localhost:8080/mail/send"
+		ReadMailPath    = "http://localhost:8080/mail"
+		TestPrivateKey1 = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestPrivateKey2 = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+		TestPrivateKey3 = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+		TestPrivateKey4 = "923cebb3d8809d3caf09faa74ae2a39c23824a6fe75c44cab2a73dc6a0f3b606"
+	)
+
+	sender, senderErr := account.ConnectAccount(TestPrivateKey1)
+	to, toErr := account.ConnectAccount(TestPrivateKey2)
+	cc, ccErr := account.ConnectAccount(TestPrivateKey3)
+	bcc, bccErr := account.ConnectAccount(TestPrivateKey4)
+	util.AssertNoAnyError(t, senderErr, toErr, ccErr, bccErr)
+
+	readMail := func(reader *account.Account, id uuid.UUID) (model.Mail, error) {
+		message, err := request.NewGetEmail(BaseApiPath, id)
+		if err != nil {
+			return model.Mail{}, err
+		}
+		response, err := postSigned(ReadMailPath, reader, message)
+		if err != nil {
+			return model.Mail{}, err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return model.Mail{}, fmt.Errorf("read mail: status %d", response.StatusCode)
+		}
+		var mail model.Mail
+		err = json.NewDecoder(response.Body).Decode(&mail)
+
+		return mail, err
+	}
+
+	t.Run("should deliver to every recipient and hide bcc from to and cc", func(t *testing.T) {
+		// Arrange
+		recipients := request.Recipients{
+			To:  []string{to.GetAddress()},
+			Cc:  []string{cc.GetAddress()},
+			Bcc: []string{bcc.GetAddress()},
+		}
+		messages, newMsgErr := request.NewSendEmails(BaseApiPath, recipients, "team subject", "team body", nil)
+		ids := map[string]uuid.UUID{}
+		var sendErrs []error
+		for _, message := range messages {
+			response, err := postSigned(SendMailPath, sender, message)
+			sendErrs = append(sendErrs, err)
+			if err != nil {
+				continue
+			}
+			var sent model.SendMailResponse
+			sendErrs = append(sendErrs, json.NewDecoder(response.Body).Decode(&sent))
+			response.Body.Close()
+			for address, id := range sent.IDs {
+				ids[address] = id
+			}
+		}
+
+		// Act
+		toMail, toReadErr := readMail(to, ids[to.GetAddress()])
+		ccMail, ccReadErr := readMail(cc, ids[cc.GetAddress()])
+		bccMail, bccReadErr := readMail(bcc, ids[bcc.GetAddress()])
+		_, otherReadErr := readMail(to, ids[bcc.GetAddress()])
+
+		// Assert
+		util.AssertNoAnyError(t, append(sendErrs, newMsgErr, toReadErr, ccReadErr, bccReadErr)...)
+		assert.Error(t, otherReadErr)
+		assert.Equal(t, toMail.MessageID, ccMail.MessageID)
+		assert.Equal(t, toMail.MessageID, bccMail.MessageID)
+		for _, mail := range []model.Mail{toMail, ccMail, bccMail} {
+			assert.Equal(t, []string{to.GetAddress()}, mail.To)
+			assert.Equal(t, []string{cc.GetAddress()}, mail.Cc)
+		}
+		assert.Empty(t, toMail.Bcc)
+		assert.Empty(t, ccMail.Bcc)
+		assert.False(t, strings.Contains(toMail.SignedData, bcc.GetAddress()))
+		assert.False(t, strings.Contains(ccMail.SignedData, bcc.GetAddress()))
+		assert.Equal(t, []string{bcc.GetAddress()}, bccMail.Bcc)
+	})
+
+	t.Run("should return bad request when recipient is listed twice", func(t *testing.T) {
+		// Arrange
+		messages, newMsgErr := request.NewSendEmails(BaseApiPath, request.Recipients{To: []string{to.GetAddress()}}, "subject", "body", nil)
+		var sendEmail request.SendEmailRequest
+		unmarshalErr := json.Unmarshal(messages[0], &sendEmail)
+		sendEmail.Cc = []string{to.GetAddress()}
+		message, marshalErr := json.Marshal(sendEmail)
+
+		// Act
+		response, sendErr := postSigned(SendMailPath, sender, message)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, unmarshalErr, marshalErr, sendErr)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+}
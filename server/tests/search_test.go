@@ -0,0 +1,100 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func SearchTestCases(t *testing.T) {
+
+	const (
+		SendMailPath    = "http://localhost:8080/mail/send"
+		SearchPath      = "http://localhost:8080/mail/search"
+		TestPrivateKey1 = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestPrivateKey2 = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+		TestPrivateKey3 = "923cebb3d8809d3caf09faa74ae2a39c23824a6fe75c44cab2a73dc6a0f3b606"
+	)
+
+	sender, senderErr := account.ConnectAccount(TestPrivateKey3)
+	recipient, recipientErr := account.ConnectAccount(TestPrivateKey1)
+	other, otherErr := account.ConnectAccount(TestPrivateKey2)
+	util.AssertNoAnyError(t, senderErr, recipientErr, otherErr)
+
+	search := func(message []byte, query string) (*http.Response, model.InboxResponse, error) {
+		response, err := postSigned(SearchPath+query, recipient, message)
+		if err != nil {
+			return nil, model.InboxResponse{}, err
+		}
+		defer response.Body.Close()
+		var result model.InboxResponse
+		if response.StatusCode == http.StatusOK {
+			err = json.NewDecoder(response.Body).Decode(&result)
+		}
+
+		return response, result, err
+	}
+
+	t.Run("should find mails of the recipient by sender since a time", func(t *testing.T) {
+		// Arrange
+		since := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+		var sendErrs []error
+		for i := 0; i < 2; i++ {
+			message, err := request.NewSendEmail(BaseApiPath, recipient.GetAddress(), "search subject", "search body")
+			sendErrs = append(sendErrs, err)
+			_, err = postSigned(SendMailPath, sender, message)
+			sendErrs = append(sendErrs, err)
+		}
+		otherMessage, err := request.NewSendEmail(BaseApiPath, recipient.GetAddress(), "other subject", "other body")
+		sendErrs = append(sendErrs, err)
+		_, err = postSigned(SendMailPath, other, otherMessage)
+		sendErrs = append(sendErrs, err)
+		message, newMsgErr := request.NewSearchEmail(BaseApiPath, sender.GetAddress(), since, "")
+
+		// Act
+		response, result, searchErr := search(message, "?page=1&limit=10")
+
+		// Assert
+		util.AssertNoAnyError(t, append(sendErrs, newMsgErr, searchErr)...)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, 2, result.Total)
+		for _, mail := range result.Inbox {
+			assert.Equal(t, sender.GetAddress(), mail.From)
+			assert.Equal(t, recipient.GetAddress(), mail.Recipient)
+		}
+	})
+
+	t.Run("should return bad request when page or limit is less than one", func(t *testing.T) {
+		// Arrange
+		zeroPageMessage, zeroPageMsgErr := request.NewSearchEmail(BaseApiPath, "", "", "")
+		zeroLimitMessage, zeroLimitMsgErr := request.NewSearchEmail(BaseApiPath, "", "", "")
+
+		// Act
+		zeroPage, _, zeroPageErr := search(zeroPageMessage, "?page=0&limit=10")
+		zeroLimit, _, zeroLimitErr := search(zeroLimitMessage, "?page=1&limit=0")
+
+		// Assert
+		util.AssertNoAnyError(t, zeroPageMsgErr, zeroLimitMsgErr, zeroPageErr, zeroLimitErr)
+		assert.Equal(t, http.StatusBadRequest, zeroPage.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, zeroLimit.StatusCode)
+	})
+
+	t.Run("should return unauthorized when search is signed for inbox", func(t *testing.T) {
+		// Arrange
+		message, newMsgErr := request.NewGetInbox(BaseApiPath)
+
+		// Act
+		response, _, searchErr := search(message, "?page=1&limit=10")
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, searchErr)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+}
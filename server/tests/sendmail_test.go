@@ -100,7 +100,7 @@ func SendMailTestCases(t *testing.T) {
 		recipient := receivedAccount.GetAddress()
 		subject := "test subject status created to " + recipient
 		body := "test mail body to " + recipient
-		message, newMsgErr := request.NewSendEmail(recipient, subject, body)
+		message, newMsgErr := request.NewSendEmail(BaseApiPath, recipient, subject, body)
 		signedBadMessage, signErr := badAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -120,8 +120,8 @@ func SendMailTestCases(t *testing.T) {
 
 		// Assert
 		util.AssertNoAnyError(t, newAccountErr1, newAccountErr2, newAccountErr3, newMsgErr, signErr)
-		util.AssertNoAnyError(t, marshalErr, newReqErr, sendReqErr, readErr)
-		assert.Error(t, unmarshalErr)
+		util.AssertNoAnyError(t, marshalErr, newReqErr, sendReqErr, readErr, unmarshalErr)
+		assert.Equal(t, uuid.Nil, result.ID)
 		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
 	})
 
@@ -129,9 +129,25 @@ func SendMailTestCases(t *testing.T) {
 		// Arrange
 		testAccount, newAccountErr := account.ConnectAccount(TestPrivateKey1)
 		badRecipientPublicKey := "bad key heehee! ow!"
-		subject := "test subject"
-		body := "test mail body"
-		message, newMsgErr := request.NewSendEmail(badRecipientPublicKey, subject, body)
+		sealed, encryptErr := account.EncryptMail(testAccount.PublicKey, "test subject", "test mail body")
+		sendEmail := request.SendEmailRequest{
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.SendEmail,
+				Origin:    BaseApiPath,
+				ID:        uuid.New(),
+				Timestamp: time.Now().Format(time.RFC3339),
+			},
+			MessageID: uuid.New(),
+			To:        []string{badRecipientPublicKey},
+			Deliveries: []request.Delivery{{
+				Recipient:    badRecipientPublicKey,
+				EphemeralKey: sealed.EphemeralKey,
+				Subject:      sealed.Subject,
+				Body:         sealed.Body,
+			}},
+		}
+		message, newMsgErr := json.Marshal(sendEmail)
 		signedMessage, signErr := testAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -150,9 +166,9 @@ func SendMailTestCases(t *testing.T) {
 		unmarshalErr := json.Unmarshal(resultBytes, &result)
 
 		// Assert
-		util.AssertNoAnyError(t, newAccountErr, newMsgErr, signErr, marshalErr)
-		util.AssertNoAnyError(t, newReqErr, sendReqErr, readErr)
-		assert.Error(t, unmarshalErr)
+		util.AssertNoAnyError(t, newAccountErr, encryptErr, newMsgErr, signErr, marshalErr)
+		util.AssertNoAnyError(t, newReqErr, sendReqErr, readErr, unmarshalErr)
+		assert.Equal(t, uuid.Nil, result.ID)
 		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
 	})
 
@@ -163,7 +179,7 @@ func SendMailTestCases(t *testing.T) {
 		recipient := receivedAccount.GetAddress()
 		subject := "test subject status created to " + recipient
 		body := "test mail body to " + recipient
-		message, newMsgErr := request.NewSendEmail(recipient, subject, body)
+		message, newMsgErr := request.NewSendEmail(BaseApiPath, recipient, subject, body)
 		signedMessage, signErr := sendAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -194,7 +210,7 @@ func SendMailTestCases(t *testing.T) {
 		recipient := receivedAccount.GetAddress()
 		subject := recipient + "should receive this subject"
 		body := recipient + "should receive this body"
-		sendMailMessage, _ := request.NewSendEmail(recipient, subject, body)
+		sendMailMessage, _ := request.NewSendEmail(BaseApiPath, recipient, subject, body)
 		signedMessage, _ := sendAccount.Sign(sendMailMessage)
 		requestBody := model.RequestBody{
 			Data:      string(sendMailMessage),
@@ -208,7 +224,7 @@ func SendMailTestCases(t *testing.T) {
 		client.Do(sendMailRequest)
 
 		// Act: read mail inbox
-		readMailMessage, _ := request.NewGetInbox()
+		readMailMessage, _ := request.NewGetInbox(BaseApiPath)
 		signedReadMailMessage, _ := receivedAccount.Sign(readMailMessage)
 		readMailRequestBody := model.RequestBody{
 			Data:      string(readMailMessage),
@@ -229,8 +245,16 @@ func SendMailTestCases(t *testing.T) {
 		assert.Equal(t, http.StatusOK, readMailResponse.StatusCode)
 		assert.NoError(t, unmarshalErr)
 		assert.Equal(t, 1, len(inbox.Inbox))
-		assert.Equal(t, subject, inbox.Inbox[0].Subject)
-		assert.Equal(t, body, inbox.Inbox[0].Body)
+		assert.NotEqual(t, subject, inbox.Inbox[0].Subject)
+		assert.NotEqual(t, body, inbox.Inbox[0].Body)
+		decryptedSubject, decryptedBody, decryptErr := receivedAccount.DecryptMail(account.SealedMail{
+			EphemeralKey: inbox.Inbox[0].EphemeralKey,
+			Subject:      inbox.Inbox[0].Subject,
+			Body:         inbox.Inbox[0].Body,
+		})
+		assert.NoError(t, decryptErr)
+		assert.Equal(t, subject, decryptedSubject)
+		assert.Equal(t, body, decryptedBody)
 		assert.Equal(t, sendAccount.GetAddress(), inbox.Inbox[0].From)
 	})
 
@@ -241,7 +265,7 @@ func SendMailTestCases(t *testing.T) {
 		recipient := receivedAccount.GetAddress()
 		subject := "test subject uuid duplicate to " + recipient
 		body := "test mail body to " + recipient
-		message, newMsgErr := request.NewSendEmail(recipient, subject, body)
+		message, newMsgErr := request.NewSendEmail(BaseApiPath, recipient, subject, body)
 		signedMessage, signErr := sendAccount.Sign(message)
 		requestBody := model.RequestBody{
 			Data:      string(message),
@@ -275,12 +299,23 @@ func SendMailTestCases(t *testing.T) {
 		recipient := receivedAccount.GetAddress()
 		subject := "test subject timeout to " + recipient
 		body := "test mail body to " + recipient
+		sealed, encryptErr := account.EncryptMail(receivedAccount.PublicKey, subject, body)
 		sendEmail := request.SendEmailRequest{
-			ID:        uuid.New(),
-			Timestamp: last3minutes1second.Format(time.RFC3339),
-			Recipient: recipient,
-			Subject:   subject,
-			Body:      body,
+			Header: request.Header{
+				Version:   request.ProtocolVersion,
+				Action:    request.SendEmail,
+				Origin:    BaseApiPath,
+				ID:        uuid.New(),
+				Timestamp: last3minutes1second.Format(time.RFC3339),
+			},
+			MessageID: uuid.New(),
+			To:        []string{recipient},
+			Deliveries: []request.Delivery{{
+				Recipient:    recipient,
+				EphemeralKey: sealed.EphemeralKey,
+				Subject:      sealed.Subject,
+				Body:         sealed.Body,
+			}},
 		}
 		message, newMsgErr := json.Marshal(sendEmail)
 		signedMessage, signErr := sendAccount.Sign(message)
@@ -299,7 +334,7 @@ func SendMailTestCases(t *testing.T) {
 		response, sendReqErr := client.Do(request)
 
 		// Assert
-		util.AssertNoAnyError(t, newAccountErr1, newAccountErr2, newMsgErr, signErr)
+		util.AssertNoAnyError(t, newAccountErr1, newAccountErr2, encryptErr, newMsgErr, signErr)
 		util.AssertNoAnyError(t, marshalErr, newReqErr, sendReqErr)
 		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
 	})
@@ -0,0 +1,102 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func SentTestCases(t *testing.T) {
+
+	const (
+		SendMailPath    = "http://localhost:8080/mail/send"
+		SentPath        = "http://localhost:8080/mail/sent"
+		TestPrivateKey2 = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+		TestPrivateKey3 = "923cebb3d8809d3caf09faa74ae2a39c23824a6fe75c44cab2a73dc6a0f3b606"
+	)
+
+	sender, senderErr := account.ConnectAccount(TestPrivateKey3)
+	recipient, recipientErr := account.ConnectAccount(TestPrivateKey2)
+	util.AssertNoAnyError(t, senderErr, recipientErr)
+
+	getSent := func(signer *account.Account, query string) (*http.Response, model.SentResponse, error) {
+		message, err := request.NewGetSent(BaseApiPath)
+		if err != nil {
+			return nil, model.SentResponse{}, err
+		}
+		response, err := postSigned(SentPath+query, signer, message)
+		if err != nil {
+			return nil, model.SentResponse{}, err
+		}
+		defer response.Body.Close()
+		var sent model.SentResponse
+		if response.StatusCode == http.StatusOK {
+			err = json.NewDecoder(response.Body).Decode(&sent)
+		}
+
+		return response, sent, err
+	}
+
+	t.Run("should allow only post request", func(t *testing.T) {
+		// Arrange
+		getRequest, newReqErr := http.NewRequest(http.MethodGet, SentPath, nil)
+
+		// Act
+		response, sendReqErr := http.DefaultClient.Do(getRequest)
+
+		// Assert
+		util.AssertNoAnyError(t, newReqErr, sendReqErr)
+		assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+	})
+
+	t.Run("should list mails sent by the user with paging", func(t *testing.T) {
+		// Arrange
+		_, before, beforeErr := getSent(sender, "?page=1&limit=100")
+		var sendErrs []error
+		for i := 0; i < 3; i++ {
+			message, err := request.NewSendEmail(BaseApiPath, recipient.GetAddress(), "sent subject", "sent body")
+			sendErrs = append(sendErrs, err)
+			response, err := postSigned(SendMailPath, sender, message)
+			sendErrs = append(sendErrs, err)
+			assert.Equal(t, http.StatusCreated, response.StatusCode)
+		}
+
+		// Act
+		response, sent, sentErr := getSent(sender, "?page=1&limit=2")
+
+		// Assert
+		util.AssertNoAnyError(t, append(sendErrs, beforeErr, sentErr)...)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, before.Total+3, sent.Total)
+		assert.Equal(t, 2, len(sent.Sent))
+		for _, mail := range sent.Sent {
+			assert.Equal(t, sender.GetAddress(), mail.From)
+		}
+	})
+
+	t.Run("should return bad request when page is missing", func(t *testing.T) {
+		// Act
+		response, _, sentErr := getSent(sender, "?limit=2")
+
+		// Assert
+		assert.NoError(t, sentErr)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+
+	t.Run("should return bad request when page or limit is less than one", func(t *testing.T) {
+		// Act
+		zeroPage, _, zeroPageErr := getSent(sender, "?page=0&limit=2")
+		negativeLimit, _, negativeLimitErr := getSent(sender, "?page=1&limit=-2")
+
+		// Assert
+		util.AssertNoAnyError(t, zeroPageErr, negativeLimitErr)
+		assert.Equal(t, http.StatusBadRequest, zeroPage.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, negativeLimit.StatusCode)
+	})
+}
@@ -1,12 +1,21 @@
 package server_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os/exec"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-server/pkg/api"
+	"passwordless-mail-server/pkg/auth"
+	"passwordless-mail-server/pkg/blob"
+	"passwordless-mail-server/pkg/mail"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/storage"
 	"passwordless-mail-server/pkg/util"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,7 +24,8 @@ import (
 
 const (
 	StartServerTime = 1000 * time.Millisecond
-	BaseApiPath     = "http://localhost:8080"
+	ServerAddress   = ":8080"
+	BaseApiPath     = "http://localhost" + ServerAddress
 )
 
 func ClearPort() {
@@ -31,29 +41,81 @@ func ClearPort() {
 	fmt.Println("port cleared")
 }
 
-func StartServer() {
-	fmt.Println("starting server...")
+// stores of the test server, DATABASE_CONNECTION_STRING selects the backend
+// like it does for the server, the suite runs in memory when it is not set
+var testStores *storage.Stores
 
-	scriptPath := "../scripts/start-server.sh"
-	cmd := exec.Command("bash", scriptPath)
+func OpenTestStores() (*storage.Stores, error) {
+	connectionString, err := util.ConnectionString()
+	if err != nil {
+		connectionString = storage.MemoryScheme
+	}
 
-	err := cmd.Run()
+	return storage.Open(connectionString)
+}
+
+// serve the api on BaseApiPath in this process, so the tests can
+// arrange and check mails through testStores
+func StartServer(stores *storage.Stores, attachmentDir string) {
+	fmt.Println("starting server...")
+
+	blobStore, err := blob.NewFileStore(attachmentDir)
 	if err != nil {
-		log.Fatal("error: can not start server")
+		log.Fatal("error: can not start server: ", err)
 	}
+	mailService := mail.NewService(stores.Mail, blobStore)
+	authService := auth.NewService(stores.Session, BaseApiPath, 15*time.Minute)
+	router := api.NewRouter(
+		api.NewHandler(mailService),
+		api.NewAuthHandler(authService),
+		api.NewMiddleware(auth.NewVerifier(stores.UUID, BaseApiPath), authService),
+	)
+	go func() {
+		log.Fatal(http.ListenAndServe(ServerAddress, router))
+	}()
 
 	time.Sleep(StartServerTime)
 
 	fmt.Println("server started")
 }
 
+// sign message with signer and post it to path
+func postSigned(path string, signer *account.Account, message []byte) (*http.Response, error) {
+	signature, err := signer.Sign(message)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, err := json.Marshal(model.RequestBody{
+		Data:      string(message),
+		Signature: signature,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequest(http.MethodPost, path, strings.NewReader(string(requestBody)))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Add("x-public-key", signer.GetAddress())
+
+	return http.DefaultClient.Do(httpRequest)
+}
+
 func TestServer(t *testing.T) {
 
-	testDatabase, _ := util.NewTestDatabase()
+	var err error
+	testStores, err = OpenTestStores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testStores.Close()
 	ClearPort()
-	StartServer()
-	defer testDatabase.DeleteItemsFromTable("mail")
-	defer testDatabase.DeleteItemsFromTable("used_uuid")
+	StartServer(testStores, t.TempDir())
+	if testDatabase, err := util.NewTestDatabase(); err == nil {
+		defer testDatabase.DeleteItemsFromTable("mail")
+		defer testDatabase.DeleteItemsFromTable("used_uuid")
+		defer testDatabase.DeleteItemsFromTable("session")
+	}
 
 	t.Run("should have healthy status", func(t *testing.T) {
 		// Arrange
@@ -75,4 +137,20 @@ func TestServer(t *testing.T) {
 	t.Run("should handle /mail/send", SendMailTestCases)
 
 	t.Run("should handle /mail", ReadMailTestCases)
+
+	t.Run("should handle /mail/sent", SentTestCases)
+
+	t.Run("should handle /mail/search", SearchTestCases)
+
+	t.Run("should handle /mail/delete, /mail/restore and /mail/trash/purge", TrashTestCases)
+
+	t.Run("should handle /mail/read and /mail/unread", ReadStateTestCases)
+
+	t.Run("should handle /mail/thread", ThreadTestCases)
+
+	t.Run("should handle /mail/send with cc and bcc", RecipientsTestCases)
+
+	t.Run("should handle /mail/attachment/upload and /mail/attachment", AttachmentTestCases)
+
+	t.Run("should handle /auth/challenge, /auth/login, /auth/logout and /auth/revoke", SessionTestCases)
 }
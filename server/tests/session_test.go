@@ -0,0 +1,158 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func SessionTestCases(t *testing.T) {
+
+	const (
+		ChallengePath   = "http://localhost:8080/auth/challenge"
+		LoginPath       = "http://localhost:8080/auth/login"
+		LogoutPath      = "http://localhost:8080/auth/logout"
+		RevokePath      = "http://localhost:8080/auth/revoke"
+		InboxPath       = "http://localhost:8080/mail/inbox?page=1&limit=10"
+		PurgeTrashPath  = "http://localhost:8080/mail/trash/purge"
+		TestPrivateKey1 = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+	)
+
+	user, userErr := account.ConnectAccount(TestPrivateKey1)
+	util.AssertNoAnyError(t, userErr)
+
+	challenge := func() (string, error) {
+		response, err := http.Post(ChallengePath, "application/json", nil)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		var result model.ChallengeResponse
+		err = json.NewDecoder(response.Body).Decode(&result)
+
+		return result.Nonce, err
+	}
+
+	login := func(scopes []request.Scope) (*http.Response, model.LoginResponse, error) {
+		nonce, err := challenge()
+		if err != nil {
+			return nil, model.LoginResponse{}, err
+		}
+		message, err := request.NewLogin(BaseApiPath, nonce, scopes)
+		if err != nil {
+			return nil, model.LoginResponse{}, err
+		}
+		response, err := postSigned(LoginPath, user, message)
+		if err != nil {
+			return nil, model.LoginResponse{}, err
+		}
+		defer response.Body.Close()
+		var result model.LoginResponse
+		if response.StatusCode == http.StatusOK {
+			err = json.NewDecoder(response.Body).Decode(&result)
+		}
+
+		return response, result, err
+	}
+
+	// post an unsigned request body with the bearer token
+	postBearer := func(path string, token string, message []byte) (*http.Response, error) {
+		requestBody, err := json.Marshal(model.RequestBody{Data: string(message)})
+		if err != nil {
+			return nil, err
+		}
+		httpRequest, err := http.NewRequest(http.MethodPost, path, strings.NewReader(string(requestBody)))
+		if err != nil {
+			return nil, err
+		}
+		httpRequest.Header.Add("Authorization", "Bearer "+token)
+
+		return http.DefaultClient.Do(httpRequest)
+	}
+
+	t.Run("should read inbox with session token without signing", func(t *testing.T) {
+		// Arrange
+		loginResponse, session, loginErr := login([]request.Scope{request.ScopeRead})
+		message, newMsgErr := request.NewGetInbox(BaseApiPath)
+
+		// Act
+		first, firstErr := postBearer(InboxPath, session.Token, message)
+		second, secondErr := postBearer(InboxPath, session.Token, message)
+
+		// Assert
+		util.AssertNoAnyError(t, loginErr, newMsgErr, firstErr, secondErr)
+		assert.Equal(t, http.StatusOK, loginResponse.StatusCode)
+		assert.Equal(t, user.GetAddress(), session.Address)
+		assert.Equal(t, http.StatusOK, first.StatusCode)
+		assert.Equal(t, http.StatusOK, second.StatusCode)
+	})
+
+	t.Run("should return forbidden when token does not have the scope", func(t *testing.T) {
+		// Arrange
+		_, session, loginErr := login([]request.Scope{request.ScopeRead})
+		message, newMsgErr := request.NewPurgeTrash(BaseApiPath)
+
+		// Act
+		response, purgeErr := postBearer(PurgeTrashPath, session.Token, message)
+
+		// Assert
+		util.AssertNoAnyError(t, loginErr, newMsgErr, purgeErr)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+	})
+
+	t.Run("should return unauthorized when challenge is used twice", func(t *testing.T) {
+		// Arrange
+		nonce, challengeErr := challenge()
+		message, newMsgErr := request.NewLogin(BaseApiPath, nonce, []request.Scope{request.ScopeRead})
+		_, firstErr := postSigned(LoginPath, user, message)
+
+		// Act
+		response, secondErr := postSigned(LoginPath, user, message)
+
+		// Assert
+		util.AssertNoAnyError(t, challengeErr, newMsgErr, firstErr, secondErr)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+
+	t.Run("should return unauthorized after logout", func(t *testing.T) {
+		// Arrange
+		_, session, loginErr := login([]request.Scope{request.ScopeRead})
+		message, newMsgErr := request.NewGetInbox(BaseApiPath)
+		logoutResponse, logoutErr := postBearer(LogoutPath, session.Token, nil)
+
+		// Act
+		response, inboxErr := postBearer(InboxPath, session.Token, message)
+
+		// Assert
+		util.AssertNoAnyError(t, loginErr, newMsgErr, logoutErr, inboxErr)
+		assert.Equal(t, http.StatusOK, logoutResponse.StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+
+	t.Run("should revoke every session of the signer", func(t *testing.T) {
+		// Arrange
+		_, first, firstLoginErr := login([]request.Scope{request.ScopeRead})
+		_, second, secondLoginErr := login([]request.Scope{request.ScopeRead, request.ScopeWrite})
+		nonce, challengeErr := challenge()
+		revokeMessage, newRevokeErr := request.NewRevokeSessions(BaseApiPath, nonce)
+		revokeResponse, revokeErr := postSigned(RevokePath, user, revokeMessage)
+		message, newMsgErr := request.NewGetInbox(BaseApiPath)
+
+		// Act
+		firstResponse, firstErr := postBearer(InboxPath, first.Token, message)
+		secondResponse, secondErr := postBearer(InboxPath, second.Token, message)
+
+		// Assert
+		util.AssertNoAnyError(t, firstLoginErr, secondLoginErr, challengeErr, newRevokeErr, revokeErr, newMsgErr, firstErr, secondErr)
+		assert.Equal(t, http.StatusOK, revokeResponse.StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, firstResponse.StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, secondResponse.StatusCode)
+	})
+}
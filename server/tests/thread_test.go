@@ -0,0 +1,110 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func ThreadTestCases(t *testing.T) {
+
+	const (
+		SendMailPath    = "http://localhost:8080/mail/send"
+		ThreadPath      = "http://localhost:8080/mail/thread"
+		TestPrivateKey1 = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestPrivateKey2 = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+		TestPrivateKey3 = "35c03d4a383c899345cc2e8d49417a92b7654fab37d404783dac84e3fcf5d66e"
+	)
+
+	alice, aliceErr := account.ConnectAccount(TestPrivateKey1)
+	bob, bobErr := account.ConnectAccount(TestPrivateKey2)
+	outsider, outsiderErr := account.ConnectAccount(TestPrivateKey3)
+	util.AssertNoAnyError(t, aliceErr, bobErr, outsiderErr)
+
+	send := func(sender *account.Account, message []byte) (*http.Response, model.SendMailResponse) {
+		response, err := postSigned(SendMailPath, sender, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		var sent model.SendMailResponse
+		json.NewDecoder(response.Body).Decode(&sent)
+
+		return response, sent
+	}
+
+	getThread := func(reader *account.Account, id uuid.UUID) (int, model.ThreadResponse, error) {
+		message, err := request.NewGetThread(BaseApiPath, id)
+		if err != nil {
+			return 0, model.ThreadResponse{}, err
+		}
+		response, err := postSigned(ThreadPath, reader, message)
+		if err != nil {
+			return 0, model.ThreadResponse{}, err
+		}
+		defer response.Body.Close()
+		var thread model.ThreadResponse
+		if response.StatusCode == http.StatusOK {
+			err = json.NewDecoder(response.Body).Decode(&thread)
+		}
+
+		return response.StatusCode, thread, err
+	}
+
+	t.Run("should return replies in the thread of the original mail in order", func(t *testing.T) {
+		// Arrange
+		original, newOriginalErr := request.NewSendEmail(BaseApiPath, bob.GetAddress(), "lunch", "lunch today?")
+		_, sentOriginal := send(alice, original)
+		reply, newReplyErr := request.NewReplyEmail(BaseApiPath, alice.GetAddress(), "re: lunch", "sure", sentOriginal.ID)
+		_, sentReply := send(bob, reply)
+
+		// Act
+		aliceStatus, aliceThread, aliceThreadErr := getThread(alice, sentReply.ID)
+		bobStatus, bobThread, bobThreadErr := getThread(bob, sentOriginal.ID)
+
+		// Assert
+		util.AssertNoAnyError(t, newOriginalErr, newReplyErr, aliceThreadErr, bobThreadErr)
+		assert.Equal(t, http.StatusOK, aliceStatus)
+		assert.Equal(t, http.StatusOK, bobStatus)
+		assert.Equal(t, sentOriginal.MessageID, aliceThread.ThreadID)
+		assert.Equal(t, 2, len(aliceThread.Mails))
+		assert.Equal(t, sentOriginal.ID, aliceThread.Mails[0].ID)
+		assert.Equal(t, sentReply.ID, aliceThread.Mails[1].ID)
+		assert.Equal(t, sentOriginal.ID, *aliceThread.Mails[1].InReplyTo)
+		assert.Equal(t, aliceThread, bobThread)
+	})
+
+	t.Run("should reject reply to a mail the sender cannot see", func(t *testing.T) {
+		// Arrange
+		original, newOriginalErr := request.NewSendEmail(BaseApiPath, bob.GetAddress(), "private", "private body")
+		_, sentOriginal := send(alice, original)
+		reply, newReplyErr := request.NewReplyEmail(BaseApiPath, alice.GetAddress(), "re: private", "let me in", sentOriginal.ID)
+
+		// Act
+		response, _ := send(outsider, reply)
+
+		// Assert
+		util.AssertNoAnyError(t, newOriginalErr, newReplyErr)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+
+	t.Run("should return not found when user is not part of the thread", func(t *testing.T) {
+		// Arrange
+		original, newOriginalErr := request.NewSendEmail(BaseApiPath, bob.GetAddress(), "private", "private body")
+		_, sentOriginal := send(alice, original)
+
+		// Act
+		status, _, threadErr := getThread(outsider, sentOriginal.ID)
+
+		// Assert
+		util.AssertNoAnyError(t, newOriginalErr, threadErr)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
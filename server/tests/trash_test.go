@@ -0,0 +1,129 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"passwordless-mail-client/pkg/account"
+	"passwordless-mail-client/pkg/request"
+	"passwordless-mail-server/pkg/model"
+	"passwordless-mail-server/pkg/util"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TrashTestCases(t *testing.T) {
+
+	const (
+		SendMailPath    = "http://localhost:8080/mail/send"
+		DeleteMailPath  = "http://localhost:8080/mail/delete"
+		RestoreMailPath = "http://localhost:8080/mail/restore"
+		PurgeTrashPath  = "http://localhost:8080/mail/trash/purge"
+		TestPrivateKey1 = "1baa694c49154f63b1503c7138f184c80f221670f035403ff428a65183bab247"
+		TestPrivateKey2 = "fd778940ddae63e19e5d2a05604a4d0eaec18b977801299a7f54aa95e33cbec2"
+	)
+
+	sender, senderErr := account.ConnectAccount(TestPrivateKey1)
+	recipient, recipientErr := account.ConnectAccount(TestPrivateKey2)
+	util.AssertNoAnyError(t, senderErr, recipientErr)
+
+	sendTestMail := func() uuid.UUID {
+		message, err := request.NewSendEmail(BaseApiPath, recipient.GetAddress(), "trash subject", "trash body")
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := postSigned(SendMailPath, sender, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		var sent model.SendMailResponse
+		err = json.NewDecoder(response.Body).Decode(&sent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return sent.ID
+	}
+
+	t.Run("should allow only post request", func(t *testing.T) {
+		// Arrange
+		getRequest, newReqErr := http.NewRequest(http.MethodGet, DeleteMailPath, nil)
+
+		// Act
+		response, sendReqErr := http.DefaultClient.Do(getRequest)
+
+		// Assert
+		util.AssertNoAnyError(t, newReqErr, sendReqErr)
+		assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+	})
+
+	t.Run("should not allow sender to delete mail of recipient", func(t *testing.T) {
+		// Arrange
+		mailID := sendTestMail()
+		message, newMsgErr := request.NewDeleteEmail(BaseApiPath, mailID)
+
+		// Act
+		response, sendReqErr := postSigned(DeleteMailPath, sender, message)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, sendReqErr)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+
+	t.Run("should move mail to trash and restore it", func(t *testing.T) {
+		// Arrange
+		mailID := sendTestMail()
+		deleteMessage, newDeleteErr := request.NewDeleteEmail(BaseApiPath, mailID)
+		deleteAgainMessage, newDeleteAgainErr := request.NewDeleteEmail(BaseApiPath, mailID)
+		restoreMessage, newRestoreErr := request.NewRestoreEmail(BaseApiPath, mailID)
+
+		// Act
+		deleteResponse, deleteErr := postSigned(DeleteMailPath, recipient, deleteMessage)
+		deleteAgainResponse, deleteAgainErr := postSigned(DeleteMailPath, recipient, deleteAgainMessage)
+		restoreResponse, restoreErr := postSigned(RestoreMailPath, recipient, restoreMessage)
+
+		// Assert
+		util.AssertNoAnyError(t, newDeleteErr, newDeleteAgainErr, newRestoreErr, deleteErr, deleteAgainErr, restoreErr)
+		assert.Equal(t, http.StatusOK, deleteResponse.StatusCode)
+		assert.Equal(t, http.StatusNotFound, deleteAgainResponse.StatusCode)
+		assert.Equal(t, http.StatusOK, restoreResponse.StatusCode)
+		restored := retrieveMail(mailID, recipient.GetAddress())
+		assert.NotNil(t, restored)
+		assert.Nil(t, restored.DeletedAt)
+	})
+
+	t.Run("should purge trashed mails of the recipient", func(t *testing.T) {
+		// Arrange
+		mailID := sendTestMail()
+		deleteMessage, newDeleteErr := request.NewDeleteEmail(BaseApiPath, mailID)
+		deleteResponse, deleteErr := postSigned(DeleteMailPath, recipient, deleteMessage)
+		purgeMessage, newPurgeErr := request.NewPurgeTrash(BaseApiPath)
+
+		// Act
+		purgeResponse, purgeErr := postSigned(PurgeTrashPath, recipient, purgeMessage)
+		var purged model.PurgeTrashResponse
+		decodeErr := json.NewDecoder(purgeResponse.Body).Decode(&purged)
+
+		// Assert
+		util.AssertNoAnyError(t, newDeleteErr, deleteErr, newPurgeErr, purgeErr, decodeErr)
+		assert.Equal(t, http.StatusOK, deleteResponse.StatusCode)
+		assert.Equal(t, http.StatusOK, purgeResponse.StatusCode)
+		assert.Equal(t, 1, purged.Purged)
+		assert.Nil(t, retrieveMail(mailID, recipient.GetAddress()))
+	})
+
+	t.Run("should return unauthorized when delete request is signed for restore", func(t *testing.T) {
+		// Arrange
+		mailID := sendTestMail()
+		message, newMsgErr := request.NewRestoreEmail(BaseApiPath, mailID)
+
+		// Act
+		response, sendReqErr := postSigned(DeleteMailPath, recipient, message)
+
+		// Assert
+		util.AssertNoAnyError(t, newMsgErr, sendReqErr)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+}